@@ -0,0 +1,109 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/ctiller15/tailscribe/internal/database"
+	"github.com/ctiller15/tailscribe/internal/oauth"
+)
+
+// A social login provider and whether the user has it connected.
+type AccountConnection struct {
+	Provider  oauth.Provider
+	Connected bool
+}
+
+type AccountPageData struct {
+	BasePageData
+	User        database.User
+	HasPassword bool
+	Connections []AccountConnection
+	Error       string
+}
+
+func isConnected(user database.User, provider oauth.Provider) bool {
+	switch p := provider.(type) {
+	case *oauth.Facebook:
+		return user.FacebookID.Valid
+	case *oauth.OIDC:
+		return user.OidcSubject.Valid && user.OidcIssuer.String == p.Issuer
+	default:
+		return false
+	}
+}
+
+// Counts the ways the user can log in, including social logins that are no longer configured.
+func loginMethods(user database.User) int {
+	count := 0
+	for _, connected := range []bool{user.Password.Valid, user.FacebookID.Valid, user.OidcSubject.Valid} {
+		if connected {
+			count++
+		}
+	}
+	return count
+}
+
+func (a *APIConfig) renderAccount(w http.ResponseWriter, r *http.Request, status int, userID int, errorMessage string) {
+	user, err := a.Db.GetUser(r.Context(), int32(userID))
+	if err != nil {
+		a.serverError(w, r, "error loading user", err)
+		return
+	}
+
+	providers := a.loginProviders()
+	connections := make([]AccountConnection, 0, len(providers))
+	for _, provider := range providers {
+		connections = append(connections, AccountConnection{
+			Provider:  provider,
+			Connected: isConnected(user, provider),
+		})
+	}
+
+	data := AccountPageData{
+		BasePageData: basePageData(r, "TailScribe - Account"),
+		User:         user,
+		HasPassword:  user.Password.Valid,
+		Connections:  connections,
+		Error:        errorMessage,
+	}
+
+	a.render(w, r, status, "account", data)
+}
+
+func (a *APIConfig) HandleGetAccount(w http.ResponseWriter, r *http.Request, user_id int) {
+	a.renderAccount(w, r, http.StatusOK, user_id, "")
+}
+
+func (a *APIConfig) HandlePostUnlinkProvider(w http.ResponseWriter, r *http.Request, user_id int) {
+	ctx := r.Context()
+	provider, ok := a.providerFromPath(w, r)
+	if !ok {
+		return
+	}
+
+	user, err := a.Db.GetUser(ctx, int32(user_id))
+	if err != nil {
+		a.serverError(w, r, "error loading user", err)
+		return
+	}
+
+	if !isConnected(user, provider) {
+		http.Redirect(w, r, "/account", http.StatusFound)
+		return
+	}
+
+	// Don't let users lock themselves out.
+	if loginMethods(user) <= 1 {
+		a.renderAccount(w, r, http.StatusBadRequest, user_id,
+			"This is the only way you can log in. Set a password with \"Forgot your password?\" or connect another login first.")
+		return
+	}
+
+	err = setIdentity(ctx, &a.Db, user.ID, provider, "")
+	if err != nil {
+		a.serverError(w, r, "error unlinking identity", err)
+		return
+	}
+
+	http.Redirect(w, r, "/account", http.StatusFound)
+}
@@ -0,0 +1,173 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/ctiller15/tailscribe/internal/database"
+)
+
+// How long a deleted account is kept, so it can be restored on request, before its data is purged.
+const accountDeletionGracePeriod = 30 * 24 * time.Hour
+
+type DeleteAccountForm struct {
+	Password string
+	// Passwordless users type "delete" instead.
+	Confirm string
+	Valid   bool
+	Errors  map[string]string
+}
+
+type DeleteAccountPageData struct {
+	BasePageData
+	HasPassword  bool
+	GraceDays    int
+	Deleted      bool
+	ContactEmail string
+	DeleteAccountForm
+}
+
+func (a *APIConfig) renderDeleteAccount(w http.ResponseWriter, r *http.Request, status int, data DeleteAccountPageData) {
+	data.BasePageData = basePageData(r, "TailScribe - Delete Account")
+	data.GraceDays = int(accountDeletionGracePeriod.Hours() / 24)
+	data.ContactEmail = a.Env.ContactEmail
+
+	a.render(w, r, status, "delete_account", data)
+}
+
+func (a *APIConfig) HandleGetDeleteAccount(w http.ResponseWriter, r *http.Request, user_id int) {
+	user, err := a.Db.GetUser(r.Context(), int32(user_id))
+	if err != nil {
+		a.serverError(w, r, "error loading user", err)
+		return
+	}
+
+	a.renderDeleteAccount(w, r, http.StatusOK, DeleteAccountPageData{
+		HasPassword:       user.Password.Valid,
+		DeleteAccountForm: DeleteAccountForm{Valid: true},
+	})
+}
+
+func (a *APIConfig) HandlePostDeleteAccount(w http.ResponseWriter, r *http.Request, user_id int) {
+	ctx := r.Context()
+	user, err := a.Db.GetUser(ctx, int32(user_id))
+	if err != nil {
+		a.serverError(w, r, "error loading user", err)
+		return
+	}
+
+	form := DeleteAccountForm{
+		Password: r.FormValue("password"),
+		Confirm:  strings.TrimSpace(r.FormValue("confirm")),
+		Errors:   map[string]string{},
+	}
+
+	if user.Password.Valid {
+		// No point rehashing a password that's about to be deleted.
+		if match, _ := a.Passwords.CheckPasswordHash(form.Password, user.Password.String); !match {
+			form.Errors["password"] = "That password isn't right."
+		}
+	} else if !strings.EqualFold(form.Confirm, "delete") {
+		form.Errors["confirm"] = "Type delete to confirm."
+	}
+
+	if len(form.Errors) > 0 {
+		a.renderDeleteAccount(w, r, http.StatusBadRequest, DeleteAccountPageData{
+			HasPassword:       user.Password.Valid,
+			DeleteAccountForm: form,
+		})
+		return
+	}
+
+	// Together, so an account is never left deleted but still signed in.
+	err = a.inTx(ctx, func(q *database.Queries) error {
+		err := q.SoftDeleteUser(ctx, user.ID)
+		if err != nil {
+			return err
+		}
+
+		return revokeAllSessions(ctx, q, user.ID)
+	})
+	if err != nil {
+		a.serverError(w, r, "error deleting user", err)
+		return
+	}
+
+	a.clearSessionCookies(w)
+
+	a.renderDeleteAccount(w, r, http.StatusOK, DeleteAccountPageData{Deleted: true})
+}
+
+// Explains why email can't be used yet, if a deleted account that could still be
+// restored holds it. Returns "" if none does.
+func (a *APIConfig) deletedAccountProblem(ctx context.Context, email string) (string, error) {
+	user, err := a.Db.GetDeletedUserByEmail(ctx, email)
+	if isNotFound(err) {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+
+	keptUntil := user.DeletedAt.Time.Add(accountDeletionGracePeriod)
+	return fmt.Sprintf("An account with this email was deleted, and is kept until %s in case it's restored. You can sign up with this email again after that.",
+		keptUntil.Format("Jan 2, 2006")), nil
+}
+
+// Permanently removes users deleted more than the grace period before now.
+// Pets they shared stay with the other people linked to them, and someone else
+// becomes owner if needed. Returns how many users were purged.
+func (a *APIConfig) PurgeDeletedUsers(ctx context.Context, now time.Time) (int, error) {
+	userIDs, err := a.Db.ListUsersToPurge(ctx, sql.NullTime{Time: now.Add(-accountDeletionGracePeriod), Valid: true})
+	if err != nil {
+		return 0, err
+	}
+
+	for i, userID := range userIDs {
+		// Each user in one transaction, so a failure can't leave pets without an owner.
+		err = a.inTx(ctx, func(q *database.Queries) error {
+			err := q.PromotePetHeirsForUser(ctx, userID)
+			if err != nil {
+				return err
+			}
+
+			err = q.DeleteSolePetsForUser(ctx, userID)
+			if err != nil {
+				return err
+			}
+
+			return q.DeleteUser(ctx, userID)
+		})
+		if err != nil {
+			return i, err
+		}
+	}
+
+	return len(userIDs), nil
+}
+
+// Purges deleted users every interval until ctx is done.
+func (a *APIConfig) RunPurger(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			purged, err := a.PurgeDeletedUsers(ctx, time.Now())
+			if err != nil {
+				a.Logger.Error("error purging deleted users", slog.String("error", err.Error()))
+			}
+			if purged > 0 {
+				a.Logger.Info("purged deleted users", slog.Int("count", purged))
+			}
+		}
+	}
+}
@@ -0,0 +1,239 @@
+package api
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ctiller15/tailscribe/internal/database"
+	"github.com/stretchr/testify/assert"
+)
+
+func postDeleteAccount(cookies []*http.Cookie, formData url.Values) *httptest.ResponseRecorder {
+	request, _ := http.NewRequest(http.MethodPost, "/account/delete", strings.NewReader(formData.Encode()))
+	request.Header.Add("Content-Type", "application/x-www-form-urlencoded")
+	for _, cookie := range cookies {
+		request.AddCookie(cookie)
+	}
+	response := httptest.NewRecorder()
+
+	apiCfg := createConfig()
+	apiCfg.CheckAuthMiddleware(apiCfg.HandlePostDeleteAccount)(response, request)
+
+	return response
+}
+
+func readZip(t *testing.T, body []byte) map[string][]byte {
+	archive, err := zip.NewReader(bytes.NewReader(body), int64(len(body)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	files := map[string][]byte{}
+	for _, file := range archive.File {
+		reader, err := file.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		contents, err := io.ReadAll(reader)
+		reader.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		files[file.Name] = contents
+	}
+	return files
+}
+
+func TestExportProfile(t *testing.T) {
+	user := database.User{
+		ID:                 7,
+		Email:              sql.NullString{String: "rex@test.com", Valid: true},
+		Password:           sql.NullString{String: "$2a$10$secrethash", Valid: true},
+		ResetPasswordToken: sql.NullString{String: "secrettoken", Valid: true},
+		FacebookID:         sql.NullString{String: "fb-123", Valid: true},
+		TotpSecret:         sql.NullString{String: "secrettotp", Valid: true},
+		TotpEnabledAt:      sql.NullTime{Time: time.Now(), Valid: true},
+	}
+
+	contents, err := json.Marshal(exportProfile(user))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Contains(t, string(contents), `"email":"rex@test.com"`)
+	assert.Contains(t, string(contents), `"has_password":true`)
+	assert.Contains(t, string(contents), `"facebook_connected":true`)
+	assert.Contains(t, string(contents), `"two_factor_enabled":true`)
+	assert.Contains(t, string(contents), `"username":null`)
+	assert.NotContains(t, string(contents), "secrethash")
+	assert.NotContains(t, string(contents), "secrettoken")
+	assert.NotContains(t, string(contents), "fb-123")
+	assert.NotContains(t, string(contents), "secrettotp")
+}
+
+func TestWritePersonalDataZip(t *testing.T) {
+	data := personalData{
+		Profile: profileExport{ID: 7},
+		Pets: []petExport{{
+			Pet: petRecordExport{ID: 3, Name: "Rex", Slug: "rex"},
+			TrainingSessions: []trainingSessionExport{{
+				ID:          1,
+				Behaviors:   []string{"sit"},
+				Reinforcers: []string{},
+			}},
+		}},
+	}
+
+	var buf bytes.Buffer
+	err := writePersonalDataZip(&buf, data)
+	assert.Nil(t, err)
+
+	files := readZip(t, buf.Bytes())
+	for _, name := range []string{
+		"profile.json",
+		"pets/3-rex/pet.json",
+		"pets/3-rex/training_sessions.json",
+		"pets/3-rex/skills.json",
+		"pets/3-rex/goals.json",
+		"pets/3-rex/titles.json",
+		"pets/3-rex/likes.json",
+	} {
+		assert.Contains(t, files, name)
+	}
+
+	var sessions []trainingSessionExport
+	err = json.Unmarshal(files["pets/3-rex/training_sessions.json"], &sessions)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"sit"}, sessions[0].Behaviors)
+}
+
+func TestHandleGetDataExport(t *testing.T) {
+	email := randTestEmail()
+	cookies := signUserUp(email, testPassword)
+	authCookie := findCookie(cookies, "token")
+	petID := createPetForUser(t, authCookie, "Rex")
+
+	request, _ := http.NewRequest(http.MethodGet, "/account/export", nil)
+	request.AddCookie(authCookie)
+	response := httptest.NewRecorder()
+
+	apiCfg := createConfig()
+	apiCfg.CheckAuthMiddleware(apiCfg.HandleGetDataExport)(response, request)
+
+	assert.Equal(t, 200, response.Result().StatusCode)
+	assert.Equal(t, "application/zip", response.Result().Header.Get("Content-Type"))
+	assert.Contains(t, response.Result().Header.Get("Content-Disposition"), "attachment")
+
+	files := readZip(t, response.Body.Bytes())
+	assert.Contains(t, string(files["profile.json"]), email)
+	assert.NotContains(t, string(files["profile.json"]), "$2a$")
+
+	var pet petRecordExport
+	for name, contents := range files {
+		if strings.HasSuffix(name, "/pet.json") {
+			err := json.Unmarshal(contents, &pet)
+			assert.Nil(t, err)
+		}
+	}
+	assert.Equal(t, int32(petID), pet.ID)
+	assert.Equal(t, "Rex", pet.Name)
+}
+
+func TestHandlePostDeleteAccount(t *testing.T) {
+	t.Run("Refuses the wrong password", func(t *testing.T) {
+		cookies := signUserUp(randTestEmail(), testPassword)
+
+		response := postDeleteAccount(cookies, url.Values{"password": {"wrong"}})
+
+		assert.Equal(t, 400, response.Result().StatusCode)
+		_, userID := callProtected(findCookie(cookies, "token"))
+		assert.NotEqual(t, -1, userID)
+	})
+
+	t.Run("Signs the user out everywhere", func(t *testing.T) {
+		email := randTestEmail()
+		cookies := signUserUp(email, testPassword)
+
+		response := postDeleteAccount(cookies, url.Values{"password": {testPassword}})
+		assert.Equal(t, 200, response.Result().StatusCode)
+
+		response, userID := callProtected(findCookie(cookies, "token"))
+		assert.Equal(t, 401, response.Result().StatusCode)
+		assert.Equal(t, -1, userID)
+
+		response, userID = callProtected(findCookie(cookies, "refresh_token"))
+		assert.Equal(t, 401, response.Result().StatusCode)
+		assert.Equal(t, -1, userID)
+
+		response = postLogin(email, testPassword)
+		assert.Equal(t, 401, response.Result().StatusCode)
+	})
+
+	t.Run("Explains why the email can't sign up again yet", func(t *testing.T) {
+		email := randTestEmail()
+		cookies := signUserUp(email, testPassword)
+		postDeleteAccount(cookies, url.Values{"password": {testPassword}})
+
+		response := postRouted("/signup", url.Values{"email": {email}, "password": {testPassword}})
+
+		assert.Equal(t, http.StatusConflict, response.Result().StatusCode)
+		assert.Contains(t, response.Body.String(), "was deleted, and is kept until")
+	})
+}
+
+func TestPurgeDeletedUsers(t *testing.T) {
+	ctx := context.Background()
+
+	ownerCookies := signUserUp(randTestEmail(), testPassword)
+	ownerToken := findCookie(ownerCookies, "token")
+	_, ownerID := callProtected(ownerToken)
+	solePetID := createPetForUser(t, ownerToken, "Rex")
+	sharedPetID := createPetForUser(t, ownerToken, "Fido")
+
+	_, coOwnerID := callProtected(findCookie(signUserUp(randTestEmail(), testPassword), "token"))
+	_, err := DbQueries.CreateUserPet(ctx, database.CreateUserPetParams{
+		Userid:           int32(coOwnerID),
+		Petid:            int32(sharedPetID),
+		PermissionsLevel: PermissionsCoOwner,
+		Active:           true,
+	})
+	assert.Nil(t, err)
+
+	response := postDeleteAccount(ownerCookies, url.Values{"password": {testPassword}})
+	assert.Equal(t, 200, response.Result().StatusCode)
+
+	apiCfg := createConfig()
+
+	// Nothing is purged during the grace period.
+	_, err = apiCfg.PurgeDeletedUsers(ctx, time.Now())
+	assert.Nil(t, err)
+	_, err = DbQueries.GetUser(ctx, int32(ownerID))
+	assert.Nil(t, err)
+
+	purged, err := apiCfg.PurgeDeletedUsers(ctx, time.Now().Add(accountDeletionGracePeriod+time.Hour))
+	assert.Nil(t, err)
+	assert.GreaterOrEqual(t, purged, 1)
+
+	_, err = DbQueries.GetUser(ctx, int32(ownerID))
+	assert.True(t, isNotFound(err))
+
+	_, err = DbQueries.GetPet(ctx, int32(solePetID))
+	assert.True(t, isNotFound(err))
+
+	userPet, err := DbQueries.GetUserPet(ctx, database.GetUserPetParams{
+		Userid: int32(coOwnerID),
+		Petid:  int32(sharedPetID),
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, PermissionsOwner, userPet.PermissionsLevel)
+}
@@ -0,0 +1,84 @@
+package api
+
+import (
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"github.com/ctiller15/tailscribe/internal/mailer"
+)
+
+type AdminTwoFactorPageData struct {
+	BasePageData
+	Email string
+	Done  bool
+	Error string
+}
+
+func twoFactorResetEmail(to string) mailer.Message {
+	return mailer.Message{
+		To:      to,
+		Subject: "Two-factor authentication was turned off",
+		Body: "At your request, TailScribe support turned off two-factor authentication " +
+			"for your account and signed you out everywhere.\n\n" +
+			"You can log in with just your password now. Set up two-factor again from your account page.\n\n" +
+			"If you didn't ask for this, reply to this email right away.\n",
+	}
+}
+
+func (a *APIConfig) renderAdminTwoFactor(w http.ResponseWriter, r *http.Request, status int, data AdminTwoFactorPageData) {
+	data.BasePageData = basePageData(r, "TailScribe - Reset Two-Factor")
+
+	a.render(w, r, status, "admin_two_factor", data)
+}
+
+func (a *APIConfig) HandleGetAdminTwoFactor(w http.ResponseWriter, r *http.Request, user_id int) {
+	a.renderAdminTwoFactor(w, r, http.StatusOK, AdminTwoFactorPageData{})
+}
+
+// Turns off two-factor for a user who has lost their authenticator and
+// recovery codes, once support has checked who they are. Their sessions are
+// revoked and they're told by email.
+func (a *APIConfig) HandlePostAdminTwoFactorReset(w http.ResponseWriter, r *http.Request, user_id int) {
+	ctx := r.Context()
+	data := AdminTwoFactorPageData{Email: strings.TrimSpace(r.FormValue("email"))}
+
+	user, err := a.Db.GetUserByEmail(ctx, data.Email)
+	if err != nil {
+		if !isNotFound(err) {
+			a.serverError(w, r, "error loading user", err)
+			return
+		}
+		data.Error = "No account uses that email."
+		a.renderAdminTwoFactor(w, r, http.StatusNotFound, data)
+		return
+	}
+
+	if !user.TotpSecret.Valid {
+		data.Error = "That account doesn't have two-factor turned on."
+		a.renderAdminTwoFactor(w, r, http.StatusBadRequest, data)
+		return
+	}
+
+	err = a.disableTwoFactor(ctx, user.ID)
+	if err != nil {
+		a.serverError(w, r, "error disabling two-factor", err)
+		return
+	}
+
+	err = revokeAllSessions(ctx, &a.Db, user.ID)
+	if err != nil {
+		a.serverError(w, r, "error revoking sessions", err)
+		return
+	}
+
+	a.Logger.Info("admin reset two-factor", slog.Int("admin_id", user_id), slog.Int("user_id", int(user.ID)))
+
+	err = a.Mailer.Send(ctx, twoFactorResetEmail(user.Email.String))
+	if err != nil {
+		a.Logger.Error("error sending two-factor reset email", slog.String("error", err.Error()))
+	}
+
+	data.Done = true
+	a.renderAdminTwoFactor(w, r, http.StatusOK, data)
+}
@@ -1,76 +1,135 @@
 package api
 
 import (
+	"context"
+	"crypto/rand"
+	"database/sql"
 	"fmt"
+	"io/fs"
 	"log/slog"
 	"os"
+	"sync"
+	"sync/atomic"
 
+	"github.com/ctiller15/tailscribe/internal/auth"
 	"github.com/ctiller15/tailscribe/internal/database"
+	"github.com/ctiller15/tailscribe/internal/mailer"
+	"github.com/ctiller15/tailscribe/internal/oauth"
+	"github.com/ctiller15/tailscribe/internal/ratelimit"
+	"github.com/ctiller15/tailscribe/internal/render"
+	"github.com/ctiller15/tailscribe/ui"
 )
 
-type DatabaseEnv struct {
-	Name     string
-	User     string
-	Password string
-	Host     string
-	Port     string
+type APIConfig struct {
+	Env EnvVars
+	// The connection pool behind Db, for health checks.
+	Pool   *sql.DB
+	Db     database.Queries
+	Logger *slog.Logger
+	Mailer mailer.Mailer
+	// Social login providers by name.
+	Providers map[string]oauth.Provider
+	// Shared by RateLimits.
+	RateLimitStore ratelimit.Store
+	RateLimits     RateLimiters
+	// AES key for two-factor secrets.
+	TwoFactorKey []byte
+	// How passwords are checked and hashed.
+	Passwords auth.PasswordPolicy
+	// The HTML pages.
+	Templates *render.Renderer
+	// Set once the startup checks pass. See RunStartupChecks.
+	ready atomic.Bool
+	// A hash of no one's password, checked at login when the account doesn't
+	// exist or has no password. Made on first use, since hashing is slow.
+	dummyPasswordHash func() string
 }
 
-type EnvVars struct {
-	Addr               string
-	ContactEmail       string
-	Database           DatabaseEnv
-	Secret             string
-	ImageKitPrivateKey string
-}
+func NewAPIConfig(env *EnvVars, db *sql.DB, logger *slog.Logger) (*APIConfig, error) {
+	queries := database.New(db)
+
+	templates, err := newRenderer(env.TemplateReload)
+	if err != nil {
+		return nil, fmt.Errorf("loading templates: %w", err)
+	}
+
+	m, err := mailer.New(env.Mailer, env.MailDir, env.MailFrom, logger)
+	if err != nil {
+		return nil, fmt.Errorf("setting up email: %w", err)
+	}
+
+	providers := map[string]oauth.Provider{}
+	if env.FacebookClientID != "" {
+		providers["facebook"] = oauth.NewFacebook(env.FacebookClientID, env.FacebookClientSecret)
+	}
+	if env.OIDCIssuer != "" && env.OIDCClientID != "" {
+		providers["oidc"] = oauth.NewOIDC(env.OIDCIssuer, env.OIDCClientID, env.OIDCClientSecret, env.OIDCName)
+	}
+
+	store, err := newRateLimitStore(env.RateLimitStore, queries)
+	if err != nil {
+		return nil, fmt.Errorf("setting up rate limits: %w", err)
+	}
 
-func NewEnvVars() *EnvVars {
-	addr := os.Getenv("PORT")
-	contactEmail := os.Getenv("CONTACT_EMAIL")
-	dbName := os.Getenv("POSTGRES_DB")
-	dbUser := os.Getenv("POSTGRES_USER")
-	dbPassword := os.Getenv("POSTGRES_PASSWORD")
-	dbHost := os.Getenv("POSTGRES_HOST")
-	dbPort := os.Getenv("POSTGRES_PORT")
-	secret := os.Getenv("SECRET")
-	imageKitPrivateKey := os.Getenv("IMAGE_KIT_PRIVATE_KEY")
-
-	return &EnvVars{
-		Addr:         addr,
-		ContactEmail: contactEmail,
-		Database: DatabaseEnv{
-			Name:     dbName,
-			User:     dbUser,
-			Password: dbPassword,
-			Host:     dbHost,
-			Port:     dbPort,
-		},
-		Secret:             secret,
-		ImageKitPrivateKey: imageKitPrivateKey,
+	twoFactorKey := auth.DeriveEncryptionKey(env.Secret)
+	if env.TwoFactorKey != "" {
+		twoFactorKey, err = auth.ParseEncryptionKey(env.TwoFactorKey)
+		if err != nil {
+			return nil, fmt.Errorf("TWO_FACTOR_KEY: %w", err)
+		}
 	}
+
+	return &APIConfig{
+		Env:            *env,
+		Pool:           db,
+		Db:             *queries,
+		Logger:         logger,
+		Mailer:         m,
+		Providers:      providers,
+		RateLimitStore: store,
+		RateLimits:     newRateLimiters(store),
+		TwoFactorKey:   twoFactorKey,
+		Passwords:      env.Passwords,
+		Templates:      templates,
+		dummyPasswordHash: sync.OnceValue(func() string {
+			// Can't fail for a policy that passed Check.
+			hash, _ := env.Passwords.HashPassword(rand.Text())
+			return hash
+		}),
+	}, nil
 }
 
-type APIConfig struct {
-	Env    EnvVars
-	Db     database.Queries
-	Logger *slog.Logger
+// An absolute URL on this site, for links opened elsewhere, such as in emails.
+// It's built from BASE_URL, never from the request's Host, which the client controls.
+func (a *APIConfig) siteURL(path string) string {
+	return a.Env.BaseURL + path
 }
 
-func NewAPIConfig(env *EnvVars, db *database.Queries, logger *slog.Logger) *APIConfig {
-	return &APIConfig{
-		Env:    *env,
-		Db:     *db,
-		Logger: logger,
+// Runs fn in a transaction, committing if it returns nil and rolling back otherwise.
+func (a *APIConfig) inTx(ctx context.Context, fn func(q *database.Queries) error) error {
+	tx, err := a.Pool.BeginTx(ctx, nil)
+	if err != nil {
+		return err
 	}
+	defer tx.Rollback()
+
+	err = fn(a.Db.WithTx(tx))
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
 }
 
-// Creates the connection string for the database instance
-func (d *DatabaseEnv) ConnectionString() string {
-	return fmt.Sprintf("postgresql://%s:%s@%s:%s/%s",
-		d.User,
-		d.Password,
-		d.Host,
-		d.Port,
-		d.Name,
-	)
+// Uses the templates built into the binary, or when reloading, the ones on disk.
+func newRenderer(reload bool) (*render.Renderer, error) {
+	if reload {
+		return render.New(os.DirFS("./ui/html"), true)
+	}
+
+	html, err := fs.Sub(ui.Files, "html")
+	if err != nil {
+		return nil, err
+	}
+	return render.New(html, false)
 }
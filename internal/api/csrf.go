@@ -0,0 +1,131 @@
+package api
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"net/http"
+
+	"github.com/ctiller15/tailscribe/internal/auth"
+)
+
+const (
+	// Holds a random value naming the browser until it signs in. The __Host-
+	// prefix keeps subdomains from setting it.
+	csrfCookie = "__Host-csrf_token"
+	// The form field every POST form carries the token in.
+	csrfField = "csrf_token"
+	// Scripts send the token in this header instead.
+	csrfHeader = "X-CSRF-Token"
+)
+
+type csrfContextKey struct{}
+
+var errFormExpired = &AppError{
+	Status:  http.StatusForbidden,
+	Title:   "Form Expired",
+	Heading: "That form has expired",
+	Message: "We couldn't tell that this came from a TailScribe page, so nothing was changed. Go back, refresh the page and try again.",
+	Err:     errors.New("csrf token mismatch"),
+}
+
+// The CSRF token for the request's browser session, for forms to submit back.
+func CSRFToken(r *http.Request) string {
+	token, _ := r.Context().Value(csrfContextKey{}).(string)
+	return token
+}
+
+func basePageData(r *http.Request, title string) BasePageData {
+	return BasePageData{
+		Title:     title,
+		CSRFToken: CSRFToken(r),
+	}
+}
+
+func validPreAuthToken(token string) bool {
+	decoded, err := hex.DecodeString(token)
+	return err == nil && len(decoded) == 32
+}
+
+// What the request's CSRF token is derived from: its session once the browser
+// has signed in, or else the value in its csrf cookie. Empty if it has neither.
+func (a *APIConfig) csrfBinding(r *http.Request) string {
+	cookie, err := r.Cookie("token")
+	if err == nil {
+		sessionID, err := auth.JWTSessionID(cookie.Value, a.Env.Secret)
+		if err == nil {
+			return "session:" + sessionID.String()
+		}
+	}
+
+	cookie, err = r.Cookie(csrfCookie)
+	if err == nil && validPreAuthToken(cookie.Value) {
+		return "browser:" + cookie.Value
+	}
+
+	return ""
+}
+
+// Gives the browser a new csrf cookie, so tokens from before a login or
+// logout stop working. Returns the binding for the new cookie.
+func rotateCSRFCookie(w http.ResponseWriter) (string, error) {
+	value, err := auth.MakePreAuthToken()
+	if err != nil {
+		return "", err
+	}
+
+	// Lax, so the cookie survives arriving from another site, such as a social login.
+	http.SetCookie(w, &http.Cookie{
+		Name:     csrfCookie,
+		Value:    value,
+		Path:     "/",
+		HttpOnly: true,
+		Secure:   true,
+		SameSite: http.SameSiteLaxMode,
+	})
+
+	return "browser:" + value, nil
+}
+
+func isSafeMethod(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace:
+		return true
+	default:
+		return false
+	}
+}
+
+// Gives each browser a CSRF token, an HMAC of its session or csrf cookie, and
+// rejects any unsafe request that doesn't send the same token back in its form
+// or the X-CSRF-Token header. Another site can make the browser send its
+// cookies, but can't read them or the page to fill in the form.
+func (a *APIConfig) CSRFMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		binding := a.csrfBinding(r)
+		if binding == "" {
+			var err error
+			binding, err = rotateCSRFCookie(w)
+			if err != nil {
+				a.serverError(w, r, "error creating csrf cookie", err)
+				return
+			}
+		}
+		token := auth.MakeCSRFToken(binding, a.Env.Secret)
+
+		if !isSafeMethod(r.Method) {
+			submitted := r.Header.Get(csrfHeader)
+			if submitted == "" {
+				submitted = r.PostFormValue(csrfField)
+			}
+
+			if subtle.ConstantTimeCompare([]byte(submitted), []byte(token)) != 1 {
+				a.serveError(w, r.WithContext(context.WithValue(r.Context(), csrfContextKey{}, token)), errFormExpired)
+				return
+			}
+		}
+
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), csrfContextKey{}, token)))
+	})
+}
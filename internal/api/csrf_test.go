@@ -0,0 +1,172 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/ctiller15/tailscribe/internal/auth"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+// Sends a request through the CSRF middleware, returning the response and
+// the token the wrapped handler saw ("" if it wasn't called).
+func callWithCSRF(request *http.Request) (*httptest.ResponseRecorder, string) {
+	response := httptest.NewRecorder()
+
+	seenToken := ""
+	apiCfg := createConfig()
+	apiCfg.CSRFMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seenToken = CSRFToken(r)
+		if seenToken == "" {
+			seenToken = "called without a token"
+		}
+	})).ServeHTTP(response, request)
+
+	return response, seenToken
+}
+
+// The token a browser with the given csrf cookie should be sending.
+func csrfTokenFor(cookieValue string) string {
+	return auth.MakeCSRFToken("browser:"+cookieValue, TestEnvVars.Secret)
+}
+
+func postWithCSRF(formToken string, cookieToken string) *http.Request {
+	formData := url.Values{"name": {"Rex"}}
+	if formToken != "" {
+		formData.Set(csrfField, formToken)
+	}
+
+	request, _ := http.NewRequest(http.MethodPost, "/dashboard/add_new_pet", strings.NewReader(formData.Encode()))
+	request.Header.Add("Content-Type", "application/x-www-form-urlencoded")
+	if cookieToken != "" {
+		request.AddCookie(&http.Cookie{Name: csrfCookie, Value: cookieToken})
+	}
+	return request
+}
+
+func TestCSRFMiddleware(t *testing.T) {
+	cookieValue := strings.Repeat("ab", 32)
+	token := csrfTokenFor(cookieValue)
+
+	t.Run("Issues a token on the first visit", func(t *testing.T) {
+		request, _ := http.NewRequest(http.MethodGet, "/login", nil)
+
+		response, seenToken := callWithCSRF(request)
+
+		assert.Equal(t, 200, response.Result().StatusCode)
+		cookie := findCookie(response.Result().Cookies(), csrfCookie)
+		assert.NotNil(t, cookie)
+		assert.True(t, validPreAuthToken(cookie.Value))
+		assert.Equal(t, csrfTokenFor(cookie.Value), seenToken)
+	})
+
+	t.Run("Keeps the browser's existing token", func(t *testing.T) {
+		request, _ := http.NewRequest(http.MethodGet, "/login", nil)
+		request.AddCookie(&http.Cookie{Name: csrfCookie, Value: cookieValue})
+
+		response, seenToken := callWithCSRF(request)
+
+		assert.Nil(t, findCookie(response.Result().Cookies(), csrfCookie))
+		assert.Equal(t, token, seenToken)
+	})
+
+	t.Run("Accepts a form with the matching token", func(t *testing.T) {
+		response, seenToken := callWithCSRF(postWithCSRF(token, cookieValue))
+
+		assert.Equal(t, 200, response.Result().StatusCode)
+		assert.Equal(t, token, seenToken)
+	})
+
+	t.Run("Accepts the token in a header", func(t *testing.T) {
+		request := postWithCSRF("", cookieValue)
+		request.Header.Set(csrfHeader, token)
+
+		response, seenToken := callWithCSRF(request)
+
+		assert.Equal(t, 200, response.Result().StatusCode)
+		assert.Equal(t, token, seenToken)
+	})
+
+	t.Run("Rejects a form without a token", func(t *testing.T) {
+		response, seenToken := callWithCSRF(postWithCSRF("", cookieValue))
+
+		assert.Equal(t, 403, response.Result().StatusCode)
+		assert.Equal(t, "", seenToken)
+		assert.Contains(t, response.Body.String(), "That form has expired")
+	})
+
+	t.Run("Rejects a mismatched token", func(t *testing.T) {
+		response, seenToken := callWithCSRF(postWithCSRF(csrfTokenFor(strings.Repeat("cd", 32)), cookieValue))
+
+		assert.Equal(t, 403, response.Result().StatusCode)
+		assert.Equal(t, "", seenToken)
+	})
+
+	t.Run("Rejects a form when the browser has no token", func(t *testing.T) {
+		response, seenToken := callWithCSRF(postWithCSRF(token, ""))
+
+		assert.Equal(t, 403, response.Result().StatusCode)
+		assert.Equal(t, "", seenToken)
+	})
+
+	t.Run("Rejects the cookie's value in place of the token", func(t *testing.T) {
+		response, _ := callWithCSRF(postWithCSRF(cookieValue, cookieValue))
+
+		assert.Equal(t, 403, response.Result().StatusCode)
+	})
+
+	t.Run("Ties a signed in browser's token to its session", func(t *testing.T) {
+		sessionJWT := func(sessionID uuid.UUID) *http.Cookie {
+			tokenString, err := auth.MakeJWT(1, sessionID, TestEnvVars.Secret)
+			assert.NoError(t, err)
+			return &http.Cookie{Name: "token", Value: tokenString}
+		}
+		sessionID := uuid.New()
+		sessionToken := auth.MakeCSRFToken("session:"+sessionID.String(), TestEnvVars.Secret)
+
+		request := postWithCSRF(sessionToken, cookieValue)
+		request.AddCookie(sessionJWT(sessionID))
+		response, seenToken := callWithCSRF(request)
+		assert.Equal(t, 200, response.Result().StatusCode)
+		assert.Equal(t, sessionToken, seenToken)
+
+		// Neither the pre-login token nor another session's works.
+		request = postWithCSRF(token, cookieValue)
+		request.AddCookie(sessionJWT(sessionID))
+		response, _ = callWithCSRF(request)
+		assert.Equal(t, 403, response.Result().StatusCode)
+
+		request = postWithCSRF(sessionToken, cookieValue)
+		request.AddCookie(sessionJWT(uuid.New()))
+		response, _ = callWithCSRF(request)
+		assert.Equal(t, 403, response.Result().StatusCode)
+	})
+
+	t.Run("Covers handlers registered on a mux", func(t *testing.T) {
+		apiCfg := createConfig()
+		mux := http.NewServeMux()
+		mux.HandleFunc("POST /logout", apiCfg.HandlePostLogout)
+
+		request, _ := http.NewRequest(http.MethodPost, "/logout", nil)
+		request.AddCookie(&http.Cookie{Name: csrfCookie, Value: cookieValue})
+		response := httptest.NewRecorder()
+		apiCfg.CSRFMiddleware(mux).ServeHTTP(response, request)
+
+		assert.Equal(t, 403, response.Result().StatusCode)
+	})
+}
+
+func TestLoginFormCarriesCSRFToken(t *testing.T) {
+	request, _ := http.NewRequest(http.MethodGet, "/login", nil)
+	request.AddCookie(&http.Cookie{Name: csrfCookie, Value: strings.Repeat("ab", 32)})
+	response := httptest.NewRecorder()
+
+	apiCfg := createConfig()
+	apiCfg.CSRFMiddleware(http.HandlerFunc(apiCfg.HandleLoginPage)).ServeHTTP(response, request)
+
+	assert.Contains(t, response.Body.String(), `name="csrf_token" value="`+csrfTokenFor(strings.Repeat("ab", 32))+`"`)
+}
@@ -0,0 +1,448 @@
+package api
+
+import (
+	"archive/zip"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/ctiller15/tailscribe/internal/database"
+)
+
+// Everything we hold about a user, as written to their data export.
+// Only the fields listed here are exported, so secrets like password and
+// token hashes never leave the database.
+type personalData struct {
+	Profile profileExport
+	Pets    []petExport
+}
+
+type profileExport struct {
+	ID                int32      `json:"id"`
+	Email             *string    `json:"email"`
+	EmailVerifiedAt   *time.Time `json:"email_verified_at"`
+	Username          *string    `json:"username"`
+	FirstName         *string    `json:"first_name"`
+	LastName          *string    `json:"last_name"`
+	HasPassword       bool       `json:"has_password"`
+	FacebookConnected bool       `json:"facebook_connected"`
+	OIDCIssuer        *string    `json:"oidc_issuer"`
+	TwoFactorEnabled  bool       `json:"two_factor_enabled"`
+	IsPremium         bool       `json:"is_premium"`
+	PremiumLevel      int32      `json:"premium_level"`
+	CreatedAt         time.Time  `json:"created_at"`
+	UpdatedAt         time.Time  `json:"updated_at"`
+	DeletedAt         *time.Time `json:"deleted_at"`
+}
+
+type petExport struct {
+	Pet              petRecordExport
+	TrainingSessions []trainingSessionExport
+	Skills           []skillExport
+	Goals            []goalExport
+	Titles           []titleExport
+	Likes            []likeExport
+}
+
+type petRecordExport struct {
+	ID                 int32      `json:"id"`
+	Name               string     `json:"name"`
+	Slug               string     `json:"slug"`
+	DateOfBirth        *time.Time `json:"date_of_birth"`
+	DateOfBirthExact   *bool      `json:"date_of_birth_exact"`
+	ImageURL           *string    `json:"image_url"`
+	About              *string    `json:"about"`
+	Species            *string    `json:"species"`
+	Breed              *string    `json:"breed"`
+	Sex                *string    `json:"sex"`
+	IsPubliclyViewable bool       `json:"is_publicly_viewable"`
+	PermissionsLevel   int32      `json:"permissions_level"`
+	Active             bool       `json:"active"`
+	Hidden             bool       `json:"hidden"`
+	CreatedAt          time.Time  `json:"created_at"`
+	UpdatedAt          time.Time  `json:"updated_at"`
+}
+
+type trainingSessionExport struct {
+	ID              int32     `json:"id"`
+	UserID          *int32    `json:"user_id"`
+	StartedAt       time.Time `json:"started_at"`
+	DurationMinutes int32     `json:"duration_minutes"`
+	Location        *string   `json:"location"`
+	Notes           *string   `json:"notes"`
+	Behaviors       []string  `json:"behaviors"`
+	Reinforcers     []string  `json:"reinforcers"`
+	CreatedAt       time.Time `json:"created_at"`
+	UpdatedAt       time.Time `json:"updated_at"`
+}
+
+type skillExport struct {
+	ID           int32               `json:"id"`
+	Name         string              `json:"name"`
+	Stage        string              `json:"stage"`
+	StageChanges []stageChangeExport `json:"stage_changes"`
+	CreatedAt    time.Time           `json:"created_at"`
+	UpdatedAt    time.Time           `json:"updated_at"`
+}
+
+type stageChangeExport struct {
+	FromStage *string   `json:"from_stage"`
+	ToStage   string    `json:"to_stage"`
+	SessionID *int32    `json:"session_id"`
+	UserID    *int32    `json:"user_id"`
+	ChangedAt time.Time `json:"changed_at"`
+}
+
+type goalExport struct {
+	ID            int32                `json:"id"`
+	Description   string               `json:"description"`
+	Status        string               `json:"status"`
+	TargetDate    *time.Time           `json:"target_date"`
+	AchievedAt    *time.Time           `json:"achieved_at"`
+	Skills        []string             `json:"skills"`
+	ProgressNotes []progressNoteExport `json:"progress_notes"`
+	CreatedAt     time.Time            `json:"created_at"`
+	UpdatedAt     time.Time            `json:"updated_at"`
+}
+
+type progressNoteExport struct {
+	UserID    *int32    `json:"user_id"`
+	Note      string    `json:"note"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type titleExport struct {
+	Name                string    `json:"name"`
+	Abbreviation        *string   `json:"abbreviation"`
+	Position            string    `json:"position"`
+	Organization        *string   `json:"organization"`
+	EarnedOn            time.Time `json:"earned_on"`
+	CertificateImageURL *string   `json:"certificate_image_url"`
+	CreatedAt           time.Time `json:"created_at"`
+}
+
+type likeExport struct {
+	Name      string    `json:"name"`
+	Category  string    `json:"category"`
+	ValueTier string    `json:"value_tier"`
+	Notes     *string   `json:"notes"`
+	Rank      int32     `json:"rank"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+func stringOrNil(value sql.NullString) *string {
+	if !value.Valid {
+		return nil
+	}
+	return &value.String
+}
+
+func timeOrNil(value sql.NullTime) *time.Time {
+	if !value.Valid {
+		return nil
+	}
+	return &value.Time
+}
+
+func int32OrNil(value sql.NullInt32) *int32 {
+	if !value.Valid {
+		return nil
+	}
+	return &value.Int32
+}
+
+func boolOrNil(value sql.NullBool) *bool {
+	if !value.Valid {
+		return nil
+	}
+	return &value.Bool
+}
+
+func exportProfile(user database.User) profileExport {
+	return profileExport{
+		ID:                user.ID,
+		Email:             stringOrNil(user.Email),
+		EmailVerifiedAt:   timeOrNil(user.EmailVerifiedAt),
+		Username:          stringOrNil(user.Username),
+		FirstName:         stringOrNil(user.Firstname),
+		LastName:          stringOrNil(user.Lastname),
+		HasPassword:       user.Password.Valid,
+		FacebookConnected: user.FacebookID.Valid,
+		OIDCIssuer:        stringOrNil(user.OidcIssuer),
+		TwoFactorEnabled:  user.TotpEnabledAt.Valid,
+		IsPremium:         user.IsPremium,
+		PremiumLevel:      user.PremiumLevel,
+		CreatedAt:         user.CreatedAt,
+		UpdatedAt:         user.UpdatedAt,
+		DeletedAt:         timeOrNil(user.DeletedAt),
+	}
+}
+
+func exportPetRecord(row database.ListPetsForUserRow) petRecordExport {
+	return petRecordExport{
+		ID:                 row.Pet.ID,
+		Name:               row.Pet.Name,
+		Slug:               row.Pet.Slug,
+		DateOfBirth:        timeOrNil(row.Pet.Dateofbirth),
+		DateOfBirthExact:   boolOrNil(row.Pet.Dateofbirthexact),
+		ImageURL:           stringOrNil(row.Pet.Imageurl),
+		About:              stringOrNil(row.Pet.AboutText),
+		Species:            stringOrNil(row.Pet.Species),
+		Breed:              stringOrNil(row.Pet.Breed),
+		Sex:                stringOrNil(row.Pet.Sex),
+		IsPubliclyViewable: row.Pet.Ispubliclyviewable,
+		PermissionsLevel:   row.PermissionsLevel,
+		Active:             row.Active,
+		Hidden:             row.Hidden,
+		CreatedAt:          row.Pet.CreatedAt,
+		UpdatedAt:          row.Pet.UpdatedAt,
+	}
+}
+
+// Loads the user's profile and every pet they're linked to, with its training records.
+func (a *APIConfig) collectPersonalData(ctx context.Context, userID int32) (personalData, error) {
+	user, err := a.Db.GetUser(ctx, userID)
+	if err != nil {
+		return personalData{}, err
+	}
+
+	rows, err := a.Db.ListPetsForUser(ctx, userID)
+	if err != nil {
+		return personalData{}, err
+	}
+
+	data := personalData{
+		Profile: exportProfile(user),
+		Pets:    make([]petExport, 0, len(rows)),
+	}
+
+	for _, row := range rows {
+		pet, err := a.collectPetData(ctx, row)
+		if err != nil {
+			return personalData{}, err
+		}
+		data.Pets = append(data.Pets, pet)
+	}
+
+	return data, nil
+}
+
+func (a *APIConfig) collectPetData(ctx context.Context, row database.ListPetsForUserRow) (petExport, error) {
+	petID := row.Pet.ID
+	pet := petExport{
+		Pet:              exportPetRecord(row),
+		TrainingSessions: []trainingSessionExport{},
+		Skills:           []skillExport{},
+		Goals:            []goalExport{},
+		Titles:           []titleExport{},
+		Likes:            []likeExport{},
+	}
+
+	sessions, err := a.Db.ListTrainingSessionsForPet(ctx, petID)
+	if err != nil {
+		return petExport{}, err
+	}
+
+	behaviors, err := a.Db.ListTrainingSessionBehaviorsForPet(ctx, petID)
+	if err != nil {
+		return petExport{}, err
+	}
+	behaviorsBySession := map[int32][]string{}
+	for _, behavior := range behaviors {
+		behaviorsBySession[behavior.SessionID] = append(behaviorsBySession[behavior.SessionID], behavior.Name)
+	}
+
+	sessionLikes, err := a.Db.ListTrainingSessionLikesForPet(ctx, petID)
+	if err != nil {
+		return petExport{}, err
+	}
+	reinforcersBySession := map[int32][]string{}
+	for _, like := range sessionLikes {
+		reinforcersBySession[like.SessionID] = append(reinforcersBySession[like.SessionID], like.Name)
+	}
+
+	for _, session := range sessions {
+		pet.TrainingSessions = append(pet.TrainingSessions, trainingSessionExport{
+			ID:              session.ID,
+			UserID:          int32OrNil(session.UserID),
+			StartedAt:       session.StartedAt,
+			DurationMinutes: session.DurationMinutes,
+			Location:        stringOrNil(session.Location),
+			Notes:           stringOrNil(session.Notes),
+			Behaviors:       append([]string{}, behaviorsBySession[session.ID]...),
+			Reinforcers:     append([]string{}, reinforcersBySession[session.ID]...),
+			CreatedAt:       session.CreatedAt,
+			UpdatedAt:       session.UpdatedAt,
+		})
+	}
+
+	skills, err := a.Db.ListSkillsForPet(ctx, petID)
+	if err != nil {
+		return petExport{}, err
+	}
+	for _, skill := range skills {
+		changes, err := a.Db.ListSkillStageChanges(ctx, skill.ID)
+		if err != nil {
+			return petExport{}, err
+		}
+
+		exported := skillExport{
+			ID:           skill.ID,
+			Name:         skill.Name,
+			Stage:        skill.Stage,
+			StageChanges: make([]stageChangeExport, 0, len(changes)),
+			CreatedAt:    skill.CreatedAt,
+			UpdatedAt:    skill.UpdatedAt,
+		}
+		for _, change := range changes {
+			exported.StageChanges = append(exported.StageChanges, stageChangeExport{
+				FromStage: stringOrNil(change.FromStage),
+				ToStage:   change.ToStage,
+				SessionID: int32OrNil(change.SessionID),
+				UserID:    int32OrNil(change.UserID),
+				ChangedAt: change.ChangedAt,
+			})
+		}
+		pet.Skills = append(pet.Skills, exported)
+	}
+
+	goals, err := a.Db.ListGoalsForPet(ctx, petID)
+	if err != nil {
+		return petExport{}, err
+	}
+	for _, goal := range goals {
+		goalSkills, err := a.Db.ListGoalSkills(ctx, goal.ID)
+		if err != nil {
+			return petExport{}, err
+		}
+
+		notes, err := a.Db.ListGoalProgressNotes(ctx, goal.ID)
+		if err != nil {
+			return petExport{}, err
+		}
+
+		exported := goalExport{
+			ID:            goal.ID,
+			Description:   goal.Description,
+			Status:        goal.Status,
+			TargetDate:    timeOrNil(goal.TargetDate),
+			AchievedAt:    timeOrNil(goal.AchievedAt),
+			Skills:        make([]string, 0, len(goalSkills)),
+			ProgressNotes: make([]progressNoteExport, 0, len(notes)),
+			CreatedAt:     goal.CreatedAt,
+			UpdatedAt:     goal.UpdatedAt,
+		}
+		for _, skill := range goalSkills {
+			exported.Skills = append(exported.Skills, skill.Name)
+		}
+		for _, note := range notes {
+			exported.ProgressNotes = append(exported.ProgressNotes, progressNoteExport{
+				UserID:    int32OrNil(note.UserID),
+				Note:      note.Note,
+				CreatedAt: note.CreatedAt,
+			})
+		}
+		pet.Goals = append(pet.Goals, exported)
+	}
+
+	titles, err := a.Db.ListTitlesForPet(ctx, petID)
+	if err != nil {
+		return petExport{}, err
+	}
+	for _, title := range titles {
+		pet.Titles = append(pet.Titles, titleExport{
+			Name:                title.Name,
+			Abbreviation:        stringOrNil(title.Abbreviation),
+			Position:            title.Position,
+			Organization:        stringOrNil(title.Organization),
+			EarnedOn:            title.EarnedOn,
+			CertificateImageURL: stringOrNil(title.CertificateImageUrl),
+			CreatedAt:           title.CreatedAt,
+		})
+	}
+
+	likes, err := a.Db.ListLikesForPet(ctx, petID)
+	if err != nil {
+		return petExport{}, err
+	}
+	for _, like := range likes {
+		pet.Likes = append(pet.Likes, likeExport{
+			Name:      like.Name,
+			Category:  like.Category,
+			ValueTier: like.ValueTier,
+			Notes:     stringOrNil(like.Notes),
+			Rank:      like.Rank,
+			CreatedAt: like.CreatedAt,
+		})
+	}
+
+	return pet, nil
+}
+
+func writeJSONFile(archive *zip.Writer, name string, v any) error {
+	file, err := archive.Create(name)
+	if err != nil {
+		return err
+	}
+
+	encoder := json.NewEncoder(file)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(v)
+}
+
+// Writes the export as a ZIP with profile.json and a folder of JSON files per pet.
+func writePersonalDataZip(w io.Writer, data personalData) error {
+	archive := zip.NewWriter(w)
+
+	err := writeJSONFile(archive, "profile.json", data.Profile)
+	if err != nil {
+		return err
+	}
+
+	for _, pet := range data.Pets {
+		dir := fmt.Sprintf("pets/%d-%s/", pet.Pet.ID, pet.Pet.Slug)
+		files := []struct {
+			name string
+			v    any
+		}{
+			{"pet.json", pet.Pet},
+			{"training_sessions.json", pet.TrainingSessions},
+			{"skills.json", pet.Skills},
+			{"goals.json", pet.Goals},
+			{"titles.json", pet.Titles},
+			{"likes.json", pet.Likes},
+		}
+		for _, file := range files {
+			err = writeJSONFile(archive, dir+file.name, file.v)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return archive.Close()
+}
+
+func (a *APIConfig) HandleGetDataExport(w http.ResponseWriter, r *http.Request, user_id int) {
+	// Everything is loaded before the response starts, so a failure can still send a proper error.
+	data, err := a.collectPersonalData(r.Context(), int32(user_id))
+	if err != nil {
+		a.serverError(w, r, "error collecting personal data", err)
+		return
+	}
+
+	filename := fmt.Sprintf("tailscribe-export-%s.zip", time.Now().Format("2006-01-02"))
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	w.Header().Set("Cache-Control", "no-store")
+
+	err = writePersonalDataZip(w, data)
+	if err != nil {
+		a.Logger.Error("error writing data export", slog.String("error", err.Error()))
+	}
+}
@@ -0,0 +1,132 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/ctiller15/tailscribe/internal/auth"
+	"github.com/ctiller15/tailscribe/internal/database"
+	"github.com/ctiller15/tailscribe/internal/mailer"
+)
+
+// How long an email verification link works for.
+const emailVerificationTTL = 48 * time.Hour
+
+type VerifyEmailPageData struct {
+	BasePageData
+	// The link worked and the email is now verified.
+	Verified bool
+	// The link is invalid, expired, or for an address the account no longer uses.
+	Expired bool
+	// A new link was just sent.
+	Sent bool
+	// The user tried something that needs a verified email.
+	Required bool
+}
+
+func verificationEmail(to, link string) mailer.Message {
+	return mailer.Message{
+		To:      to,
+		Subject: "Verify your TailScribe email",
+		Body: fmt.Sprintf("Thanks for signing up to TailScribe.\n\n"+
+			"Follow this link within the next two days to verify your email address:\n\n%s\n\n"+
+			"If you didn't sign up, you can ignore this email.\n", link),
+	}
+}
+
+// Emails the user a link that verifies their current address.
+func (a *APIConfig) sendVerificationEmail(ctx context.Context, user database.User) error {
+	token, err := auth.MakeEmailVerificationToken(user.ID, user.Email.String, time.Now().Add(emailVerificationTTL), a.Env.Secret)
+	if err != nil {
+		return err
+	}
+
+	return a.Mailer.Send(ctx, verificationEmail(user.Email.String, a.siteURL("/verify-email/"+token)))
+}
+
+func (a *APIConfig) renderVerifyEmail(w http.ResponseWriter, r *http.Request, status int, data VerifyEmailPageData) {
+	data.BasePageData = basePageData(r, "TailScribe - Verify Email")
+
+	a.render(w, r, status, "verify_email", data)
+}
+
+// Reports whether the user has verified their email. If not, it shows them a
+// page explaining why and offering a new link, and the caller should stop.
+func (a *APIConfig) checkVerifiedEmail(w http.ResponseWriter, r *http.Request, userID int) bool {
+	user, err := a.Db.GetUser(r.Context(), int32(userID))
+	if err != nil {
+		a.serverError(w, r, "error loading user", err)
+		return false
+	}
+
+	if !user.EmailVerifiedAt.Valid {
+		a.renderVerifyEmail(w, r, http.StatusForbidden, VerifyEmailPageData{Required: true})
+		return false
+	}
+
+	return true
+}
+
+// Verifies the email from a link. It works without being logged in, since the
+// link may be opened on another device.
+func (a *APIConfig) HandleGetVerifyEmail(w http.ResponseWriter, r *http.Request) {
+	userID, email, err := auth.ValidateEmailVerificationToken(r.PathValue("token"), a.Env.Secret)
+	if err != nil {
+		a.renderVerifyEmail(w, r, http.StatusNotFound, VerifyEmailPageData{Expired: true})
+		return
+	}
+
+	verified, err := a.Db.MarkUserEmailVerified(r.Context(), database.MarkUserEmailVerifiedParams{
+		ID:    userID,
+		Email: email,
+	})
+	if err != nil {
+		a.serverError(w, r, "error verifying email", err)
+		return
+	}
+	if verified == 0 {
+		// The account is gone or has changed its email since the link was sent.
+		a.renderVerifyEmail(w, r, http.StatusNotFound, VerifyEmailPageData{Expired: true})
+		return
+	}
+
+	a.renderVerifyEmail(w, r, http.StatusOK, VerifyEmailPageData{Verified: true})
+}
+
+// Sends the signed in user a new verification link.
+func (a *APIConfig) HandlePostResendVerification(w http.ResponseWriter, r *http.Request, user_id int) {
+	ctx := r.Context()
+
+	user, err := a.Db.GetUser(ctx, int32(user_id))
+	if err != nil {
+		a.serverError(w, r, "error loading user", err)
+		return
+	}
+
+	if user.EmailVerifiedAt.Valid || !user.Email.Valid {
+		http.Redirect(w, r, "/account", http.StatusFound)
+		return
+	}
+
+	key := strconv.Itoa(user_id)
+	wait, err := a.RateLimits.VerificationEmail.Attempt(ctx, key)
+	if err != nil {
+		a.serverError(w, r, "error checking verification email rate limit", err)
+		return
+	}
+	if wait > 0 {
+		a.serveError(w, r, tooManyRequests(wait))
+		return
+	}
+
+	err = a.sendVerificationEmail(ctx, user)
+	if err != nil {
+		a.serverError(w, r, "error sending verification email", err)
+		return
+	}
+
+	a.renderVerifyEmail(w, r, http.StatusOK, VerifyEmailPageData{Sent: true})
+}
@@ -0,0 +1,187 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ctiller15/tailscribe/internal/auth"
+	"github.com/ctiller15/tailscribe/internal/database"
+	"github.com/ctiller15/tailscribe/internal/mailer"
+	"github.com/stretchr/testify/assert"
+)
+
+var verifyLinkPattern = regexp.MustCompile(`/verify-email/([A-Za-z0-9_.-]+)`)
+
+// Signs up a user and verifies their email, as if they'd followed the link.
+func signUpVerified(t *testing.T, email, password string) []*http.Cookie {
+	cookies := signUserUp(email, password)
+
+	apiCfg := createConfig()
+	user, err := apiCfg.Db.GetUserByEmail(context.Background(), email)
+	assert.NoError(t, err)
+	_, err = apiCfg.Db.MarkUserEmailVerified(context.Background(), database.MarkUserEmailVerifiedParams{
+		ID:    user.ID,
+		Email: email,
+	})
+	assert.NoError(t, err)
+
+	return cookies
+}
+
+func getVerifyEmail(token string) *httptest.ResponseRecorder {
+	request, _ := http.NewRequest(http.MethodGet, "/verify-email/"+token, nil)
+	request.SetPathValue("token", token)
+	response := httptest.NewRecorder()
+
+	createConfig().HandleGetVerifyEmail(response, request)
+
+	return response
+}
+
+func postPublicVisibility(authCookie *http.Cookie, petID int, public bool) *httptest.ResponseRecorder {
+	formData := url.Values{}
+	if public {
+		formData.Set("publicly_viewable", "on")
+	}
+	request, _ := http.NewRequest(http.MethodPost, "/dashboard/pet/"+strconv.Itoa(petID)+"/visibility", strings.NewReader(formData.Encode()))
+	request.Header.Add("Content-Type", "application/x-www-form-urlencoded")
+	request.SetPathValue("id", strconv.Itoa(petID))
+	request.AddCookie(authCookie)
+	response := httptest.NewRecorder()
+
+	apiCfg := createConfig()
+	apiCfg.CheckAuthMiddleware(apiCfg.HandlePostPetPublicVisibility)(response, request)
+
+	return response
+}
+
+func TestSignupSendsVerificationEmail(t *testing.T) {
+	dir := t.TempDir()
+	email := randTestEmail()
+	formData := url.Values{"email": {email}, "password": {testPassword}}
+	request, _ := http.NewRequest(http.MethodPost, "/signup", strings.NewReader(formData.Encode()))
+	request.Header.Add("Content-Type", "application/x-www-form-urlencoded")
+	request.Host = "evil.example"
+	response := httptest.NewRecorder()
+
+	apiCfg := createConfig()
+	apiCfg.Mailer = &mailer.FileMailer{Dir: dir}
+	apiCfg.HandlePostSignup(response, request)
+	assert.Equal(t, http.StatusFound, response.Result().StatusCode)
+
+	user, err := apiCfg.Db.GetUserByEmail(context.Background(), email)
+	assert.NoError(t, err)
+	assert.False(t, user.EmailVerifiedAt.Valid)
+
+	files, _ := filepath.Glob(filepath.Join(dir, "*.eml"))
+	if len(files) == 0 {
+		t.Fatal("no verification email sent")
+	}
+	contents, err := os.ReadFile(files[0])
+	assert.NoError(t, err)
+	match := verifyLinkPattern.FindStringSubmatch(string(contents))
+	if match == nil {
+		t.Fatalf("no verification link in %q", contents)
+	}
+	assert.Contains(t, string(contents), TestEnvVars.BaseURL+"/verify-email/")
+	assert.NotContains(t, string(contents), "evil.example")
+
+	assert.Equal(t, http.StatusOK, getVerifyEmail(match[1]).Result().StatusCode)
+
+	user, err = apiCfg.Db.GetUser(context.Background(), user.ID)
+	assert.NoError(t, err)
+	assert.True(t, user.EmailVerifiedAt.Valid)
+}
+
+func TestHandleGetVerifyEmail(t *testing.T) {
+	t.Run("Rejects a link for an address the account doesn't use", func(t *testing.T) {
+		email := randTestEmail()
+		signUserUp(email, testPassword)
+		user, err := createConfig().Db.GetUserByEmail(context.Background(), email)
+		assert.NoError(t, err)
+
+		token, err := auth.MakeEmailVerificationToken(user.ID, randTestEmail(), time.Now().Add(time.Hour), TestEnvVars.Secret)
+		assert.NoError(t, err)
+
+		assert.Equal(t, http.StatusNotFound, getVerifyEmail(token).Result().StatusCode)
+	})
+
+	t.Run("Rejects a tampered token", func(t *testing.T) {
+		assert.Equal(t, http.StatusNotFound, getVerifyEmail("not-a-token").Result().StatusCode)
+	})
+}
+
+func TestEmailsIgnoreCase(t *testing.T) {
+	email := randTestEmail()
+	signUserUp(strings.ToUpper(email[:1])+email[1:], testPassword)
+
+	cookies := signUserUp(email, testPassword)
+	assert.Nil(t, findCookie(cookies, "token"))
+
+	response := postLoginWith(createConfig(), "203.0.113.40:1000", strings.ToUpper(email), testPassword)
+	assert.Equal(t, "/dashboard", response.Result().Header.Get("Location"))
+}
+
+func TestUnverifiedRestrictions(t *testing.T) {
+	t.Run("Can't invite people", func(t *testing.T) {
+		cookies := signUserUp(randTestEmail(), testPassword)
+		petID := createPetForUser(t, cookies[0], "Clover")
+
+		formData := url.Values{"email": {randTestEmail()}, "permissions_level": {"1"}}
+		request, _ := http.NewRequest(http.MethodPost, "/dashboard/pet/"+strconv.Itoa(petID)+"/access/invitations", strings.NewReader(formData.Encode()))
+		request.Header.Add("Content-Type", "application/x-www-form-urlencoded")
+		request.SetPathValue("id", strconv.Itoa(petID))
+		request.AddCookie(cookies[0])
+		response := httptest.NewRecorder()
+		apiCfg := createConfig()
+		apiCfg.CheckAuthMiddleware(apiCfg.HandlePostPetInvitation)(response, request)
+
+		assert.Equal(t, http.StatusForbidden, response.Result().StatusCode)
+		assert.Contains(t, response.Body.String(), "/account/verify-email")
+	})
+
+	t.Run("Can still see who has access, with the invite form disabled", func(t *testing.T) {
+		cookies := signUserUp(randTestEmail(), testPassword)
+		petID := createPetForUser(t, cookies[0], "Clover")
+
+		request, _ := http.NewRequest(http.MethodGet, "/dashboard/pet/"+strconv.Itoa(petID)+"/access", nil)
+		request.SetPathValue("id", strconv.Itoa(petID))
+		request.AddCookie(cookies[0])
+		response := httptest.NewRecorder()
+		apiCfg := createConfig()
+		apiCfg.CheckAuthMiddleware(apiCfg.HandleGetPetAccess)(response, request)
+
+		assert.Equal(t, http.StatusOK, response.Result().StatusCode)
+		assert.Contains(t, response.Body.String(), "<fieldset disabled>")
+		assert.Contains(t, response.Body.String(), "/account/verify-email")
+	})
+
+	t.Run("Can't accept invitations", func(t *testing.T) {
+		ownerCookies := signUpVerified(t, randTestEmail(), testPassword)
+		petID := createPetForUser(t, ownerCookies[0], "Clover")
+		inviteeEmail := randTestEmail()
+		token := invite(t, ownerCookies[0], petID, inviteeEmail, PermissionsViewer)
+
+		inviteeCookies := signUserUp(inviteeEmail, testPassword)
+		result := acceptInvitation(inviteeCookies[0], token)
+
+		assert.Equal(t, http.StatusForbidden, result.StatusCode)
+	})
+
+	t.Run("Can't make a profile public, but can make it private", func(t *testing.T) {
+		cookies := signUserUp(randTestEmail(), testPassword)
+		petID := createPetForUser(t, cookies[0], "Clover")
+
+		assert.Equal(t, http.StatusForbidden, postPublicVisibility(cookies[0], petID, true).Result().StatusCode)
+		assert.Equal(t, http.StatusFound, postPublicVisibility(cookies[0], petID, false).Result().StatusCode)
+	})
+}
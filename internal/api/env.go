@@ -0,0 +1,464 @@
+package api
+
+import (
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ctiller15/tailscribe/internal/auth"
+	"github.com/joho/godotenv"
+)
+
+type DatabaseEnv struct {
+	// Overrides the separate connection settings below when set.
+	URL      string
+	Name     string
+	User     string
+	Password string
+	Host     string
+	Port     string
+	// Passed to the driver as sslmode. Empty leaves the driver's default.
+	SSLMode string
+	// Connection pool sizing. Zero open connections means no limit.
+	MaxOpenConns    int
+	MaxIdleConns    int
+	ConnMaxLifetime time.Duration
+}
+
+type EnvVars struct {
+	Addr string
+	// Where people reach the site, such as https://tailscribe.com, with no
+	// trailing slash. Links in emails and OAuth callbacks are built from it.
+	BaseURL      string
+	ContactEmail string
+	Database     DatabaseEnv
+	Secret       string
+	// Signs ImageKit upload requests.
+	ImageKitPrivateKey string
+	// Where outgoing email goes: "log" or "file".
+	Mailer   string
+	MailDir  string
+	MailFrom string
+	// Social login. A provider is offered when its client id is set.
+	FacebookClientID     string
+	FacebookClientSecret string
+	OIDCIssuer           string
+	OIDCClientID         string
+	OIDCClientSecret     string
+	OIDCName             string
+	// Where rate limit counters live: "memory" or "postgres".
+	RateLimitStore string
+	// Encrypts two-factor secrets at rest. 64 hex characters, or empty to derive it from Secret.
+	TwoFactorKey string
+	// How passwords are checked and hashed.
+	Passwords auth.PasswordPolicy
+	// Re-read templates from ./ui/html on every request, for development.
+	TemplateReload bool
+
+	// What each setting ended up as, for logging.
+	effective []effectiveSetting
+}
+
+type effectiveSetting struct {
+	name   string
+	value  string
+	source string
+}
+
+// Tokens are signed with SECRET, so it has to be long enough to resist guessing.
+const minSecretLength = 32
+
+var sslModes = []string{"disable", "allow", "prefer", "require", "verify-ca", "verify-full"}
+
+// Everything wrong with the configuration, so it can all be fixed at once.
+type ConfigError struct {
+	Problems []string
+}
+
+func (e *ConfigError) Error() string {
+	return "invalid configuration: " + strings.Join(e.Problems, "; ")
+}
+
+// One environment variable and where its value goes.
+type setting struct {
+	name string
+	// Used when the variable is unset or empty.
+	fallback string
+	required bool
+	// How the value appears in logs. Nil shows it as it is.
+	redact func(value string) string
+	// Stores the value, or says what's wrong with it.
+	set func(e *EnvVars, value string) error
+}
+
+func redactSecret(string) string {
+	return "[redacted]"
+}
+
+// Keeps the host and database, which are handy when debugging, but not the password.
+func redactURL(value string) string {
+	u, err := url.Parse(value)
+	if err != nil {
+		return "[redacted]"
+	}
+	return u.Redacted()
+}
+
+func setString(field func(e *EnvVars) *string) func(e *EnvVars, value string) error {
+	return func(e *EnvVars, value string) error {
+		*field(e) = value
+		return nil
+	}
+}
+
+func setOneOf(field func(e *EnvVars) *string, allowed ...string) func(e *EnvVars, value string) error {
+	return func(e *EnvVars, value string) error {
+		for _, option := range allowed {
+			if value == option {
+				*field(e) = value
+				return nil
+			}
+		}
+		return fmt.Errorf("%q isn't one of %s", value, strings.Join(allowed, ", "))
+	}
+}
+
+func setCount(field func(e *EnvVars) *int) func(e *EnvVars, value string) error {
+	return func(e *EnvVars, value string) error {
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 0 {
+			return fmt.Errorf("%q isn't a whole number of 0 or more", value)
+		}
+		*field(e) = n
+		return nil
+	}
+}
+
+func setUint(bits int, store func(e *EnvVars, n uint64)) func(e *EnvVars, value string) error {
+	return func(e *EnvVars, value string) error {
+		n, err := strconv.ParseUint(value, 10, bits)
+		if err != nil {
+			return fmt.Errorf("%q isn't a whole number that fits in %d bits", value, bits)
+		}
+		store(e, n)
+		return nil
+	}
+}
+
+// Accepts "8080" as well as ":8080" and "host:8080".
+func setAddr(e *EnvVars, value string) error {
+	if !strings.Contains(value, ":") {
+		value = ":" + value
+	}
+
+	_, port, err := net.SplitHostPort(value)
+	if err != nil {
+		return fmt.Errorf("%q isn't an address such as :8080", value)
+	}
+	n, err := strconv.Atoi(port)
+	if err != nil || n < 1 || n > 65535 {
+		return fmt.Errorf("%q isn't a port number", port)
+	}
+
+	e.Addr = value
+	return nil
+}
+
+// Only a scheme and host, so paths can be appended to it.
+func setBaseURL(e *EnvVars, value string) error {
+	u, err := url.Parse(value)
+	if err != nil {
+		return fmt.Errorf("isn't a URL")
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return fmt.Errorf("should start with https:// or http://")
+	}
+	if u.Host == "" || u.User != nil || (u.Path != "" && u.Path != "/") || u.RawQuery != "" || u.Fragment != "" {
+		return fmt.Errorf("should be just a scheme and host, such as https://tailscribe.com")
+	}
+
+	e.BaseURL = u.Scheme + "://" + u.Host
+	return nil
+}
+
+func setDatabaseURL(e *EnvVars, value string) error {
+	u, err := url.Parse(value)
+	if err != nil {
+		return fmt.Errorf("isn't a URL")
+	}
+	if u.Scheme != "postgres" && u.Scheme != "postgresql" {
+		return fmt.Errorf("should start with postgres://")
+	}
+
+	e.Database.URL = value
+	return nil
+}
+
+func setTwoFactorKey(e *EnvVars, value string) error {
+	_, err := auth.ParseEncryptionKey(value)
+	if err != nil {
+		return err
+	}
+
+	e.TwoFactorKey = value
+	return nil
+}
+
+var defaultPasswords = auth.DefaultPasswordPolicy()
+
+// Every setting, in the order they're reported.
+var settings = []setting{
+	{name: "PORT", fallback: ":8080", set: setAddr},
+	{name: "BASE_URL", required: true, set: setBaseURL},
+	{name: "CONTACT_EMAIL", set: setString(func(e *EnvVars) *string { return &e.ContactEmail })},
+
+	{name: "DATABASE_URL", redact: redactURL, set: setDatabaseURL},
+	{name: "POSTGRES_DB", set: setString(func(e *EnvVars) *string { return &e.Database.Name })},
+	{name: "POSTGRES_USER", set: setString(func(e *EnvVars) *string { return &e.Database.User })},
+	{name: "POSTGRES_PASSWORD", redact: redactSecret, set: setString(func(e *EnvVars) *string { return &e.Database.Password })},
+	{name: "POSTGRES_HOST", fallback: "localhost", set: setString(func(e *EnvVars) *string { return &e.Database.Host })},
+	{name: "POSTGRES_PORT", fallback: "5432", set: setString(func(e *EnvVars) *string { return &e.Database.Port })},
+	{name: "POSTGRES_SSLMODE", set: setOneOf(func(e *EnvVars) *string { return &e.Database.SSLMode }, sslModes...)},
+	{name: "POSTGRES_MAX_OPEN_CONNS", fallback: "25", set: setCount(func(e *EnvVars) *int { return &e.Database.MaxOpenConns })},
+	{name: "POSTGRES_MAX_IDLE_CONNS", fallback: "10", set: setCount(func(e *EnvVars) *int { return &e.Database.MaxIdleConns })},
+	{name: "POSTGRES_CONN_MAX_LIFETIME", fallback: "30m", set: func(e *EnvVars, value string) error {
+		lifetime, err := time.ParseDuration(value)
+		if err != nil || lifetime < 0 {
+			return fmt.Errorf("%q isn't a duration such as 30m", value)
+		}
+		e.Database.ConnMaxLifetime = lifetime
+		return nil
+	}},
+
+	{name: "SECRET", required: true, redact: redactSecret, set: func(e *EnvVars, value string) error {
+		if len(value) < minSecretLength {
+			return fmt.Errorf("%d characters is too short, use at least %d", len(value), minSecretLength)
+		}
+		e.Secret = value
+		return nil
+	}},
+	{name: "IMAGE_KIT_PRIVATE_KEY", redact: redactSecret, set: setString(func(e *EnvVars) *string { return &e.ImageKitPrivateKey })},
+
+	{name: "MAILER", fallback: "log", set: setOneOf(func(e *EnvVars) *string { return &e.Mailer }, "log", "file")},
+	{name: "MAIL_DIR", set: setString(func(e *EnvVars) *string { return &e.MailDir })},
+	{name: "MAIL_FROM", set: setString(func(e *EnvVars) *string { return &e.MailFrom })},
+
+	{name: "FACEBOOK_CLIENT_ID", set: setString(func(e *EnvVars) *string { return &e.FacebookClientID })},
+	{name: "FACEBOOK_CLIENT_SECRET", redact: redactSecret, set: setString(func(e *EnvVars) *string { return &e.FacebookClientSecret })},
+	{name: "OIDC_ISSUER", set: setString(func(e *EnvVars) *string { return &e.OIDCIssuer })},
+	{name: "OIDC_CLIENT_ID", set: setString(func(e *EnvVars) *string { return &e.OIDCClientID })},
+	{name: "OIDC_CLIENT_SECRET", redact: redactSecret, set: setString(func(e *EnvVars) *string { return &e.OIDCClientSecret })},
+	{name: "OIDC_NAME", set: setString(func(e *EnvVars) *string { return &e.OIDCName })},
+
+	{name: "RATE_LIMIT_STORE", fallback: "memory", set: setOneOf(func(e *EnvVars) *string { return &e.RateLimitStore }, "memory", "postgres")},
+	{name: "TWO_FACTOR_KEY", redact: redactSecret, set: setTwoFactorKey},
+
+	{name: "PASSWORD_HASH", fallback: defaultPasswords.Algorithm,
+		set: setOneOf(func(e *EnvVars) *string { return &e.Passwords.Algorithm }, auth.PasswordBcrypt, auth.PasswordArgon2id)},
+	{name: "BCRYPT_COST", fallback: strconv.Itoa(defaultPasswords.BcryptCost),
+		set: setUint(32, func(e *EnvVars, n uint64) { e.Passwords.BcryptCost = int(n) })},
+	{name: "ARGON2_TIME", fallback: strconv.Itoa(int(defaultPasswords.Argon2.Time)),
+		set: setUint(32, func(e *EnvVars, n uint64) { e.Passwords.Argon2.Time = uint32(n) })},
+	{name: "ARGON2_MEMORY", fallback: strconv.Itoa(int(defaultPasswords.Argon2.Memory)),
+		set: setUint(32, func(e *EnvVars, n uint64) { e.Passwords.Argon2.Memory = uint32(n) })},
+	{name: "ARGON2_THREADS", fallback: strconv.Itoa(int(defaultPasswords.Argon2.Threads)),
+		set: setUint(8, func(e *EnvVars, n uint64) { e.Passwords.Argon2.Threads = uint8(n) })},
+	{name: "PASSWORD_MIN_LENGTH", fallback: strconv.Itoa(defaultPasswords.MinLength),
+		set: setUint(32, func(e *EnvVars, n uint64) { e.Passwords.MinLength = int(n) })},
+
+	{name: "TEMPLATE_RELOAD", fallback: "false", set: func(e *EnvVars, value string) error {
+		reload, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("%q isn't true or false", value)
+		}
+		e.TemplateReload = reload
+		return nil
+	}},
+}
+
+// Loads the settings, looking for each one in turn in:
+//
+//   - the environment, through lookupEnv
+//   - a file named by NAME_FILE, such as a Docker secret
+//   - the optional dotenv-style file named by CONFIG_FILE
+//   - the setting's default
+//
+// Empty values count as unset. It returns a *ConfigError listing every problem.
+func LoadEnvVars(lookupEnv func(key string) (string, bool)) (*EnvVars, error) {
+	var problems []string
+
+	fileValues := map[string]string{}
+	configFile, _ := lookupEnv("CONFIG_FILE")
+	if configFile != "" {
+		values, err := godotenv.Read(configFile)
+		if err != nil {
+			problems = append(problems, fmt.Sprintf("CONFIG_FILE: %v", err))
+		} else {
+			fileValues = values
+		}
+	}
+
+	// The environment wins over the config file.
+	lookup := func(key string) (string, string) {
+		value, _ := lookupEnv(key)
+		if value != "" {
+			return value, "environment"
+		}
+		if value := fileValues[key]; value != "" {
+			return value, configFile
+		}
+		return "", ""
+	}
+
+	e := &EnvVars{}
+	for _, s := range settings {
+		value, source, err := resolveSetting(s.name, lookup)
+		if err != nil {
+			problems = append(problems, fmt.Sprintf("%s: %v", s.name, err))
+			continue
+		}
+
+		if value == "" {
+			if s.required {
+				problems = append(problems, fmt.Sprintf("%s is required", s.name))
+				continue
+			}
+			if s.fallback == "" {
+				continue
+			}
+			value, source = s.fallback, "default"
+		}
+
+		err = s.set(e, value)
+		if err != nil {
+			problems = append(problems, fmt.Sprintf("%s: %v", s.name, err))
+			continue
+		}
+
+		if s.redact != nil {
+			value = s.redact(value)
+		}
+		e.effective = append(e.effective, effectiveSetting{name: s.name, value: value, source: source})
+	}
+
+	problems = append(problems, e.check()...)
+	if len(problems) > 0 {
+		return nil, &ConfigError{Problems: problems}
+	}
+
+	return e, nil
+}
+
+// The value of name, or the contents of the file that name_FILE points to.
+func resolveSetting(name string, lookup func(key string) (string, string)) (string, string, error) {
+	value, source := lookup(name)
+	path, _ := lookup(name + "_FILE")
+	if path == "" {
+		return value, source, nil
+	}
+	if value != "" {
+		return "", "", fmt.Errorf("set either %s or %s_FILE, not both", name, name)
+	}
+
+	contents, err := os.ReadFile(path)
+	if err != nil {
+		return "", "", fmt.Errorf("reading %s_FILE: %w", name, err)
+	}
+
+	// Secret files usually end with a newline that isn't part of the value.
+	return strings.TrimRight(string(contents), "\r\n"), name + "_FILE", nil
+}
+
+// Problems that involve more than one setting.
+func (e *EnvVars) check() []string {
+	var problems []string
+
+	if e.Database.URL == "" {
+		if e.Database.Name == "" {
+			problems = append(problems, "POSTGRES_DB is required unless DATABASE_URL is set")
+		}
+		if e.Database.User == "" {
+			problems = append(problems, "POSTGRES_USER is required unless DATABASE_URL is set")
+		}
+	}
+
+	if e.Mailer == "file" && e.MailDir == "" {
+		problems = append(problems, "MAIL_DIR is required when MAILER is file")
+	}
+
+	if e.FacebookClientID != "" && e.FacebookClientSecret == "" {
+		problems = append(problems, "FACEBOOK_CLIENT_SECRET is required when FACEBOOK_CLIENT_ID is set")
+	}
+	if (e.OIDCIssuer == "") != (e.OIDCClientID == "") {
+		problems = append(problems, "OIDC_ISSUER and OIDC_CLIENT_ID have to be set together")
+	}
+
+	err := e.Passwords.Check()
+	if err != nil {
+		problems = append(problems, fmt.Sprintf("password settings: %v", err))
+	}
+
+	return problems
+}
+
+// Reports the effective settings, with secrets redacted. Settings that came
+// from somewhere other than the environment say where.
+func (e *EnvVars) LogValue() slog.Value {
+	attrs := make([]slog.Attr, 0, len(e.effective))
+	for _, s := range e.effective {
+		value := s.value
+		if s.source != "environment" {
+			value = fmt.Sprintf("%s (%s)", value, s.source)
+		}
+		attrs = append(attrs, slog.String(s.name, value))
+	}
+
+	return slog.GroupValue(attrs...)
+}
+
+// Creates the connection string for the database instance
+func (d *DatabaseEnv) ConnectionString() string {
+	if d.URL != "" {
+		u, err := url.Parse(d.URL)
+		if err != nil || d.SSLMode == "" || u.Query().Has("sslmode") {
+			return d.URL
+		}
+
+		query := u.Query()
+		query.Set("sslmode", d.SSLMode)
+		u.RawQuery = query.Encode()
+		return u.String()
+	}
+
+	u := url.URL{
+		Scheme: "postgresql",
+		User:   url.UserPassword(d.User, d.Password),
+		Host:   net.JoinHostPort(d.Host, d.Port),
+		Path:   "/" + d.Name,
+	}
+	if d.SSLMode != "" {
+		u.RawQuery = url.Values{"sslmode": {d.SSLMode}}.Encode()
+	}
+
+	return u.String()
+}
+
+// Opens the connection pool with the configured limits.
+func (d *DatabaseEnv) Open() (*sql.DB, error) {
+	db, err := sql.Open("postgres", d.ConnectionString())
+	if err != nil {
+		return nil, err
+	}
+
+	db.SetMaxOpenConns(d.MaxOpenConns)
+	db.SetMaxIdleConns(d.MaxIdleConns)
+	db.SetConnMaxLifetime(d.ConnMaxLifetime)
+
+	return db, nil
+}
@@ -0,0 +1,241 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// An error that knows the response it should become. Handlers return one, or
+// pass it to serveError, rather than writing the error response themselves.
+type AppError struct {
+	Status int
+	// Shown to the user. Empty ones use the defaults for the status.
+	Title   string
+	Heading string
+	Message string
+	// Logged, never shown.
+	Err error
+	// For 429s, how long the client should wait.
+	RetryAfter time.Duration
+}
+
+func (e *AppError) Error() string {
+	if e.Err != nil {
+		return e.Err.Error()
+	}
+	if e.Message != "" {
+		return e.Message
+	}
+	return http.StatusText(e.Status)
+}
+
+func (e *AppError) Unwrap() error {
+	return e.Err
+}
+
+var errNotFound = &AppError{Status: http.StatusNotFound}
+
+func badRequest(message string) *AppError {
+	return &AppError{Status: http.StatusBadRequest, Message: message}
+}
+
+func forbidden(message string) *AppError {
+	return &AppError{Status: http.StatusForbidden, Message: message}
+}
+
+func unauthorized(err error) *AppError {
+	return &AppError{Status: http.StatusUnauthorized, Err: err}
+}
+
+func tooManyRequests(wait time.Duration) *AppError {
+	return &AppError{Status: http.StatusTooManyRequests, RetryAfter: wait}
+}
+
+// A 500. message says what was being done, for the log.
+func internalError(message string, err error) *AppError {
+	return &AppError{Status: http.StatusInternalServerError, Err: fmt.Errorf("%s: %w", message, err)}
+}
+
+type errorPageText struct {
+	title, heading, message string
+}
+
+var errorPages = map[int]errorPageText{
+	http.StatusBadRequest: {
+		"Bad Request",
+		"Something's not right",
+		"We couldn't make sense of that request. Go back, check what you entered and try again.",
+	},
+	http.StatusUnauthorized: {
+		"Log In Required",
+		"Please log in",
+		"You need to be logged in to see this page.",
+	},
+	http.StatusForbidden: {
+		"Forbidden",
+		"You can't do that",
+		"Your account doesn't have access to this.",
+	},
+	http.StatusNotFound: {
+		"Page Not Found",
+		"We couldn't find that page",
+		"It may have moved, or the link may be wrong.",
+	},
+	http.StatusTooManyRequests: {
+		"Too Many Attempts",
+		"Too many attempts",
+		"To keep accounts safe, we've paused this for now.",
+	},
+	http.StatusInternalServerError: {
+		"Something Went Wrong",
+		"Something went wrong",
+		"We couldn't finish that. Please try again in a moment.",
+	},
+}
+
+type ErrorPageData struct {
+	BasePageData
+	Status     int
+	Heading    string
+	Message    string
+	RetryAfter string
+}
+
+type errorResponse struct {
+	Status  int    `json:"status"`
+	Error   string `json:"error"`
+	Message string `json:"message"`
+}
+
+// Reports whether the client would rather have JSON than HTML, going by the
+// first of the two its Accept header lists.
+func wantsJSON(r *http.Request) bool {
+	for _, accepted := range strings.Split(r.Header.Get("Accept"), ",") {
+		mediaType, _, err := mime.ParseMediaType(strings.TrimSpace(accepted))
+		if err != nil {
+			continue
+		}
+		switch mediaType {
+		case "application/json":
+			return true
+		case "text/html":
+			return false
+		}
+	}
+	return false
+}
+
+// Logs the error once, with the request it failed, and sends the matching
+// error page, or JSON if the client asked for it. Errors that aren't an
+// *AppError are 500s.
+func (a *APIConfig) serveError(w http.ResponseWriter, r *http.Request, err error) {
+	var appErr *AppError
+	if !errors.As(err, &appErr) {
+		appErr = &AppError{Status: http.StatusInternalServerError, Err: err}
+	}
+
+	a.logError(r, appErr)
+
+	text, ok := errorPages[appErr.Status]
+	if !ok {
+		text = errorPageText{http.StatusText(appErr.Status), http.StatusText(appErr.Status), ""}
+	}
+	if appErr.Title != "" {
+		text.title = appErr.Title
+	}
+	if appErr.Heading != "" {
+		text.heading = appErr.Heading
+	}
+	if appErr.Message != "" {
+		text.message = appErr.Message
+	}
+
+	if appErr.RetryAfter > 0 {
+		w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(appErr.RetryAfter.Seconds()))))
+	}
+
+	if wantsJSON(r) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(appErr.Status)
+		json.NewEncoder(w).Encode(errorResponse{
+			Status:  appErr.Status,
+			Error:   text.heading,
+			Message: text.message,
+		})
+		return
+	}
+
+	data := ErrorPageData{
+		BasePageData: basePageData(r, "TailScribe - "+text.title),
+		Status:       appErr.Status,
+		Heading:      text.heading,
+		Message:      text.message,
+	}
+	if appErr.RetryAfter > 0 {
+		data.RetryAfter = formatWait(appErr.RetryAfter)
+	}
+
+	renderErr := a.Templates.Render(w, appErr.Status, "error", data)
+	if renderErr != nil {
+		a.Logger.Error("error rendering error page", slog.String("error", renderErr.Error()))
+		http.Error(w, http.StatusText(appErr.Status), appErr.Status)
+	}
+}
+
+// Server errors are errors. Clients asking for pages that don't exist, or
+// that they need to log in for, are routine.
+func (a *APIConfig) logError(r *http.Request, appErr *AppError) {
+	level := slog.LevelWarn
+	switch {
+	case appErr.Status >= 500:
+		level = slog.LevelError
+	case appErr.Status == http.StatusNotFound || appErr.Status == http.StatusUnauthorized:
+		level = slog.LevelInfo
+	}
+
+	attrs := []slog.Attr{
+		slog.Int("status", appErr.Status),
+		slog.String("method", r.Method),
+		slog.String("path", r.URL.Path),
+		slog.String("ip", clientIP(r)),
+	}
+	if userID, ok := currentUserID(r); ok {
+		attrs = append(attrs, slog.Int("user_id", userID))
+	}
+	if appErr.Err != nil {
+		attrs = append(attrs, slog.String("error", appErr.Err.Error()))
+	}
+
+	a.Logger.LogAttrs(r.Context(), level, "request failed", attrs...)
+}
+
+// Sends a 500 for err. message says what was being done, for the log.
+func (a *APIConfig) serverError(w http.ResponseWriter, r *http.Request, message string, err error) {
+	a.serveError(w, r, internalError(message, err))
+}
+
+// A handler that returns its error rather than writing a response for it.
+type errorHandler func(w http.ResponseWriter, r *http.Request) error
+
+// Turns an errorHandler into an http.HandlerFunc that serves its errors.
+func (a *APIConfig) HandleErrors(handler errorHandler) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		err := handler(w, r)
+		if err != nil {
+			a.serveError(w, r, err)
+		}
+	}
+}
+
+// For requests that match no route.
+func (a *APIConfig) HandleNotFound(w http.ResponseWriter, r *http.Request) error {
+	return errNotFound
+}
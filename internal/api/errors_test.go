@@ -0,0 +1,93 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func serveTestError(accept string, err error) *httptest.ResponseRecorder {
+	request, _ := http.NewRequest(http.MethodGet, "/somewhere", nil)
+	if accept != "" {
+		request.Header.Set("Accept", accept)
+	}
+	response := httptest.NewRecorder()
+
+	createConfig().serveError(response, request, err)
+
+	return response
+}
+
+func TestServeError(t *testing.T) {
+	t.Run("Renders the page for the status", func(t *testing.T) {
+		response := serveTestError("text/html,application/xhtml+xml,*/*;q=0.8", errNotFound)
+
+		assert.Equal(t, http.StatusNotFound, response.Result().StatusCode)
+		assert.Contains(t, response.Result().Header.Get("Content-Type"), "text/html")
+		assert.Contains(t, response.Body.String(), "<title>TailScribe - Page Not Found</title>")
+		assert.Contains(t, response.Body.String(), "find that page")
+	})
+
+	t.Run("Sends JSON to clients that ask for it", func(t *testing.T) {
+		response := serveTestError("application/json", badRequest("Choose a permission level."))
+
+		assert.Equal(t, http.StatusBadRequest, response.Result().StatusCode)
+		assert.Equal(t, "application/json", response.Result().Header.Get("Content-Type"))
+
+		var body errorResponse
+		assert.NoError(t, json.NewDecoder(response.Body).Decode(&body))
+		assert.Equal(t, http.StatusBadRequest, body.Status)
+		assert.Equal(t, "Choose a permission level.", body.Message)
+	})
+
+	t.Run("Treats other errors as a 500 without showing them", func(t *testing.T) {
+		response := serveTestError("", errors.New("connection refused"))
+
+		assert.Equal(t, http.StatusInternalServerError, response.Result().StatusCode)
+		assert.Contains(t, response.Body.String(), "Something went wrong")
+		assert.NotContains(t, response.Body.String(), "connection refused")
+	})
+
+	t.Run("Tells clients how long to wait", func(t *testing.T) {
+		response := serveTestError("", tooManyRequests(90*time.Second))
+
+		assert.Equal(t, http.StatusTooManyRequests, response.Result().StatusCode)
+		assert.Equal(t, "90", response.Result().Header.Get("Retry-After"))
+		assert.Contains(t, response.Body.String(), "2 minutes")
+	})
+}
+
+func TestWantsJSON(t *testing.T) {
+	var acceptTests = []struct {
+		accept   string
+		expected bool
+	}{
+		{"", false},
+		{"*/*", false},
+		{"application/json", true},
+		{"application/json, text/plain, */*", true},
+		{"text/html,application/json;q=0.9", false},
+	}
+
+	for _, tt := range acceptTests {
+		request, _ := http.NewRequest(http.MethodGet, "/", nil)
+		request.Header.Set("Accept", tt.accept)
+
+		assert.Equal(t, tt.expected, wantsJSON(request), tt.accept)
+	}
+}
+
+func TestHandleNotFound(t *testing.T) {
+	request, _ := http.NewRequest(http.MethodGet, "/no/such/page", nil)
+	response := httptest.NewRecorder()
+	apiCfg := createConfig()
+	apiCfg.HandleErrors(apiCfg.HandleNotFound)(response, request)
+
+	assert.Equal(t, http.StatusNotFound, response.Result().StatusCode)
+	assert.Contains(t, response.Body.String(), `href="/"`)
+}
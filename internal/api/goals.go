@@ -0,0 +1,445 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ctiller15/tailscribe/internal/database"
+)
+
+// Statuses a goal can be in.
+var GoalStatuses = []string{
+	"open",
+	"achieved",
+	"abandoned",
+}
+
+// How far back the pet dashboard looks for achieved goals.
+const recentlyAchievedGoalsWindow = 30 * 24 * time.Hour
+
+type GoalForm struct {
+	Description string
+	TargetDate  string
+	Status      string
+	SkillIDs    []int32
+	Valid       bool
+	Errors      map[string]string
+}
+
+// Reports whether the skill is linked in the form, for ticking checkboxes.
+func (f GoalForm) HasSkill(skillID int32) bool {
+	return slices.Contains(f.SkillIDs, skillID)
+}
+
+type GoalsPageData struct {
+	BasePageData
+	Pet      database.Pet
+	Goals    []database.Goal
+	Skills   []database.Skill
+	Statuses []string
+	GoalForm
+}
+
+type GoalPageData struct {
+	BasePageData
+	Pet           database.Pet
+	Goal          database.Goal
+	LinkedSkills  []database.Skill
+	Skills        []database.Skill
+	ProgressNotes []database.GoalProgressNote
+	Statuses      []string
+	GoalForm
+}
+
+// Validated values from a GoalForm.
+type goalInput struct {
+	TargetDate sql.NullTime
+	SkillIDs   []int32
+}
+
+func goalFormFromRequest(r *http.Request) GoalForm {
+	form := GoalForm{
+		Description: strings.TrimSpace(r.FormValue("description")),
+		TargetDate:  strings.TrimSpace(r.FormValue("target_date")),
+		Status:      r.FormValue("status"),
+	}
+
+	// ParseForm has already run as part of FormValue.
+	for _, raw := range r.Form["skill_ids"] {
+		skillID, err := strconv.ParseInt(raw, 10, 32)
+		if err == nil {
+			form.SkillIDs = append(form.SkillIDs, int32(skillID))
+		}
+	}
+
+	return form
+}
+
+func goalFormFromGoal(goal database.Goal, linkedSkills []database.Skill) GoalForm {
+	form := GoalForm{
+		Description: goal.Description,
+		Status:      goal.Status,
+		Valid:       true,
+	}
+	if goal.TargetDate.Valid {
+		form.TargetDate = goal.TargetDate.Time.Format(dateInputLayout)
+	}
+	for _, skill := range linkedSkills {
+		form.SkillIDs = append(form.SkillIDs, skill.ID)
+	}
+
+	return form
+}
+
+// Validates the form against the pet's skills. Skills that aren't the pet's are rejected.
+func (f *GoalForm) validate(petSkills []database.Skill) (goalInput, bool) {
+	input := goalInput{}
+	f.Errors = map[string]string{}
+
+	if f.Description == "" {
+		f.Errors["description"] = "Describe what you're working towards."
+	}
+
+	if f.TargetDate != "" {
+		targetDate, err := time.Parse(dateInputLayout, f.TargetDate)
+		if err != nil {
+			f.Errors["target_date"] = "Enter a valid target date."
+		}
+		input.TargetDate = sql.NullTime{Time: targetDate, Valid: err == nil}
+	}
+
+	if !slices.Contains(GoalStatuses, f.Status) {
+		f.Errors["status"] = "Choose a status."
+	}
+
+	for _, skillID := range f.SkillIDs {
+		owned := slices.ContainsFunc(petSkills, func(skill database.Skill) bool {
+			return skill.ID == skillID
+		})
+		if !owned {
+			f.Errors["skill_ids"] = "Choose from this pet's skills."
+			break
+		}
+	}
+	input.SkillIDs = f.SkillIDs
+
+	f.Valid = len(f.Errors) == 0
+	return input, f.Valid
+}
+
+// Replaces the skills linked to a goal.
+func setGoalSkills(ctx context.Context, q *database.Queries, goalID int32, skillIDs []int32) error {
+	err := q.DeleteGoalSkills(ctx, goalID)
+	if err != nil {
+		return err
+	}
+
+	for _, skillID := range skillIDs {
+		err := q.AddGoalSkill(ctx, database.AddGoalSkillParams{
+			GoalID:  goalID,
+			SkillID: skillID,
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Works out achieved_at for a goal moving into the given status.
+// The original achievement time is kept when an achieved goal is edited.
+func goalAchievedAt(current database.Goal, status string) sql.NullTime {
+	if status != "achieved" {
+		return sql.NullTime{}
+	}
+	if current.Status == "achieved" && current.AchievedAt.Valid {
+		return current.AchievedAt
+	}
+
+	return sql.NullTime{Time: time.Now(), Valid: true}
+}
+
+func (a *APIConfig) renderGoals(w http.ResponseWriter, r *http.Request, status int, pet database.Pet, skills []database.Skill, form GoalForm) {
+	goals, err := a.Db.ListGoalsForPet(r.Context(), pet.ID)
+	if err != nil {
+		a.serverError(w, r, "error listing goals", err)
+		return
+	}
+
+	data := GoalsPageData{
+		BasePageData: basePageData(r, fmt.Sprintf("%s - Goals", pet.Name)),
+		Pet:          pet,
+		Goals:        goals,
+		Skills:       skills,
+		Statuses:     GoalStatuses,
+		GoalForm:     form,
+	}
+
+	a.render(w, r, status, "goals", data)
+}
+
+func (a *APIConfig) renderGoal(w http.ResponseWriter, r *http.Request, status int, pet database.Pet, goal database.Goal, skills []database.Skill, form GoalForm) {
+	ctx := r.Context()
+	linkedSkills, err := a.Db.ListGoalSkills(ctx, goal.ID)
+	if err != nil {
+		a.serverError(w, r, "error listing goal skills", err)
+		return
+	}
+
+	notes, err := a.Db.ListGoalProgressNotes(ctx, goal.ID)
+	if err != nil {
+		a.serverError(w, r, "error listing goal progress notes", err)
+		return
+	}
+
+	data := GoalPageData{
+		BasePageData:  basePageData(r, fmt.Sprintf("%s - Goal", pet.Name)),
+		Pet:           pet,
+		Goal:          goal,
+		LinkedSkills:  linkedSkills,
+		Skills:        skills,
+		ProgressNotes: notes,
+		Statuses:      GoalStatuses,
+		GoalForm:      form,
+	}
+
+	a.render(w, r, status, "goal", data)
+}
+
+// Resolves the {goalID} path value to a goal belonging to the pet.
+// Writes a 404 (or 500) and returns false when it can't be found.
+func (a *APIConfig) goalFromPath(w http.ResponseWriter, r *http.Request, pet database.Pet) (database.Goal, bool) {
+	goalID, err := pathID(r, "goalID")
+	if err != nil {
+		a.serveError(w, r, errNotFound)
+		return database.Goal{}, false
+	}
+
+	goal, err := a.Db.GetGoal(r.Context(), database.GetGoalParams{
+		ID:    goalID,
+		PetID: pet.ID,
+	})
+	if err != nil {
+		if isNotFound(err) {
+			a.serveError(w, r, errNotFound)
+		} else {
+			a.serverError(w, r, "error loading goal", err)
+		}
+		return database.Goal{}, false
+	}
+
+	return goal, true
+}
+
+func (a *APIConfig) HandleGetGoals(w http.ResponseWriter, r *http.Request, user_id int) {
+	pet, _, ok := a.linkedPetFromPath(w, r, user_id, PermissionsViewer)
+	if !ok {
+		return
+	}
+
+	skills, err := a.Db.ListSkillsForPet(r.Context(), pet.ID)
+	if err != nil {
+		a.serverError(w, r, "error listing skills", err)
+		return
+	}
+
+	a.renderGoals(w, r, http.StatusOK, pet, skills, GoalForm{Status: "open", Valid: true})
+}
+
+func (a *APIConfig) HandlePostGoal(w http.ResponseWriter, r *http.Request, user_id int) {
+	ctx := r.Context()
+	pet, _, ok := a.linkedPetFromPath(w, r, user_id, PermissionsCoOwner)
+	if !ok {
+		return
+	}
+
+	skills, err := a.Db.ListSkillsForPet(ctx, pet.ID)
+	if err != nil {
+		a.serverError(w, r, "error listing skills", err)
+		return
+	}
+
+	form := goalFormFromRequest(r)
+	// New goals always start open.
+	form.Status = "open"
+	input, valid := form.validate(skills)
+	if !valid {
+		a.renderGoals(w, r, http.StatusBadRequest, pet, skills, form)
+		return
+	}
+
+	// All or nothing, so a failure can't leave a goal without its skills.
+	err = a.inTx(ctx, func(q *database.Queries) error {
+		goal, err := q.CreateGoal(ctx, database.CreateGoalParams{
+			PetID:       pet.ID,
+			Description: form.Description,
+			TargetDate:  input.TargetDate,
+		})
+		if err != nil {
+			return err
+		}
+
+		return setGoalSkills(ctx, q, goal.ID, input.SkillIDs)
+	})
+	if err != nil {
+		a.serverError(w, r, "error creating goal", err)
+		return
+	}
+
+	http.Redirect(w, r, fmt.Sprintf("/dashboard/pet/%d/goals", pet.ID), http.StatusFound)
+}
+
+func (a *APIConfig) HandleGetGoal(w http.ResponseWriter, r *http.Request, user_id int) {
+	ctx := r.Context()
+	pet, _, ok := a.linkedPetFromPath(w, r, user_id, PermissionsViewer)
+	if !ok {
+		return
+	}
+
+	goal, ok := a.goalFromPath(w, r, pet)
+	if !ok {
+		return
+	}
+
+	skills, err := a.Db.ListSkillsForPet(ctx, pet.ID)
+	if err != nil {
+		a.serverError(w, r, "error listing skills", err)
+		return
+	}
+
+	linkedSkills, err := a.Db.ListGoalSkills(ctx, goal.ID)
+	if err != nil {
+		a.serverError(w, r, "error listing goal skills", err)
+		return
+	}
+
+	a.renderGoal(w, r, http.StatusOK, pet, goal, skills, goalFormFromGoal(goal, linkedSkills))
+}
+
+func (a *APIConfig) HandlePostEditGoal(w http.ResponseWriter, r *http.Request, user_id int) {
+	ctx := r.Context()
+	pet, _, ok := a.linkedPetFromPath(w, r, user_id, PermissionsCoOwner)
+	if !ok {
+		return
+	}
+
+	goal, ok := a.goalFromPath(w, r, pet)
+	if !ok {
+		return
+	}
+
+	skills, err := a.Db.ListSkillsForPet(ctx, pet.ID)
+	if err != nil {
+		a.serverError(w, r, "error listing skills", err)
+		return
+	}
+
+	form := goalFormFromRequest(r)
+	input, valid := form.validate(skills)
+	if !valid {
+		a.renderGoal(w, r, http.StatusBadRequest, pet, goal, skills, form)
+		return
+	}
+
+	// All or nothing, so a failure can't unlink the skills without saving the new ones.
+	err = a.inTx(ctx, func(q *database.Queries) error {
+		_, err := q.UpdateGoal(ctx, database.UpdateGoalParams{
+			ID:          goal.ID,
+			PetID:       pet.ID,
+			Description: form.Description,
+			TargetDate:  input.TargetDate,
+			Status:      form.Status,
+			AchievedAt:  goalAchievedAt(goal, form.Status),
+		})
+		if err != nil {
+			return err
+		}
+
+		return setGoalSkills(ctx, q, goal.ID, input.SkillIDs)
+	})
+	if err != nil {
+		a.serverError(w, r, "error updating goal", err)
+		return
+	}
+
+	http.Redirect(w, r, fmt.Sprintf("/dashboard/pet/%d/goals/%d", pet.ID, goal.ID), http.StatusFound)
+}
+
+func (a *APIConfig) HandlePostGoalProgressNote(w http.ResponseWriter, r *http.Request, user_id int) {
+	pet, _, ok := a.linkedPetFromPath(w, r, user_id, PermissionsCoOwner)
+	if !ok {
+		return
+	}
+
+	goal, ok := a.goalFromPath(w, r, pet)
+	if !ok {
+		return
+	}
+
+	note := strings.TrimSpace(r.FormValue("note"))
+	if note == "" {
+		a.serveError(w, r, badRequest("A progress note can't be empty."))
+		return
+	}
+
+	_, err := a.Db.CreateGoalProgressNote(r.Context(), database.CreateGoalProgressNoteParams{
+		GoalID: goal.ID,
+		UserID: sql.NullInt32{Int32: int32(user_id), Valid: true},
+		Note:   note,
+	})
+	if err != nil {
+		a.serverError(w, r, "error creating goal progress note", err)
+		return
+	}
+
+	http.Redirect(w, r, fmt.Sprintf("/dashboard/pet/%d/goals/%d", pet.ID, goal.ID), http.StatusFound)
+}
+
+func (a *APIConfig) HandlePostDeleteGoal(w http.ResponseWriter, r *http.Request, user_id int) {
+	pet, _, ok := a.linkedPetFromPath(w, r, user_id, PermissionsCoOwner)
+	if !ok {
+		return
+	}
+
+	goal, ok := a.goalFromPath(w, r, pet)
+	if !ok {
+		return
+	}
+
+	err := a.Db.DeleteGoal(r.Context(), database.DeleteGoalParams{
+		ID:    goal.ID,
+		PetID: pet.ID,
+	})
+	if err != nil {
+		a.serverError(w, r, "error deleting goal", err)
+		return
+	}
+
+	http.Redirect(w, r, fmt.Sprintf("/dashboard/pet/%d/goals", pet.ID), http.StatusFound)
+}
+
+// Toggles whether goals show on the pet's public profile.
+func (a *APIConfig) HandlePostGoalsVisibility(w http.ResponseWriter, r *http.Request, user_id int) {
+	pet, _, ok := a.linkedPetFromPath(w, r, user_id, PermissionsCoOwner)
+	if !ok {
+		return
+	}
+
+	err := a.Db.UpdatePetGoalsHidden(r.Context(), database.UpdatePetGoalsHiddenParams{
+		ID:          pet.ID,
+		Goalshidden: r.FormValue("goals_hidden") == "on",
+	})
+	if err != nil {
+		a.serverError(w, r, "error updating goals visibility", err)
+		return
+	}
+
+	http.Redirect(w, r, fmt.Sprintf("/dashboard/pet/%d/goals", pet.ID), http.StatusFound)
+}
@@ -0,0 +1,103 @@
+package api
+
+import (
+	"database/sql"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ctiller15/tailscribe/internal/database"
+	"github.com/stretchr/testify/assert"
+)
+
+func postGoalForm(authCookie *http.Cookie, target string, pathValues map[string]string, formData url.Values, handler authorizedHandler) *http.Response {
+	request, _ := http.NewRequest(http.MethodPost, target, strings.NewReader(formData.Encode()))
+	request.Header.Add("Content-Type", "application/x-www-form-urlencoded")
+	for name, value := range pathValues {
+		request.SetPathValue(name, value)
+	}
+	request.AddCookie(authCookie)
+	response := httptest.NewRecorder()
+
+	apiCfg := createConfig()
+	apiCfg.CheckAuthMiddleware(handler)(response, request)
+
+	return response.Result()
+}
+
+func TestGoalFormValidate(t *testing.T) {
+	petSkills := []database.Skill{{ID: 7, Name: "sit"}}
+
+	t.Run("Accepts the pet's own skills", func(t *testing.T) {
+		form := GoalForm{Description: "Pass the CGC", Status: "open", SkillIDs: []int32{7}}
+		_, valid := form.validate(petSkills)
+		assert.True(t, valid)
+	})
+
+	t.Run("Rejects skills from another pet", func(t *testing.T) {
+		form := GoalForm{Description: "Pass the CGC", Status: "open", SkillIDs: []int32{8}}
+		_, valid := form.validate(petSkills)
+		assert.False(t, valid)
+		assert.Contains(t, form.Errors, "skill_ids")
+	})
+
+	t.Run("Rejects a malformed target date", func(t *testing.T) {
+		form := GoalForm{Description: "Pass the CGC", Status: "open", TargetDate: "next week"}
+		_, valid := form.validate(petSkills)
+		assert.False(t, valid)
+		assert.Contains(t, form.Errors, "target_date")
+	})
+}
+
+func TestGoalAchievedAt(t *testing.T) {
+	t.Run("Clears the timestamp unless achieved", func(t *testing.T) {
+		achievedAt := goalAchievedAt(database.Goal{Status: "open"}, "abandoned")
+		assert.False(t, achievedAt.Valid)
+	})
+
+	t.Run("Keeps the original timestamp for an achieved goal", func(t *testing.T) {
+		original := sql.NullTime{Time: time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC), Valid: true}
+		achievedAt := goalAchievedAt(database.Goal{Status: "achieved", AchievedAt: original}, "achieved")
+		assert.Equal(t, original, achievedAt)
+	})
+}
+
+func TestHandlePostEditGoal(t *testing.T) {
+	t.Run("Shows a newly achieved goal on the pet dashboard", func(t *testing.T) {
+		cookies := signUserUp(randTestEmail(), testPassword)
+		petID := createPetForUser(t, cookies[0], "Olive")
+		petPath := map[string]string{"id": strconv.Itoa(petID)}
+
+		result := postGoalForm(cookies[0], fmt.Sprintf("/dashboard/pet/%d/goals", petID), petPath, url.Values{
+			"description": {"Earn a trick dog title"},
+			"target_date": {"2020-01-01"},
+		}, createConfig().HandlePostGoal)
+		assert.Equal(t, 302, result.StatusCode)
+
+		apiCfg := createConfig()
+		overdue, err := apiCfg.Db.ListOverdueGoalsForPet(t.Context(), int32(petID))
+		assert.NoError(t, err)
+		assert.Len(t, overdue, 1)
+
+		goalPath := map[string]string{"id": strconv.Itoa(petID), "goalID": strconv.Itoa(int(overdue[0].ID))}
+		result = postGoalForm(cookies[0], fmt.Sprintf("/dashboard/pet/%d/goals/%d", petID, overdue[0].ID), goalPath, url.Values{
+			"description": {"Earn a trick dog title"},
+			"target_date": {"2020-01-01"},
+			"status":      {"achieved"},
+		}, apiCfg.HandlePostEditGoal)
+		assert.Equal(t, 302, result.StatusCode)
+
+		goal, err := apiCfg.Db.GetGoal(t.Context(), database.GetGoalParams{ID: overdue[0].ID, PetID: int32(petID)})
+		assert.NoError(t, err)
+		assert.Equal(t, "achieved", goal.Status)
+		assert.True(t, goal.AchievedAt.Valid)
+
+		response := getPetDashboard(cookies[0], strconv.Itoa(petID))
+		assert.Contains(t, response.Body.String(), "Recently achieved")
+	})
+}
@@ -1,23 +1,33 @@
 package api
 
 import (
+	"context"
 	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"html/template"
 	"log/slog"
+	"maps"
 	"net/http"
 	"net/mail"
+	"slices"
+	"strings"
 	"time"
 
 	"github.com/ctiller15/tailscribe/internal/auth"
 	"github.com/ctiller15/tailscribe/internal/database"
+	"github.com/ctiller15/tailscribe/internal/oauth"
+	"github.com/ctiller15/tailscribe/internal/ratelimit"
+	"github.com/google/uuid"
 	"github.com/imagekit-developer/imagekit-go/v2" // imported as imagekit
 	"github.com/imagekit-developer/imagekit-go/v2/option"
+	"github.com/lib/pq"
 )
 
+// Fields every page has. Forms submit CSRFToken back in a csrf_token field.
 type BasePageData struct {
-	Title string
+	Title     string
+	CSRFToken string
 }
 
 // Probably some sort of abstraction here. I'll figure it out eventually.
@@ -29,10 +39,11 @@ type SignupForm struct {
 	Email    string
 	Password string
 	Valid    bool
+	Errors   map[string]string
 }
 
 type SignupPageData struct {
-	Title string
+	BasePageData
 	SignupForm
 }
 
@@ -43,7 +54,10 @@ type LoginForm struct {
 }
 
 type LoginPageData struct {
-	Title string
+	BasePageData
+	Providers []oauth.Provider
+	// Why a social login didn't go through.
+	Error string
 	LoginForm
 }
 
@@ -54,25 +68,25 @@ type AddNewPetForm struct {
 }
 
 type AddNewPetPageData struct {
-	Title string
+	BasePageData
 	AddNewPetForm
 }
 
 type AttributionsPageData struct {
-	Title string
+	BasePageData
 }
 
 type TermsAndConditionsPageData struct {
-	Title string
+	BasePageData
 }
 
 type PrivacyPolicyPageData struct {
-	Title        string
+	BasePageData
 	ContactEmail string
 }
 
 type ContactUsPageData struct {
-	Title        string
+	BasePageData
 	ContactEmail string
 }
 
@@ -82,75 +96,68 @@ type AddPetForm struct {
 }
 
 type NewPetPageData struct {
-	Title string
+	BasePageData
 	AddPetForm
 }
 
 func (a *APIConfig) HandleIndex(w http.ResponseWriter, r *http.Request) {
-
-	tmpl := template.Must(template.ParseFiles(
-		"./ui/html/base.tmpl",
-		"./ui/html/partials/nav.tmpl",
-		"./ui/html/pages/index.tmpl",
-	))
-
-	err := tmpl.ExecuteTemplate(w, "base", nil)
-	if err != nil {
-		a.Logger.Error(err.Error())
-		return
-	}
+	a.render(w, r, http.StatusOK, "index", nil)
 }
 
 func (a *APIConfig) HandleSignupPage(w http.ResponseWriter, r *http.Request) {
-
-	tmpl := template.Must(template.ParseFiles(
-		"./ui/html/base.tmpl",
-		"./ui/html/partials/nav.tmpl",
-		"./ui/html/pages/signup.tmpl",
-	))
-
-	err := tmpl.ExecuteTemplate(w, "base", nil)
-	if err != nil {
-		a.Logger.Error(err.Error())
-		return
+	data := SignupPageData{
+		BasePageData: basePageData(r, "TailScribe - Sign Up"),
+		SignupForm:   SignupForm{Valid: true},
 	}
+
+	a.render(w, r, http.StatusOK, "signup", data)
 }
 
 func (a *APIConfig) HandlePostSignup(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
-	signupDetails := SignupForm{
-		Email:    r.FormValue("email"),
-		Password: r.FormValue("password"),
+	signupDetails := SignupPageData{
+		BasePageData: basePageData(r, "TailScribe - Sign Up"),
+		SignupForm: SignupForm{
+			Email:    strings.TrimSpace(r.FormValue("email")),
+			Password: r.FormValue("password"),
+		},
 	}
 
-	tmpl := template.Must(template.ParseFiles(
-		"./ui/html/base.tmpl",
-		"./ui/html/partials/nav.tmpl",
-		"./ui/html/pages/signup.tmpl",
-	))
+	// Every attempt counts, successful or not, to stop mass account creation.
+	ip := clientIP(r)
+	wait, err := a.RateLimits.SignupByIP.Attempt(ctx, ip)
+	if err != nil {
+		a.serverError(w, r, "error checking signup rate limit", err)
+		return
+	}
+	if wait > 0 {
+		a.Logger.Warn("signup rate limited", slog.String("ip", ip))
+		a.serveError(w, r, tooManyRequests(wait))
+		return
+	}
 
 	// Validate email.
-	_, err := mail.ParseAddress(signupDetails.Email)
+	_, err = mail.ParseAddress(signupDetails.Email)
 	if err != nil {
 		// Abstract this failure state into a function
 		signupDetails.Valid = false
-		w.WriteHeader(http.StatusBadRequest)
-		err := tmpl.ExecuteTemplate(w, "base", signupDetails)
-		if err != nil {
-			a.Logger.Error(err.Error())
-		}
+		a.render(w, r, http.StatusBadRequest, "signup", signupDetails)
+		return
+	}
+
+	problem := passwordProblem(a.Passwords, signupDetails.Password)
+	if problem != "" {
+		signupDetails.Valid = false
+		signupDetails.Errors = map[string]string{"password": problem}
+		a.render(w, r, http.StatusBadRequest, "signup", signupDetails)
 		return
 	}
 
 	// Hash password.
-	hashedPassword, err := auth.HashPassword(signupDetails.Password)
+	hashedPassword, err := a.Passwords.HashPassword(signupDetails.Password)
 	if err != nil {
 		signupDetails.Valid = false
-		w.WriteHeader(http.StatusBadRequest)
-		err := tmpl.ExecuteTemplate(w, "base", signupDetails)
-		if err != nil {
-			a.Logger.Error(err.Error())
-		}
+		a.render(w, r, http.StatusBadRequest, "signup", signupDetails)
 		return
 	}
 
@@ -168,29 +175,42 @@ func (a *APIConfig) HandlePostSignup(w http.ResponseWriter, r *http.Request) {
 
 	// First is user.
 	user, err := a.Db.CreateUser(ctx, createUserParams)
+	if isUniqueViolation(err) {
+		problem, lookupErr := a.deletedAccountProblem(ctx, signupDetails.Email)
+		if lookupErr != nil {
+			a.serverError(w, r, "error loading deleted user", lookupErr)
+			return
+		}
+		if problem != "" {
+			signupDetails.Valid = false
+			signupDetails.Errors = map[string]string{"email": problem}
+			a.render(w, r, http.StatusConflict, "signup", signupDetails)
+			return
+		}
+	}
 	if err != nil {
 		signupDetails.Valid = false
-		w.WriteHeader(http.StatusBadRequest)
-		err := tmpl.ExecuteTemplate(w, "base", signupDetails)
-		if err != nil {
-			a.Logger.Error(err.Error())
-		}
+		a.render(w, r, http.StatusBadRequest, "signup", signupDetails)
 		return
 	}
 
-	err = a.createAndAttachSessionCookies(&w, user)
+	// The account works without it, so a mail problem shouldn't stop signup.
+	// The user can ask for another link from their account page.
+	err = a.sendVerificationEmail(ctx, user)
+	if err != nil {
+		a.Logger.Error("error sending verification email", slog.String("error", err.Error()))
+	}
+
+	err = a.createAndAttachSessionCookies(&w, r, user)
 	if err != nil {
 		a.Logger.Error("error creating session cookies", slog.String("error", err.Error()))
 		signupDetails.Valid = false
-		w.WriteHeader(http.StatusBadRequest)
-		err := tmpl.ExecuteTemplate(w, "base", signupDetails)
-		if err != nil {
-			a.Logger.Error(err.Error())
-		}
+		a.render(w, r, http.StatusBadRequest, "signup", signupDetails)
 		return
 	}
 
-	http.Redirect(w, r, "/add_new_pet", http.StatusFound)
+	// A new account has no pets yet, so start with adding one.
+	http.Redirect(w, r, "/dashboard/add_new_pet", http.StatusFound)
 }
 
 func expireCookie(w *http.ResponseWriter, cookie_name string) {
@@ -202,11 +222,72 @@ func expireCookie(w *http.ResponseWriter, cookie_name string) {
 	})
 }
 
+// Signs the browser out, giving it a new csrf cookie so its old forms stop working too.
+func (a *APIConfig) clearSessionCookies(w http.ResponseWriter) {
+	expireCookie(&w, "token")
+	expireCookie(&w, "refresh_token")
+
+	_, err := rotateCSRFCookie(w)
+	if err != nil {
+		a.Logger.Error("error rotating csrf cookie", slog.String("error", err.Error()))
+	}
+}
+
+func isNotFound(err error) bool {
+	return errors.Is(err, sql.ErrNoRows)
+}
+
+// Reports whether a write failed on a unique constraint.
+func isUniqueViolation(err error) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == "23505"
+}
+
+// Writes one of the pages in ui/html/pages, or a 500 if it doesn't render.
+func (a *APIConfig) render(w http.ResponseWriter, r *http.Request, status int, page string, data any) {
+	err := a.Templates.Render(w, status, page, data)
+	if err != nil {
+		a.serverError(w, r, "error rendering page "+page, err)
+	}
+}
+
+// How long a refresh token can go unused before the user has to log in again.
+const refreshTokenTTL = time.Hour * 30 * 24
+
+// Starts a new session for the user on the requesting device. The session's
+// id is also the family id of its refresh tokens.
 func (a *APIConfig) createAndAttachSessionCookies(
 	w *http.ResponseWriter,
+	r *http.Request,
 	user database.User,
 ) error {
-	tokenString, err := auth.MakeJWT(user.ID, a.Env.Secret)
+	session, err := a.Db.CreateSession(r.Context(), database.CreateSessionParams{
+		ID:        uuid.New(),
+		UserID:    user.ID,
+		UserAgent: r.UserAgent(),
+		IpAddress: clientIP(r),
+	})
+	if err != nil {
+		return err
+	}
+
+	// Forms from before signing in carry the old csrf cookie's token, and stop working.
+	_, err = rotateCSRFCookie(*w)
+	if err != nil {
+		return err
+	}
+
+	return a.attachSessionCookies(r.Context(), w, user.ID, session.ID)
+}
+
+// Issues an access token for the session and a stored refresh token in its family.
+func (a *APIConfig) attachSessionCookies(
+	ctx context.Context,
+	w *http.ResponseWriter,
+	userID int32,
+	familyID uuid.UUID,
+) error {
+	tokenString, err := auth.MakeJWT(userID, familyID, a.Env.Secret)
 	if err != nil {
 		return err
 	}
@@ -216,6 +297,16 @@ func (a *APIConfig) createAndAttachSessionCookies(
 		return err
 	}
 
+	_, err = a.Db.CreateRefreshToken(ctx, database.CreateRefreshTokenParams{
+		UserID:    userID,
+		TokenHash: auth.HashToken(refreshTokenString),
+		FamilyID:  familyID,
+		ExpiresAt: time.Now().Add(refreshTokenTTL),
+	})
+	if err != nil {
+		return err
+	}
+
 	http.SetCookie(*w, &http.Cookie{
 		Name:     "token",
 		Value:    tokenString,
@@ -228,7 +319,7 @@ func (a *APIConfig) createAndAttachSessionCookies(
 	http.SetCookie(*w, &http.Cookie{
 		Name:     "refresh_token",
 		Value:    refreshTokenString,
-		Expires:  time.Now().Add(time.Hour * 30 * 24),
+		Expires:  time.Now().Add(refreshTokenTTL),
 		HttpOnly: true,
 		// Domain:   "/",
 		SameSite: http.SameSiteStrictMode,
@@ -237,64 +328,100 @@ func (a *APIConfig) createAndAttachSessionCookies(
 	return nil
 }
 
-func RejectPostLogin(
-	w http.ResponseWriter,
-	tmpl *template.Template,
-	loginDetails *LoginForm,
-	status int) error {
-
+// Shows the login form again, marked invalid.
+func (a *APIConfig) rejectPostLogin(w http.ResponseWriter, r *http.Request, loginDetails LoginPageData, status int) {
 	loginDetails.Valid = false
-	w.WriteHeader(status)
+	a.renderLogin(w, r, status, loginDetails)
+}
 
-	err := tmpl.ExecuteTemplate(w, "base", loginDetails)
+// The configured social login providers, in a stable order for the login page.
+func (a *APIConfig) loginProviders() []oauth.Provider {
+	names := slices.Sorted(maps.Keys(a.Providers))
+	providers := make([]oauth.Provider, 0, len(names))
+	for _, name := range names {
+		providers = append(providers, a.Providers[name])
+	}
+	return providers
+}
 
-	return err
+func (a *APIConfig) renderLogin(w http.ResponseWriter, r *http.Request, status int, data LoginPageData) {
+	data.BasePageData = basePageData(r, "TailScribe - Log In")
+	data.Providers = a.loginProviders()
+
+	a.render(w, r, status, "login", data)
+}
+
+func (a *APIConfig) HandleLoginPage(w http.ResponseWriter, r *http.Request) {
+	a.renderLogin(w, r, http.StatusOK, LoginPageData{LoginForm: LoginForm{Valid: true}})
 }
 
 func (a *APIConfig) HandlePostLogin(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
-	loginDetails := LoginForm{
-		Email:    r.FormValue("email"),
-		Password: r.FormValue("password"),
+	loginDetails := LoginPageData{
+		BasePageData: basePageData(r, "TailScribe - Log In"),
+		Providers:    a.loginProviders(),
+		LoginForm: LoginForm{
+			Email:    r.FormValue("email"),
+			Password: r.FormValue("password"),
+		},
 	}
 
-	tmpl := template.Must(template.ParseFiles(
-		"./ui/html/base.tmpl",
-		"./ui/html/partials/nav.tmpl",
-		"./ui/html/pages/login.tmpl",
-	))
+	ip := clientIP(r)
+	emailKey := normalizeEmailKey(loginDetails.Email)
 
-	email := sql.NullString{
-		String: loginDetails.Email,
-		Valid:  true,
+	// Counted before the password is checked, so a locked out account can't be
+	// guessed at even with the right one, and a burst can't all get in at once.
+	wait, err := attemptAll(ctx, map[*ratelimit.Limiter]string{
+		a.RateLimits.LoginByIP:    ip,
+		a.RateLimits.LoginByEmail: emailKey,
+	})
+	if err != nil {
+		a.serverError(w, r, "error checking login rate limit", err)
+		return
+	}
+	if wait > 0 {
+		a.Logger.Warn("login rate limited", slog.String("ip", ip))
+		a.serveError(w, r, tooManyRequests(wait))
+		return
 	}
 
-	user, err := a.Db.GetUserByEmail(ctx, email)
-	if err != nil {
-		err = RejectPostLogin(w, tmpl, &loginDetails, http.StatusUnauthorized)
-		if err != nil {
-			a.Logger.Error(err.Error())
-		}
+	user, err := a.Db.GetUserByEmail(ctx, strings.TrimSpace(loginDetails.Email))
+	hash := user.Password.String
+	// Without a password to check, check a stand-in. Refusing straight away
+	// would be quicker than refusing a wrong password, and so would reveal
+	// which emails have accounts.
+	if err != nil || !user.Password.Valid {
+		hash = a.dummyPasswordHash()
+	}
+
+	valid, rehash := a.Passwords.CheckPasswordHash(loginDetails.Password, hash)
+
+	if err != nil || !user.Password.Valid || !valid {
+		a.rejectPostLogin(w, r, loginDetails, http.StatusUnauthorized)
 		return
 	}
 
-	valid := auth.CheckPasswordHash(loginDetails.Password, user.Password.String)
+	if rehash {
+		a.rehashPassword(ctx, user, loginDetails.Password)
+	}
 
-	if !valid {
-		err = RejectPostLogin(w, tmpl, &loginDetails, http.StatusUnauthorized)
-		if err != nil {
-			a.Logger.Error(err.Error())
-		}
+	// Only the account's count is cleared. The address keeps its attempts, so
+	// logging into one account doesn't buy more guesses at others.
+	err = a.RateLimits.LoginByEmail.Reset(ctx, emailKey)
+	if err != nil {
+		a.Logger.Error("error resetting login rate limit", slog.String("error", err.Error()))
+	}
+
+	if user.TotpEnabledAt.Valid {
+		a.beginTwoFactorLogin(&w, user)
+		http.Redirect(w, r, "/login/two_factor", http.StatusFound)
 		return
 	}
 
-	err = a.createAndAttachSessionCookies(&w, user)
+	err = a.createAndAttachSessionCookies(&w, r, user)
 	if err != nil {
 		a.Logger.Error(err.Error())
-		err = RejectPostLogin(w, tmpl, &loginDetails, http.StatusInternalServerError)
-		if err != nil {
-			a.Logger.Error(err.Error())
-		}
+		a.rejectPostLogin(w, r, loginDetails, http.StatusInternalServerError)
 		return
 	}
 
@@ -302,84 +429,54 @@ func (a *APIConfig) HandlePostLogin(w http.ResponseWriter, r *http.Request) {
 }
 
 func (a *APIConfig) HandlePostLogout(w http.ResponseWriter, r *http.Request) {
-	expireCookie(&w, "token")
-	expireCookie(&w, "refresh_token")
+	// Revoke the whole session, so neither this refresh token nor any it was rotated into can be used again.
+	refreshCookie, err := r.Cookie("refresh_token")
+	if err == nil {
+		stored, err := a.Db.GetRefreshTokenByHash(r.Context(), auth.HashToken(refreshCookie.Value))
+		if err == nil {
+			_, err = a.revokeSession(r.Context(), stored.UserID, stored.FamilyID)
+		}
+		if err != nil && !isNotFound(err) {
+			a.Logger.Error("error revoking refresh token", slog.String("error", err.Error()))
+		}
+	}
+
+	a.clearSessionCookies(w)
 
 	http.Redirect(w, r, "/", http.StatusFound)
 }
 
 func (a *APIConfig) HandleAttributions(w http.ResponseWriter, r *http.Request) {
-	tmpl := template.Must(template.ParseFiles(
-		"./ui/html/base.tmpl",
-		"./ui/html/partials/nav.tmpl",
-		"./ui/html/pages/attributions.tmpl",
-	))
-
-	err := tmpl.ExecuteTemplate(w, "base", nil)
-	// Instead of a log fatal, probably a generic 500 page.
-	if err != nil {
-		a.Logger.Error(err.Error())
-	}
+	a.render(w, r, http.StatusOK, "attributions", nil)
 }
 
 func (a *APIConfig) HandleTerms(w http.ResponseWriter, r *http.Request) {
-	tmpl := template.Must(template.ParseFiles(
-		"./ui/html/base.tmpl",
-		"./ui/html/partials/nav.tmpl",
-		"./ui/html/pages/terms_and_conditions.tmpl",
-	))
-
-	err := tmpl.ExecuteTemplate(w, "base", nil)
-	if err != nil {
-		a.Logger.Error(err.Error())
-	}
+	a.render(w, r, http.StatusOK, "terms_and_conditions", nil)
 }
 
 func (a *APIConfig) HandlePrivacyPolicy(w http.ResponseWriter, r *http.Request) {
-	tmpl := template.Must(template.ParseFiles(
-		"./ui/html/base.tmpl",
-		"./ui/html/partials/nav.tmpl",
-		"./ui/html/pages/privacy_policy.tmpl",
-	))
-
 	data := PrivacyPolicyPageData{
 		ContactEmail: a.Env.ContactEmail,
 	}
 
-	err := tmpl.ExecuteTemplate(w, "base", data)
-	if err != nil {
-		a.Logger.Error(err.Error())
-	}
+	a.render(w, r, http.StatusOK, "privacy_policy", data)
 }
 
 func (a *APIConfig) HandleContactUs(w http.ResponseWriter, r *http.Request) {
-	tmpl := template.Must(template.ParseFiles(
-		"./ui/html/base.tmpl",
-		"./ui/html/partials/nav.tmpl",
-		"./ui/html/pages/contact_us.tmpl",
-	))
-
 	data := ContactUsPageData{
 		ContactEmail: a.Env.ContactEmail,
 	}
 
-	err := tmpl.ExecuteTemplate(w, "base", data)
-	if err != nil {
-		a.Logger.Error(err.Error())
-	}
+	a.render(w, r, http.StatusOK, "contact_us", data)
 }
 
 func (a *APIConfig) HandleGetAddNewPet(w http.ResponseWriter, r *http.Request, user_id int) {
-	tmpl := template.Must(template.ParseFiles(
-		"./ui/html/base.tmpl",
-		"./ui/html/partials/nav.tmpl",
-		"./ui/html/pages/new_pet.tmpl",
-	))
-
-	err := tmpl.ExecuteTemplate(w, "base", nil)
-	if err != nil {
-		a.Logger.Error(err.Error())
+	data := AddNewPetPageData{
+		BasePageData:  basePageData(r, "TailScribe - Add a Pet"),
+		AddNewPetForm: AddNewPetForm{Valid: true},
 	}
+
+	a.render(w, r, http.StatusOK, "new_pet", data)
 }
 
 func (a *APIConfig) HandlePostAddNewPet(w http.ResponseWriter, r *http.Request, user_id int) {
@@ -390,40 +487,39 @@ func (a *APIConfig) HandlePostAddNewPet(w http.ResponseWriter, r *http.Request,
 	}
 
 	addNewPetPageData := AddNewPetPageData{
-		Title:         "TailScribe - Log In",
+		BasePageData:  basePageData(r, "TailScribe - Add a Pet"),
 		AddNewPetForm: addNewPetForm,
 	}
 
 	// Attempt to create pet.
 	imageUrl := sql.NullString{
 		String: addNewPetForm.Image,
-	}
-	createPetParams := database.CreatePetParams{
-		Name:     addNewPetForm.Name,
-		Imageurl: imageUrl,
+		Valid:  addNewPetForm.Image != "",
 	}
 
-	newPet, err := a.Db.CreatePet(ctx, createPetParams)
+	newPet, err := a.createPetWithSlug(ctx, addNewPetForm.Name, imageUrl)
 
 	if err != nil {
 		// return previous page, etc.
 		a.Logger.Error("error creating pet", slog.String("error", err.Error()))
-		tmpl := template.Must(template.ParseFiles(
-			"./templates/new_pet.html",
-			"./templates/base.html",
-		))
-
 		addNewPetPageData.Valid = false
-		w.WriteHeader(http.StatusBadRequest)
+		a.render(w, r, http.StatusBadRequest, "new_pet", addNewPetPageData)
+		return
+	}
 
-		err = tmpl.ExecuteTemplate(w, "base", addNewPetPageData)
-		if err != nil {
-			a.Logger.Error(err.Error())
-		}
+	// Link the creator to the new pet, otherwise nobody can see it.
+	_, err = a.Db.CreateUserPet(ctx, database.CreateUserPetParams{
+		Userid:           int32(user_id),
+		Petid:            newPet.ID,
+		PermissionsLevel: PermissionsOwner,
+		Active:           true,
+	})
+	if err != nil {
+		a.serverError(w, r, "error linking pet to user", err)
 		return
 	}
 
-	http.Redirect(w, r, fmt.Sprintf("/dashboard/pet/%d", newPet.ID), http.StatusCreated)
+	http.Redirect(w, r, fmt.Sprintf("/dashboard/pet/%d", newPet.ID), http.StatusFound)
 }
 
 func (a *APIConfig) HandleGetImageAuthParams(w http.ResponseWriter, r *http.Request, user_id int) {
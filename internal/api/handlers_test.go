@@ -11,6 +11,7 @@ import (
 	"net/url"
 	"os"
 	"regexp"
+	"strconv"
 	"strings"
 	"testing"
 
@@ -24,11 +25,33 @@ import (
 var (
 	TestEnvVars *EnvVars
 
+	TestDB *sql.DB
+
 	DbQueries *database.Queries
 
 	TestLogger *slog.Logger
 )
 
+// Settings for the docker-compose test database, for when .env.test leaves them out.
+var testEnvDefaults = map[string]string{
+	"SECRET":            "a-test-secret-that-is-long-enough-to-sign-tokens",
+	"BASE_URL":          "http://localhost:8080",
+	"POSTGRES_DB":       "animal_training_journal_test",
+	"POSTGRES_USER":     "postgres",
+	"POSTGRES_PASSWORD": "postgres",
+	"POSTGRES_PORT":     "6432",
+	"POSTGRES_SSLMODE":  "disable",
+}
+
+func lookupTestEnv(key string) (string, bool) {
+	value, ok := os.LookupEnv(key)
+	if ok && value != "" {
+		return value, true
+	}
+	value, ok = testEnvDefaults[key]
+	return value, ok
+}
+
 func teardown(ctx context.Context) {
 	DbQueries.DeleteUsers(ctx)
 }
@@ -45,16 +68,17 @@ func init() {
 		log.Printf("error loading .env file: %v.\n May experience degraded behavior during tests.\n", err)
 	}
 
-	TestEnvVars = NewEnvVars()
-
-	dbUrl := TestEnvVars.Database.ConnectionString() + "?sslmode=disable"
+	TestEnvVars, err = LoadEnvVars(lookupTestEnv)
+	if err != nil {
+		log.Fatal(err)
+	}
 
-	db, err := sql.Open("postgres", dbUrl)
+	TestDB, err = TestEnvVars.Database.Open()
 	if err != nil {
 		log.Fatal(err)
 	}
 
-	DbQueries = database.New(db)
+	DbQueries = database.New(TestDB)
 
 	TestLogger = slog.New(slog.NewTextHandler(os.Stdout, nil))
 
@@ -62,9 +86,16 @@ func init() {
 }
 
 func createConfig() *APIConfig {
-	return NewAPIConfig(TestEnvVars, DbQueries, TestLogger)
+	apiCfg, err := NewAPIConfig(TestEnvVars, TestDB, TestLogger)
+	if err != nil {
+		panic(err)
+	}
+	return apiCfg
 }
 
+// Long and uncommon enough for the default password policy.
+const testPassword = "tail-wagging-4-treats"
+
 const letterBytes = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ"
 
 func randStringBytes(n int) string {
@@ -99,6 +130,14 @@ func signUserUp(email, password string) []*http.Cookie {
 	return cookies
 }
 
+func TestRenderFailure(t *testing.T) {
+	request, _ := http.NewRequest(http.MethodGet, "/", nil)
+	response := httptest.NewRecorder()
+	createConfig().render(response, request, http.StatusOK, "no_such_page", nil)
+
+	assert.Equal(t, http.StatusInternalServerError, response.Result().StatusCode)
+}
+
 func TestGetIndex(t *testing.T) {
 	request, _ := http.NewRequest(http.MethodGet, "/", nil)
 	response := httptest.NewRecorder()
@@ -121,7 +160,7 @@ func TestHandlePostSignup(t *testing.T) {
 	t.Run("Happy path", func(t *testing.T) {
 		formData := url.Values{
 			"email":    {randTestEmail()},
-			"password": {"password123"},
+			"password": {testPassword},
 		}
 
 		request, _ := http.NewRequest(http.MethodPost, "/signup", strings.NewReader(formData.Encode()))
@@ -133,7 +172,7 @@ func TestHandlePostSignup(t *testing.T) {
 		result := response.Result()
 		assert.Equal(t, 302, result.StatusCode)
 
-		assert.Equal(t, result.Header.Get("Location"), "/add_new_pet")
+		assert.Equal(t, "/dashboard/add_new_pet", result.Header.Get("Location"))
 
 		cookies := result.Cookies()
 		assert.NotNil(t, cookies[0])
@@ -145,7 +184,7 @@ func TestHandlePostSignup(t *testing.T) {
 	t.Run("Invalid email", func(t *testing.T) {
 		formData := url.Values{
 			"email":    {"invalidEmail"},
-			"password": {"password123"},
+			"password": {testPassword},
 		}
 
 		request, _ := http.NewRequest(http.MethodPost, "/signup", strings.NewReader(formData.Encode()))
@@ -162,7 +201,7 @@ func TestHandleLogout(t *testing.T) {
 	t.Run("Logs out if currently logged in", func(t *testing.T) {
 		formData := url.Values{
 			"email":    {"testEmail1@email.com"},
-			"password": {"password123"},
+			"password": {testPassword},
 		}
 
 		request, _ := http.NewRequest(http.MethodPost, "/signup", strings.NewReader(formData.Encode()))
@@ -220,7 +259,7 @@ func TestHandlePostLogin(t *testing.T) {
 	t.Run("Successfully logs a user in", func(t *testing.T) {
 		formData := url.Values{
 			"email":    {"testEmail2@email.com"},
-			"password": {"password123"},
+			"password": {testPassword},
 		}
 
 		request, _ := http.NewRequest(http.MethodPost, "/signup", strings.NewReader(formData.Encode()))
@@ -234,7 +273,7 @@ func TestHandlePostLogin(t *testing.T) {
 
 		loginFormData := url.Values{
 			"email":    {"testEmail2@email.com"},
-			"password": {"password123"},
+			"password": {testPassword},
 		}
 
 		loginRequest, _ := http.NewRequest(http.MethodPost, "/login", strings.NewReader(loginFormData.Encode()))
@@ -259,7 +298,7 @@ func TestHandlePostLogin(t *testing.T) {
 	t.Run("Fails to log a user in if invalid username/password", func(t *testing.T) {
 		loginFormData := url.Values{
 			"email":    {"testEmail3@email.com"},
-			"password": {"password123"},
+			"password": {testPassword},
 		}
 
 		loginRequest, _ := http.NewRequest(http.MethodPost, "/login", strings.NewReader(loginFormData.Encode()))
@@ -275,6 +314,20 @@ func TestHandlePostLogin(t *testing.T) {
 		cookies := loginResult.Cookies()
 		assert.Len(t, cookies, 0)
 	})
+
+	t.Run("Checks a stand-in hash for an unknown email", func(t *testing.T) {
+		apiCfg := createConfig()
+		checked := false
+		apiCfg.dummyPasswordHash = func() string {
+			checked = true
+			return "$2a$04$invalidinvalidinvalidinvalidinvalidinvalidinvalidinvali"
+		}
+
+		response := postLoginWith(apiCfg, "203.0.113.60:1000", randTestEmail(), testPassword)
+
+		assert.Equal(t, 401, response.Result().StatusCode)
+		assert.True(t, checked)
+	})
 }
 
 // Authorized routes
@@ -291,11 +344,11 @@ func TestGetAddNewPethandler(t *testing.T) {
 		result := response.Result()
 
 		assert.Equal(t, 401, result.StatusCode)
-		assert.Equal(t, result.Header.Get("Location"), "/login")
+		assert.Contains(t, response.Body.String(), `href="/login"`)
 	})
 
 	t.Run("Succeeds at finding add new pet page when authorized", func(t *testing.T) {
-		cookies := signUserUp("testEmail4@test.com", "password123")
+		cookies := signUserUp("testEmail4@test.com", testPassword)
 
 		auth_cookie := *cookies[0]
 
@@ -310,6 +363,8 @@ func TestGetAddNewPethandler(t *testing.T) {
 
 		result := response.Result()
 		assert.Equal(t, 200, result.StatusCode)
+		assert.Contains(t, response.Body.String(), `action="/dashboard/add_new_pet"`)
+		assert.Contains(t, response.Body.String(), `name="csrf_token"`)
 	})
 }
 
@@ -326,12 +381,12 @@ func TestHandlePostAddNewPet(t *testing.T) {
 
 		result := response.Result()
 		assert.Equal(t, 401, result.StatusCode)
-		assert.Equal(t, result.Header.Get("Location"), "/login")
+		assert.Contains(t, response.Body.String(), `href="/login"`)
 	})
 
 	t.Run("Succeeds at creating new pet when authorized", func(t *testing.T) {
 		testEmail := randTestEmail()
-		cookies := signUserUp(testEmail, "password123")
+		cookies := signUserUp(testEmail, testPassword)
 
 		auth_cookie := *cookies[0]
 
@@ -351,7 +406,7 @@ func TestHandlePostAddNewPet(t *testing.T) {
 		)(response, request)
 
 		result := response.Result()
-		assert.Equal(t, 201, result.StatusCode)
+		assert.Equal(t, 302, result.StatusCode)
 
 		pathRegex := `/dashboard/pet/\d+`
 		matched, err := regexp.MatchString(pathRegex, result.Header.Get("Location"))
@@ -361,6 +416,26 @@ func TestHandlePostAddNewPet(t *testing.T) {
 
 		assert.True(t, matched)
 	})
+
+	t.Run("Saves the image only when one is given", func(t *testing.T) {
+		cookies := signUserUp(randTestEmail(), testPassword)
+		withoutID := createPetForUser(t, cookies[0], "Plain")
+
+		formData := url.Values{"name": {"Pictured"}, "image": {"https://example.com/rex.png"}}
+		request, _ := http.NewRequest(http.MethodPost, "/dashboard/add_new_pet", strings.NewReader(formData.Encode()))
+		request.Header.Add("Content-Type", "application/x-www-form-urlencoded")
+		request.AddCookie(cookies[0])
+		response := httptest.NewRecorder()
+		apiCfg := createConfig()
+		apiCfg.CheckAuthMiddleware(apiCfg.HandlePostAddNewPet)(response, request)
+		withID, err := strconv.Atoi(strings.TrimPrefix(response.Result().Header.Get("Location"), "/dashboard/pet/"))
+		assert.NoError(t, err)
+
+		without, _ := apiCfg.Db.GetPet(t.Context(), int32(withoutID))
+		assert.False(t, without.Imageurl.Valid)
+		with, _ := apiCfg.Db.GetPet(t.Context(), int32(withID))
+		assert.Equal(t, sql.NullString{String: "https://example.com/rex.png", Valid: true}, with.Imageurl)
+	})
 }
 
 // Unauthorized routes
@@ -0,0 +1,186 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"net/http"
+	"path"
+	"runtime/debug"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ctiller15/tailscribe/sql/schema"
+)
+
+// How long one round of readiness checks may take.
+const readinessTimeout = 2 * time.Second
+
+type ReadinessResponse struct {
+	Status string `json:"status"`
+	// Each check's result: "ok", or what went wrong.
+	Checks map[string]string `json:"checks,omitempty"`
+}
+
+type VersionResponse struct {
+	Version   string `json:"version"`
+	Revision  string `json:"revision,omitempty"`
+	Time      string `json:"time,omitempty"`
+	Modified  bool   `json:"modified"`
+	GoVersion string `json:"go_version"`
+}
+
+func writeJSON(w http.ResponseWriter, status int, body any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(body)
+}
+
+// The newest migration in fsys, going by the numbers goose filenames start with.
+func latestMigration(fsys fs.FS) (int64, error) {
+	files, err := fs.Glob(fsys, "*.sql")
+	if err != nil {
+		return 0, err
+	}
+
+	var latest int64
+	for _, file := range files {
+		number, _, _ := strings.Cut(path.Base(file), "_")
+		version, err := strconv.ParseInt(number, 10, 64)
+		if err != nil {
+			return 0, fmt.Errorf("migration %s doesn't start with a version number", file)
+		}
+		latest = max(latest, version)
+	}
+
+	if latest == 0 {
+		return 0, fmt.Errorf("no migrations found")
+	}
+	return latest, nil
+}
+
+// The version goose last applied. Its table isn't part of the sqlc schema, so
+// this queries the pool directly.
+func (a *APIConfig) migrationVersion(ctx context.Context) (int64, error) {
+	var version int64
+	err := a.Pool.QueryRowContext(ctx,
+		"SELECT version_id FROM goose_db_version WHERE is_applied ORDER BY id DESC LIMIT 1",
+	).Scan(&version)
+	return version, err
+}
+
+// Checks the database is reachable and migrated to the schema this build expects.
+// The results are public, so the details only go to the log.
+func (a *APIConfig) readinessChecks(ctx context.Context) (map[string]string, bool) {
+	ctx, cancel := context.WithTimeout(ctx, readinessTimeout)
+	defer cancel()
+
+	checks := map[string]string{"database": "ok", "migrations": "ok"}
+
+	err := a.Pool.PingContext(ctx)
+	if err != nil {
+		a.Logger.Warn("readiness: database unreachable", slog.String("error", err.Error()))
+		checks["database"] = "unavailable"
+		checks["migrations"] = "not checked"
+		return checks, false
+	}
+
+	want, err := latestMigration(schema.Migrations)
+	if err != nil {
+		a.Logger.Warn("readiness: reading the bundled migrations", slog.String("error", err.Error()))
+		checks["migrations"] = "unavailable"
+		return checks, false
+	}
+
+	got, err := a.migrationVersion(ctx)
+	if err != nil {
+		a.Logger.Warn("readiness: reading the migration version", slog.String("error", err.Error()))
+		checks["migrations"] = "unavailable"
+		return checks, false
+	}
+	if got != want {
+		a.Logger.Warn("readiness: migration version mismatch", slog.Int64("database", got), slog.Int64("expected", want))
+		checks["migrations"] = "mismatch"
+		return checks, false
+	}
+
+	return checks, true
+}
+
+// Runs the readiness checks every interval until they pass, then lets /readyz
+// report ready. Until then, load balancers keep traffic away.
+func (a *APIConfig) RunStartupChecks(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		checks, ok := a.readinessChecks(ctx)
+		if ok {
+			a.Logger.Info("startup checks passed")
+			a.ready.Store(true)
+			return
+		}
+		a.Logger.Warn("startup checks failed, retrying",
+			slog.String("database", checks["database"]),
+			slog.String("migrations", checks["migrations"]),
+		)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Liveness: the process is up and serving requests. It checks nothing else, so
+// a database outage doesn't get the server restarted.
+func (a *APIConfig) HandleHealthz(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, ReadinessResponse{Status: "ok"})
+}
+
+// Readiness: whether the server should be sent traffic.
+func (a *APIConfig) HandleReadyz(w http.ResponseWriter, r *http.Request) {
+	if !a.ready.Load() {
+		writeJSON(w, http.StatusServiceUnavailable, ReadinessResponse{Status: "starting"})
+		return
+	}
+
+	checks, ok := a.readinessChecks(r.Context())
+	if !ok {
+		writeJSON(w, http.StatusServiceUnavailable, ReadinessResponse{Status: "unavailable", Checks: checks})
+		return
+	}
+
+	writeJSON(w, http.StatusOK, ReadinessResponse{Status: "ready", Checks: checks})
+}
+
+// What was built: the module version, and the commit when built from a checkout.
+func (a *APIConfig) HandleVersion(w http.ResponseWriter, r *http.Request) {
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		a.serveError(w, r, internalError("error reading build info", fmt.Errorf("binary built without module support")))
+		return
+	}
+
+	version := VersionResponse{
+		Version:   info.Main.Version,
+		GoVersion: info.GoVersion,
+	}
+	for _, setting := range info.Settings {
+		switch setting.Key {
+		case "vcs.revision":
+			version.Revision = setting.Value
+		case "vcs.time":
+			version.Time = setting.Value
+		case "vcs.modified":
+			version.Modified = setting.Value == "true"
+		}
+	}
+
+	writeJSON(w, http.StatusOK, version)
+}
@@ -0,0 +1,487 @@
+package api
+
+import (
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/mail"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ctiller15/tailscribe/internal/auth"
+	"github.com/ctiller15/tailscribe/internal/database"
+	"github.com/ctiller15/tailscribe/internal/mailer"
+)
+
+// How long an invite link keeps working.
+const invitationTTL = 7 * 24 * time.Hour
+
+type PermissionLevel struct {
+	Level int32
+	Name  string
+}
+
+// Levels an owner can hand out. Invitations can't make someone an owner.
+var InvitablePermissionLevels = []PermissionLevel{
+	{PermissionsViewer, "viewer"},
+	{PermissionsLogger, "logger"},
+	{PermissionsCoOwner, "co-owner"},
+}
+
+// Levels an owner can move an existing member between.
+var PermissionLevels = []PermissionLevel{
+	{PermissionsViewer, "viewer"},
+	{PermissionsLogger, "logger"},
+	{PermissionsCoOwner, "co-owner"},
+	{PermissionsOwner, "owner"},
+}
+
+// Names a UserPets.permissions_level for display.
+func PermissionsName(level int32) string {
+	for _, permissionLevel := range PermissionLevels {
+		if permissionLevel.Level == level {
+			return permissionLevel.Name
+		}
+	}
+
+	return "unknown"
+}
+
+type InvitationForm struct {
+	Email            string
+	PermissionsLevel string
+	Valid            bool
+	Errors           map[string]string
+}
+
+type PetAccessPageData struct {
+	BasePageData
+	Pet         database.Pet
+	UserID      int32
+	Members     []database.ListPetAccessRow
+	Invitations []PendingInvitation
+	Levels      []PermissionLevel
+	AllLevels   []PermissionLevel
+	// Set right after an invitation is created: "emailed", or "unsent" if the email failed.
+	Invited string
+	// Unverified owners see the invite form, but can't use it.
+	CanInvite bool
+	InvitationForm
+}
+
+// An invitation that hasn't been accepted yet, with a link for the owner to
+// pass on if the email doesn't arrive.
+type PendingInvitation struct {
+	database.PetInvitation
+	Link string
+}
+
+type InvitationPageData struct {
+	BasePageData
+	Pet        database.Pet
+	Invitation database.PetInvitation
+	// Name of the access the invitation grants.
+	Access string
+	Token  string
+}
+
+// Parses a permission level from a form, accepting only the given levels.
+func parsePermissionsLevel(raw string, allowed []PermissionLevel) (int32, bool) {
+	level, err := strconv.ParseInt(raw, 10, 32)
+	if err != nil {
+		return 0, false
+	}
+
+	for _, permissionLevel := range allowed {
+		if permissionLevel.Level == int32(level) {
+			return int32(level), true
+		}
+	}
+
+	return 0, false
+}
+
+func (f *InvitationForm) validate() (int32, bool) {
+	f.Errors = map[string]string{}
+
+	_, err := mail.ParseAddress(f.Email)
+	if err != nil {
+		f.Errors["email"] = "Enter the email address to invite."
+	}
+
+	level, ok := parsePermissionsLevel(f.PermissionsLevel, InvitablePermissionLevels)
+	if !ok {
+		f.Errors["permissions_level"] = "Choose viewer, logger or co-owner."
+	}
+
+	f.Valid = len(f.Errors) == 0
+	return level, f.Valid
+}
+
+func invitationEmail(to string, pet database.Pet, level int32, link string) mailer.Message {
+	return mailer.Message{
+		To:      to,
+		Subject: fmt.Sprintf("You're invited to follow %s on TailScribe", pet.Name),
+		Body: fmt.Sprintf("You've been invited to follow %s's training on TailScribe as a %s.\n\n"+
+			"Follow this link within the next week to accept:\n\n%s\n\n"+
+			"If you weren't expecting this, you can ignore this email.\n", pet.Name, PermissionsName(level), link),
+	}
+}
+
+// Links to an invitation. Any number can be signed for one, and each stops working once it's accepted.
+func (a *APIConfig) invitationLink(invitation database.PetInvitation) (string, error) {
+	token, err := auth.MakeInvitationToken(invitation.ID, invitation.ExpiresAt, a.Env.Secret)
+	if err != nil {
+		return "", err
+	}
+
+	return a.siteURL("/invitations/" + token), nil
+}
+
+func (a *APIConfig) renderPetAccess(w http.ResponseWriter, r *http.Request, status int, pet database.Pet, userID int, form InvitationForm) {
+	ctx := r.Context()
+	user, err := a.Db.GetUser(ctx, int32(userID))
+	if err != nil {
+		a.serverError(w, r, "error loading user", err)
+		return
+	}
+
+	members, err := a.Db.ListPetAccess(ctx, pet.ID)
+	if err != nil {
+		a.serverError(w, r, "error listing pet access", err)
+		return
+	}
+
+	invitations, err := a.Db.ListPendingPetInvitations(ctx, pet.ID)
+	if err != nil {
+		a.serverError(w, r, "error listing pet invitations", err)
+		return
+	}
+
+	pending := make([]PendingInvitation, 0, len(invitations))
+	for _, invitation := range invitations {
+		link, err := a.invitationLink(invitation)
+		if err != nil {
+			a.serverError(w, r, "error signing pet invitation", err)
+			return
+		}
+		pending = append(pending, PendingInvitation{PetInvitation: invitation, Link: link})
+	}
+
+	data := PetAccessPageData{
+		BasePageData:   basePageData(r, fmt.Sprintf("%s - Access", pet.Name)),
+		Pet:            pet,
+		UserID:         int32(userID),
+		Members:        members,
+		Invitations:    pending,
+		Levels:         InvitablePermissionLevels,
+		AllLevels:      PermissionLevels,
+		Invited:        r.URL.Query().Get("invited"),
+		CanInvite:      user.EmailVerifiedAt.Valid,
+		InvitationForm: form,
+	}
+
+	a.render(w, r, status, "pet_access", data)
+}
+
+func (a *APIConfig) HandleGetPetAccess(w http.ResponseWriter, r *http.Request, user_id int) {
+	pet, _, ok := a.linkedPetFromPath(w, r, user_id, PermissionsOwner)
+	if !ok {
+		return
+	}
+
+	form := InvitationForm{
+		PermissionsLevel: strconv.Itoa(int(PermissionsViewer)),
+		Valid:            true,
+	}
+	a.renderPetAccess(w, r, http.StatusOK, pet, user_id, form)
+}
+
+// Invites someone to the pet by email. The signed link is emailed to them, and
+// the owner can copy it from the pending invitations too.
+func (a *APIConfig) HandlePostPetInvitation(w http.ResponseWriter, r *http.Request, user_id int) {
+	pet, _, ok := a.linkedPetFromPath(w, r, user_id, PermissionsOwner)
+	if !ok {
+		return
+	}
+
+	// Otherwise an unverified account could send invitations from any address.
+	if !a.checkVerifiedEmail(w, r, user_id) {
+		return
+	}
+
+	form := InvitationForm{
+		Email:            strings.TrimSpace(r.FormValue("email")),
+		PermissionsLevel: r.FormValue("permissions_level"),
+	}
+	level, valid := form.validate()
+	if !valid {
+		a.renderPetAccess(w, r, http.StatusBadRequest, pet, user_id, form)
+		return
+	}
+
+	invitation, err := a.Db.CreatePetInvitation(r.Context(), database.CreatePetInvitationParams{
+		PetID:            pet.ID,
+		Email:            form.Email,
+		PermissionsLevel: level,
+		InvitedBy:        sql.NullInt32{Int32: int32(user_id), Valid: true},
+		ExpiresAt:        time.Now().Add(invitationTTL),
+	})
+	if err != nil {
+		a.serverError(w, r, "error creating pet invitation", err)
+		return
+	}
+
+	link, err := a.invitationLink(invitation)
+	if err != nil {
+		a.serverError(w, r, "error signing pet invitation", err)
+		return
+	}
+
+	invited := "emailed"
+	// The invitation exists either way, and the owner can pass the link on themselves.
+	err = a.Mailer.Send(r.Context(), invitationEmail(invitation.Email, pet, level, link))
+	if err != nil {
+		a.Logger.Error("error sending invitation email", slog.String("error", err.Error()))
+		invited = "unsent"
+	}
+
+	http.Redirect(w, r, fmt.Sprintf("/dashboard/pet/%d/access?invited=%s", pet.ID, invited), http.StatusSeeOther)
+}
+
+func (a *APIConfig) HandlePostRevokePetInvitation(w http.ResponseWriter, r *http.Request, user_id int) {
+	pet, _, ok := a.linkedPetFromPath(w, r, user_id, PermissionsOwner)
+	if !ok {
+		return
+	}
+
+	invitationID, err := pathID(r, "invitationID")
+	if err != nil {
+		a.serveError(w, r, errNotFound)
+		return
+	}
+
+	err = a.Db.RevokePetInvitation(r.Context(), database.RevokePetInvitationParams{
+		ID:    invitationID,
+		PetID: pet.ID,
+	})
+	if err != nil {
+		a.serverError(w, r, "error revoking pet invitation", err)
+		return
+	}
+
+	http.Redirect(w, r, fmt.Sprintf("/dashboard/pet/%d/access", pet.ID), http.StatusFound)
+}
+
+// Resolves the {userID} path value to another member of the pet. Owners
+// can't change their own access, so a pet always keeps at least one owner.
+func (a *APIConfig) memberFromPath(w http.ResponseWriter, r *http.Request, pet database.Pet, userID int) (database.Userpet, bool) {
+	memberID, err := pathID(r, "userID")
+	if err != nil {
+		a.serveError(w, r, errNotFound)
+		return database.Userpet{}, false
+	}
+
+	if memberID == int32(userID) {
+		a.serveError(w, r, badRequest("You can't change your own access."))
+		return database.Userpet{}, false
+	}
+
+	member, err := a.Db.GetUserPet(r.Context(), database.GetUserPetParams{
+		Userid: memberID,
+		Petid:  pet.ID,
+	})
+	if err != nil {
+		if isNotFound(err) {
+			a.serveError(w, r, errNotFound)
+		} else {
+			a.serverError(w, r, "error loading pet member", err)
+		}
+		return database.Userpet{}, false
+	}
+
+	return member, true
+}
+
+func (a *APIConfig) HandlePostPetAccess(w http.ResponseWriter, r *http.Request, user_id int) {
+	pet, _, ok := a.linkedPetFromPath(w, r, user_id, PermissionsOwner)
+	if !ok {
+		return
+	}
+
+	member, ok := a.memberFromPath(w, r, pet, user_id)
+	if !ok {
+		return
+	}
+
+	level, ok := parsePermissionsLevel(r.FormValue("permissions_level"), PermissionLevels)
+	if !ok {
+		a.serveError(w, r, badRequest("Choose a permission level."))
+		return
+	}
+
+	err := a.Db.UpdateUserPetPermissions(r.Context(), database.UpdateUserPetPermissionsParams{
+		Userid:           member.Userid,
+		Petid:            pet.ID,
+		PermissionsLevel: level,
+	})
+	if err != nil {
+		a.serverError(w, r, "error updating pet access", err)
+		return
+	}
+
+	http.Redirect(w, r, fmt.Sprintf("/dashboard/pet/%d/access", pet.ID), http.StatusFound)
+}
+
+func (a *APIConfig) HandlePostRevokePetAccess(w http.ResponseWriter, r *http.Request, user_id int) {
+	pet, _, ok := a.linkedPetFromPath(w, r, user_id, PermissionsOwner)
+	if !ok {
+		return
+	}
+
+	member, ok := a.memberFromPath(w, r, pet, user_id)
+	if !ok {
+		return
+	}
+
+	err := a.Db.DeleteUserPet(r.Context(), database.DeleteUserPetParams{
+		Userid: member.Userid,
+		Petid:  pet.ID,
+	})
+	if err != nil {
+		a.serverError(w, r, "error revoking pet access", err)
+		return
+	}
+
+	http.Redirect(w, r, fmt.Sprintf("/dashboard/pet/%d/access", pet.ID), http.StatusFound)
+}
+
+// Resolves the {token} path value to a pending invitation and its pet.
+// Bad, expired, used and revoked invitations are all a 404.
+func (a *APIConfig) invitationFromPath(w http.ResponseWriter, r *http.Request) (database.PetInvitation, database.Pet, bool) {
+	ctx := r.Context()
+	invitationID, err := auth.ValidateInvitationToken(r.PathValue("token"), a.Env.Secret)
+	if err != nil {
+		a.serveError(w, r, errNotFound)
+		return database.PetInvitation{}, database.Pet{}, false
+	}
+
+	invitation, err := a.Db.GetPetInvitation(ctx, invitationID)
+	if err == nil && (invitation.AcceptedAt.Valid || invitation.RevokedAt.Valid || time.Now().After(invitation.ExpiresAt)) {
+		err = sql.ErrNoRows
+	}
+	if err != nil {
+		if isNotFound(err) {
+			a.serveError(w, r, errNotFound)
+		} else {
+			a.serverError(w, r, "error loading pet invitation", err)
+		}
+		return database.PetInvitation{}, database.Pet{}, false
+	}
+
+	pet, err := a.Db.GetPet(ctx, invitation.PetID)
+	if err != nil {
+		a.serverError(w, r, "error loading pet", err)
+		return database.PetInvitation{}, database.Pet{}, false
+	}
+
+	return invitation, pet, true
+}
+
+func (a *APIConfig) HandleGetInvitation(w http.ResponseWriter, r *http.Request, user_id int) {
+	invitation, pet, ok := a.invitationFromPath(w, r)
+	if !ok {
+		return
+	}
+
+	data := InvitationPageData{
+		BasePageData: basePageData(r, fmt.Sprintf("Join %s on TailScribe", pet.Name)),
+		Pet:          pet,
+		Invitation:   invitation,
+		Access:       PermissionsName(invitation.PermissionsLevel),
+		Token:        r.PathValue("token"),
+	}
+
+	a.render(w, r, http.StatusOK, "invitation", data)
+}
+
+// Accepts an invitation for the signed in user, linking them to the pet.
+// The user's email has to match the address the invitation was sent to.
+func (a *APIConfig) HandlePostAcceptInvitation(w http.ResponseWriter, r *http.Request, user_id int) {
+	ctx := r.Context()
+	invitation, pet, ok := a.invitationFromPath(w, r)
+	if !ok {
+		return
+	}
+
+	user, err := a.Db.GetUser(ctx, int32(user_id))
+	if err != nil {
+		a.serverError(w, r, "error loading user", err)
+		return
+	}
+
+	if !strings.EqualFold(user.Email.String, invitation.Email) {
+		a.serveError(w, r, forbidden("This invitation was sent to a different email address."))
+		return
+	}
+
+	// Otherwise anyone could sign up with the invited address and take the invitation.
+	if !user.EmailVerifiedAt.Valid {
+		a.renderVerifyEmail(w, r, http.StatusForbidden, VerifyEmailPageData{Required: true})
+		return
+	}
+
+	// Together, so the invitation is only used up if the user gets the access.
+	err = a.inTx(ctx, func(q *database.Queries) error {
+		accepted, err := q.AcceptPetInvitation(ctx, database.AcceptPetInvitationParams{
+			ID:         invitation.ID,
+			AcceptedBy: sql.NullInt32{Int32: int32(user_id), Valid: true},
+		})
+		if err != nil {
+			return err
+		}
+		if accepted == 0 {
+			// Used or revoked since the page loaded.
+			return sql.ErrNoRows
+		}
+
+		existing, err := q.GetUserPet(ctx, database.GetUserPetParams{
+			Userid: int32(user_id),
+			Petid:  pet.ID,
+		})
+		switch {
+		case err == nil:
+			// Already linked. Invitations only ever raise access.
+			if existing.PermissionsLevel < invitation.PermissionsLevel {
+				return q.UpdateUserPetPermissions(ctx, database.UpdateUserPetPermissionsParams{
+					Userid:           int32(user_id),
+					Petid:            pet.ID,
+					PermissionsLevel: invitation.PermissionsLevel,
+				})
+			}
+			return nil
+		case isNotFound(err):
+			_, err = q.CreateUserPet(ctx, database.CreateUserPetParams{
+				Userid:           int32(user_id),
+				Petid:            pet.ID,
+				PermissionsLevel: invitation.PermissionsLevel,
+				Active:           true,
+			})
+			return err
+		default:
+			return err
+		}
+	})
+	if err != nil {
+		if isNotFound(err) {
+			a.serveError(w, r, errNotFound)
+		} else {
+			a.serverError(w, r, "error accepting pet invitation", err)
+		}
+		return
+	}
+
+	http.Redirect(w, r, fmt.Sprintf("/dashboard/pet/%d", pet.ID), http.StatusFound)
+}
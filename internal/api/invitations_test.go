@@ -0,0 +1,189 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/ctiller15/tailscribe/internal/auth"
+	"github.com/ctiller15/tailscribe/internal/mailer"
+	"github.com/stretchr/testify/assert"
+)
+
+var inviteTokenPattern = regexp.MustCompile(`/invitations/([A-Za-z0-9_.-]+)`)
+
+// Returns the invite token from the one email in dir.
+func emailedInviteToken(t *testing.T, dir string) string {
+	files, _ := filepath.Glob(filepath.Join(dir, "*.eml"))
+	if len(files) != 1 {
+		t.Fatalf("expected one invitation email, found %d", len(files))
+	}
+	contents, err := os.ReadFile(files[0])
+	assert.NoError(t, err)
+
+	match := inviteTokenPattern.FindStringSubmatch(string(contents))
+	if match == nil {
+		t.Fatalf("no invite link in %q", contents)
+	}
+	return match[1]
+}
+
+// Invites email to the pet at the given level and returns the token from the emailed link.
+func invite(t *testing.T, ownerCookie *http.Cookie, petID int, email string, level int32) string {
+	formData := url.Values{
+		"email":             {email},
+		"permissions_level": {strconv.Itoa(int(level))},
+	}
+	request, _ := http.NewRequest(http.MethodPost, fmt.Sprintf("/dashboard/pet/%d/access/invitations", petID), strings.NewReader(formData.Encode()))
+	request.Header.Add("Content-Type", "application/x-www-form-urlencoded")
+	request.SetPathValue("id", strconv.Itoa(petID))
+	request.AddCookie(ownerCookie)
+	response := httptest.NewRecorder()
+
+	dir := t.TempDir()
+	apiCfg := createConfig()
+	apiCfg.Mailer = &mailer.FileMailer{Dir: dir}
+	apiCfg.CheckAuthMiddleware(apiCfg.HandlePostPetInvitation)(response, request)
+
+	assert.Equal(t, http.StatusSeeOther, response.Result().StatusCode)
+	return emailedInviteToken(t, dir)
+}
+
+func getPetAccess(authCookie *http.Cookie, petID int, query string) *httptest.ResponseRecorder {
+	request, _ := http.NewRequest(http.MethodGet, fmt.Sprintf("/dashboard/pet/%d/access?%s", petID, query), nil)
+	request.SetPathValue("id", strconv.Itoa(petID))
+	request.AddCookie(authCookie)
+	response := httptest.NewRecorder()
+
+	apiCfg := createConfig()
+	apiCfg.CheckAuthMiddleware(apiCfg.HandleGetPetAccess)(response, request)
+
+	return response
+}
+
+func acceptInvitation(authCookie *http.Cookie, token string) *http.Response {
+	request, _ := http.NewRequest(http.MethodPost, fmt.Sprintf("/invitations/%s", token), nil)
+	request.SetPathValue("token", token)
+	request.AddCookie(authCookie)
+	response := httptest.NewRecorder()
+
+	apiCfg := createConfig()
+	apiCfg.CheckAuthMiddleware(apiCfg.HandlePostAcceptInvitation)(response, request)
+
+	return response.Result()
+}
+
+func TestInvitationFormValidate(t *testing.T) {
+	t.Run("Accepts a co-owner invitation", func(t *testing.T) {
+		form := InvitationForm{Email: "trainer@example.com", PermissionsLevel: "3"}
+		level, valid := form.validate()
+		assert.True(t, valid)
+		assert.Equal(t, PermissionsCoOwner, level)
+	})
+
+	t.Run("Can't invite another owner", func(t *testing.T) {
+		form := InvitationForm{Email: "trainer@example.com", PermissionsLevel: "4"}
+		_, valid := form.validate()
+		assert.False(t, valid)
+		assert.Contains(t, form.Errors, "permissions_level")
+	})
+}
+
+func TestHandlePostPetInvitationSendsEmail(t *testing.T) {
+	dir := t.TempDir()
+	ownerCookies := signUpVerified(t, randTestEmail(), testPassword)
+	petID := createPetForUser(t, ownerCookies[0], "Clover")
+	inviteeEmail := randTestEmail()
+
+	formData := url.Values{"email": {inviteeEmail}, "permissions_level": {strconv.Itoa(int(PermissionsLogger))}}
+	request, _ := http.NewRequest(http.MethodPost, fmt.Sprintf("/dashboard/pet/%d/access/invitations", petID), strings.NewReader(formData.Encode()))
+	request.Header.Add("Content-Type", "application/x-www-form-urlencoded")
+	request.SetPathValue("id", strconv.Itoa(petID))
+	request.AddCookie(ownerCookies[0])
+	request.Host = "evil.example"
+	response := httptest.NewRecorder()
+
+	apiCfg := createConfig()
+	apiCfg.Mailer = &mailer.FileMailer{Dir: dir}
+	apiCfg.CheckAuthMiddleware(apiCfg.HandlePostPetInvitation)(response, request)
+	assert.Equal(t, http.StatusSeeOther, response.Result().StatusCode)
+	location := fmt.Sprintf("/dashboard/pet/%d/access?invited=emailed", petID)
+	assert.Equal(t, location, response.Result().Header.Get("Location"))
+
+	files, _ := filepath.Glob(filepath.Join(dir, "*.eml"))
+	if len(files) == 0 {
+		t.Fatal("no invitation email sent")
+	}
+	contents, err := os.ReadFile(files[0])
+	assert.NoError(t, err)
+	assert.Contains(t, string(contents), inviteeEmail)
+	assert.Contains(t, string(contents), "as a logger")
+	assert.Contains(t, string(contents), TestEnvVars.BaseURL+"/invitations/")
+	assert.NotContains(t, string(contents), "evil.example")
+	emailedID, err := auth.ValidateInvitationToken(emailedInviteToken(t, dir), TestEnvVars.Secret)
+	assert.NoError(t, err)
+
+	// The owner can copy a link to the same invitation, in case the email goes astray.
+	page := getPetAccess(ownerCookies[0], petID, "invited=emailed")
+	assert.Contains(t, page.Body.String(), "We've emailed them an invitation")
+	match := inviteTokenPattern.FindStringSubmatch(page.Body.String())
+	if match == nil {
+		t.Fatal("invite link missing from the access page")
+	}
+	shownID, err := auth.ValidateInvitationToken(match[1], TestEnvVars.Secret)
+	assert.NoError(t, err)
+	assert.Equal(t, emailedID, shownID)
+}
+
+func TestHandlePostAcceptInvitation(t *testing.T) {
+	t.Run("Links the invitee at the invited level", func(t *testing.T) {
+		ownerCookies := signUpVerified(t, randTestEmail(), testPassword)
+		petID := createPetForUser(t, ownerCookies[0], "Clover")
+
+		trainerEmail := randTestEmail()
+		token := invite(t, ownerCookies[0], petID, trainerEmail, PermissionsViewer)
+
+		trainerCookies := signUpVerified(t, trainerEmail, testPassword)
+		result := acceptInvitation(trainerCookies[0], token)
+		assert.Equal(t, 302, result.StatusCode)
+
+		dashboard := getPetDashboard(trainerCookies[0], strconv.Itoa(petID))
+		assert.Equal(t, 200, dashboard.Result().StatusCode)
+
+		// Viewers can't change anything.
+		result = postSkill(trainerCookies[0], petID, url.Values{
+			"name":  {"sit"},
+			"stage": {"learning"},
+		})
+		assert.Equal(t, 403, result.StatusCode)
+
+		// Links only work once.
+		result = acceptInvitation(trainerCookies[0], token)
+		assert.Equal(t, 404, result.StatusCode)
+	})
+
+	t.Run("Rejects a user the invitation wasn't sent to", func(t *testing.T) {
+		ownerCookies := signUpVerified(t, randTestEmail(), testPassword)
+		petID := createPetForUser(t, ownerCookies[0], "Clover")
+		token := invite(t, ownerCookies[0], petID, randTestEmail(), PermissionsLogger)
+
+		strangerCookies := signUserUp(randTestEmail(), testPassword)
+		result := acceptInvitation(strangerCookies[0], token)
+
+		assert.Equal(t, 403, result.StatusCode)
+	})
+
+	t.Run("Rejects a tampered token", func(t *testing.T) {
+		cookies := signUserUp(randTestEmail(), testPassword)
+		result := acceptInvitation(cookies[0], "not-a-token")
+
+		assert.Equal(t, 404, result.StatusCode)
+	})
+}
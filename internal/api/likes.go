@@ -0,0 +1,334 @@
+package api
+
+import (
+	"database/sql"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/ctiller15/tailscribe/internal/database"
+)
+
+// Kinds of reinforcer a pet can like.
+var LikeCategories = []string{"food", "toy", "play", "environmental"}
+
+// How hard a pet will work for a reinforcer, lowest first.
+var LikeValueTiers = []string{"low", "medium", "high"}
+
+type LikeForm struct {
+	Name      string
+	Category  string
+	ValueTier string
+	Notes     string
+	Valid     bool
+	Errors    map[string]string
+}
+
+type LikesPageData struct {
+	BasePageData
+	Pet        database.Pet
+	Likes      []database.Like
+	Categories []string
+	ValueTiers []string
+	LikeForm
+}
+
+type LikePageData struct {
+	BasePageData
+	Pet        database.Pet
+	Like       database.Like
+	Categories []string
+	ValueTiers []string
+	LikeForm
+}
+
+func likeFormFromRequest(r *http.Request) LikeForm {
+	return LikeForm{
+		Name:      strings.TrimSpace(r.FormValue("name")),
+		Category:  r.FormValue("category"),
+		ValueTier: r.FormValue("value_tier"),
+		Notes:     strings.TrimSpace(r.FormValue("notes")),
+	}
+}
+
+func likeFormFromLike(like database.Like) LikeForm {
+	return LikeForm{
+		Name:      like.Name,
+		Category:  like.Category,
+		ValueTier: like.ValueTier,
+		Notes:     like.Notes.String,
+		Valid:     true,
+	}
+}
+
+func (f *LikeForm) validate() (sql.NullString, bool) {
+	f.Errors = map[string]string{}
+
+	if f.Name == "" {
+		f.Errors["name"] = "Enter what your pet likes."
+	}
+
+	if !slices.Contains(LikeCategories, f.Category) {
+		f.Errors["category"] = "Choose a category."
+	}
+
+	if !slices.Contains(LikeValueTiers, f.ValueTier) {
+		f.Errors["value_tier"] = "Choose how valuable this is to your pet."
+	}
+
+	f.Valid = len(f.Errors) == 0
+	return sql.NullString{String: f.Notes, Valid: f.Notes != ""}, f.Valid
+}
+
+// Parses a requested ordering of like ids, favorite first.
+// The ids must name every one of the pet's likes exactly once.
+func parseLikeOrder(rawIDs []string, likes []database.Like) ([]int32, bool) {
+	if len(rawIDs) != len(likes) {
+		return nil, false
+	}
+
+	remaining := map[int32]bool{}
+	for _, like := range likes {
+		remaining[like.ID] = true
+	}
+
+	order := make([]int32, 0, len(rawIDs))
+	for _, rawID := range rawIDs {
+		id, err := strconv.ParseInt(rawID, 10, 32)
+		if err != nil || !remaining[int32(id)] {
+			return nil, false
+		}
+		delete(remaining, int32(id))
+		order = append(order, int32(id))
+	}
+
+	return order, true
+}
+
+func (a *APIConfig) renderLikes(w http.ResponseWriter, r *http.Request, status int, pet database.Pet, form LikeForm) {
+	likes, err := a.Db.ListLikesForPet(r.Context(), pet.ID)
+	if err != nil {
+		a.serverError(w, r, "error listing likes", err)
+		return
+	}
+
+	data := LikesPageData{
+		BasePageData: basePageData(r, fmt.Sprintf("%s - Likes", pet.Name)),
+		Pet:          pet,
+		Likes:        likes,
+		Categories:   LikeCategories,
+		ValueTiers:   LikeValueTiers,
+		LikeForm:     form,
+	}
+
+	a.render(w, r, status, "likes", data)
+}
+
+func (a *APIConfig) renderLike(w http.ResponseWriter, r *http.Request, status int, pet database.Pet, like database.Like, form LikeForm) {
+	data := LikePageData{
+		BasePageData: basePageData(r, fmt.Sprintf("%s - %s", pet.Name, like.Name)),
+		Pet:          pet,
+		Like:         like,
+		Categories:   LikeCategories,
+		ValueTiers:   LikeValueTiers,
+		LikeForm:     form,
+	}
+
+	a.render(w, r, status, "like", data)
+}
+
+// Resolves the {likeID} path value to a like belonging to the pet.
+// Writes a 404 (or 500) and returns false when it can't be found.
+func (a *APIConfig) likeFromPath(w http.ResponseWriter, r *http.Request, pet database.Pet) (database.Like, bool) {
+	likeID, err := pathID(r, "likeID")
+	if err != nil {
+		a.serveError(w, r, errNotFound)
+		return database.Like{}, false
+	}
+
+	like, err := a.Db.GetLike(r.Context(), database.GetLikeParams{
+		ID:    likeID,
+		PetID: pet.ID,
+	})
+	if err != nil {
+		if isNotFound(err) {
+			a.serveError(w, r, errNotFound)
+		} else {
+			a.serverError(w, r, "error loading like", err)
+		}
+		return database.Like{}, false
+	}
+
+	return like, true
+}
+
+func (a *APIConfig) HandleGetLikes(w http.ResponseWriter, r *http.Request, user_id int) {
+	pet, _, ok := a.linkedPetFromPath(w, r, user_id, PermissionsViewer)
+	if !ok {
+		return
+	}
+
+	a.renderLikes(w, r, http.StatusOK, pet, LikeForm{Category: "food", ValueTier: "medium", Valid: true})
+}
+
+func (a *APIConfig) HandlePostLike(w http.ResponseWriter, r *http.Request, user_id int) {
+	pet, _, ok := a.linkedPetFromPath(w, r, user_id, PermissionsCoOwner)
+	if !ok {
+		return
+	}
+
+	form := likeFormFromRequest(r)
+	notes, valid := form.validate()
+	if !valid {
+		a.renderLikes(w, r, http.StatusBadRequest, pet, form)
+		return
+	}
+
+	_, err := a.Db.CreateLike(r.Context(), database.CreateLikeParams{
+		PetID:     pet.ID,
+		Name:      form.Name,
+		Category:  form.Category,
+		ValueTier: form.ValueTier,
+		Notes:     notes,
+	})
+	if err != nil {
+		a.serverError(w, r, "error creating like", err)
+		return
+	}
+
+	http.Redirect(w, r, fmt.Sprintf("/dashboard/pet/%d/likes", pet.ID), http.StatusFound)
+}
+
+func (a *APIConfig) HandleGetLike(w http.ResponseWriter, r *http.Request, user_id int) {
+	pet, _, ok := a.linkedPetFromPath(w, r, user_id, PermissionsViewer)
+	if !ok {
+		return
+	}
+
+	like, ok := a.likeFromPath(w, r, pet)
+	if !ok {
+		return
+	}
+
+	a.renderLike(w, r, http.StatusOK, pet, like, likeFormFromLike(like))
+}
+
+func (a *APIConfig) HandlePostEditLike(w http.ResponseWriter, r *http.Request, user_id int) {
+	pet, _, ok := a.linkedPetFromPath(w, r, user_id, PermissionsCoOwner)
+	if !ok {
+		return
+	}
+
+	like, ok := a.likeFromPath(w, r, pet)
+	if !ok {
+		return
+	}
+
+	form := likeFormFromRequest(r)
+	notes, valid := form.validate()
+	if !valid {
+		a.renderLike(w, r, http.StatusBadRequest, pet, like, form)
+		return
+	}
+
+	_, err := a.Db.UpdateLike(r.Context(), database.UpdateLikeParams{
+		ID:        like.ID,
+		PetID:     pet.ID,
+		Name:      form.Name,
+		Category:  form.Category,
+		ValueTier: form.ValueTier,
+		Notes:     notes,
+	})
+	if err != nil {
+		a.serverError(w, r, "error updating like", err)
+		return
+	}
+
+	http.Redirect(w, r, fmt.Sprintf("/dashboard/pet/%d/likes", pet.ID), http.StatusFound)
+}
+
+func (a *APIConfig) HandlePostDeleteLike(w http.ResponseWriter, r *http.Request, user_id int) {
+	pet, _, ok := a.linkedPetFromPath(w, r, user_id, PermissionsCoOwner)
+	if !ok {
+		return
+	}
+
+	like, ok := a.likeFromPath(w, r, pet)
+	if !ok {
+		return
+	}
+
+	err := a.Db.DeleteLike(r.Context(), database.DeleteLikeParams{
+		ID:    like.ID,
+		PetID: pet.ID,
+	})
+	if err != nil {
+		a.serverError(w, r, "error deleting like", err)
+		return
+	}
+
+	http.Redirect(w, r, fmt.Sprintf("/dashboard/pet/%d/likes", pet.ID), http.StatusFound)
+}
+
+// Re-ranks a pet's likes from an ordered list of like_ids, favorite first.
+// Called by the drag-to-reorder list, so it answers with a bare status code.
+func (a *APIConfig) HandlePostLikesOrder(w http.ResponseWriter, r *http.Request, user_id int) {
+	ctx := r.Context()
+	pet, _, ok := a.linkedPetFromPath(w, r, user_id, PermissionsCoOwner)
+	if !ok {
+		return
+	}
+
+	likes, err := a.Db.ListLikesForPet(ctx, pet.ID)
+	if err != nil {
+		a.serverError(w, r, "error listing likes", err)
+		return
+	}
+
+	err = r.ParseForm()
+	if err != nil {
+		a.serveError(w, r, &AppError{Status: http.StatusBadRequest, Err: err})
+		return
+	}
+
+	order, valid := parseLikeOrder(r.Form["like_ids"], likes)
+	if !valid {
+		a.serveError(w, r, badRequest("The new order must list each of the pet's likes once."))
+		return
+	}
+
+	for i, likeID := range order {
+		err := a.Db.UpdateLikeRank(ctx, database.UpdateLikeRankParams{
+			ID:    likeID,
+			PetID: pet.ID,
+			Rank:  int32(i + 1),
+		})
+		if err != nil {
+			a.serverError(w, r, "error ranking like", err)
+			return
+		}
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Toggles whether likes show on the pet's public profile.
+func (a *APIConfig) HandlePostLikesVisibility(w http.ResponseWriter, r *http.Request, user_id int) {
+	pet, _, ok := a.linkedPetFromPath(w, r, user_id, PermissionsCoOwner)
+	if !ok {
+		return
+	}
+
+	err := a.Db.UpdatePetLikesHidden(r.Context(), database.UpdatePetLikesHiddenParams{
+		ID:          pet.ID,
+		Likeshidden: r.FormValue("likes_hidden") == "on",
+	})
+	if err != nil {
+		a.serverError(w, r, "error updating likes visibility", err)
+		return
+	}
+
+	http.Redirect(w, r, fmt.Sprintf("/dashboard/pet/%d/likes", pet.ID), http.StatusFound)
+}
@@ -0,0 +1,132 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/ctiller15/tailscribe/internal/database"
+	"github.com/stretchr/testify/assert"
+)
+
+func postLike(authCookie *http.Cookie, petID int, formData url.Values) *http.Response {
+	request, _ := http.NewRequest(http.MethodPost, fmt.Sprintf("/dashboard/pet/%d/likes", petID), strings.NewReader(formData.Encode()))
+	request.Header.Add("Content-Type", "application/x-www-form-urlencoded")
+	request.SetPathValue("id", strconv.Itoa(petID))
+	request.AddCookie(authCookie)
+	response := httptest.NewRecorder()
+
+	apiCfg := createConfig()
+	apiCfg.CheckAuthMiddleware(apiCfg.HandlePostLike)(response, request)
+
+	return response.Result()
+}
+
+func TestLikeFormValidate(t *testing.T) {
+	t.Run("Accepts a known category and tier", func(t *testing.T) {
+		form := LikeForm{Name: "tug", Category: "play", ValueTier: "high"}
+		_, valid := form.validate()
+		assert.True(t, valid)
+	})
+
+	t.Run("Rejects an unknown value tier", func(t *testing.T) {
+		form := LikeForm{Name: "tug", Category: "play", ValueTier: "priceless"}
+		_, valid := form.validate()
+		assert.False(t, valid)
+		assert.Contains(t, form.Errors, "value_tier")
+	})
+}
+
+func TestParseLikeOrder(t *testing.T) {
+	likes := []database.Like{{ID: 1}, {ID: 2}, {ID: 3}}
+
+	var likeOrderTests = []struct {
+		name  string
+		ids   []string
+		order []int32
+		valid bool
+	}{
+		{"every like once", []string{"3", "1", "2"}, []int32{3, 1, 2}, true},
+		{"missing a like", []string{"3", "1"}, nil, false},
+		{"repeated like", []string{"3", "3", "1"}, nil, false},
+		{"another pet's like", []string{"3", "1", "4"}, nil, false},
+		{"not a number", []string{"3", "1", "two"}, nil, false},
+	}
+
+	for _, tt := range likeOrderTests {
+		t.Run(tt.name, func(t *testing.T) {
+			order, valid := parseLikeOrder(tt.ids, likes)
+			assert.Equal(t, tt.valid, valid)
+			assert.Equal(t, tt.order, order)
+		})
+	}
+}
+
+func TestHandlePostLikesOrder(t *testing.T) {
+	t.Run("Re-ranks the pet's likes", func(t *testing.T) {
+		cookies := signUserUp(randTestEmail(), testPassword)
+		petID := createPetForUser(t, cookies[0], "Biscuit")
+
+		for _, name := range []string{"cheese", "squeaky ball", "sniffing"} {
+			result := postLike(cookies[0], petID, url.Values{
+				"name":       {name},
+				"category":   {"food"},
+				"value_tier": {"medium"},
+			})
+			assert.Equal(t, 302, result.StatusCode)
+		}
+
+		apiCfg := createConfig()
+		likes, err := apiCfg.Db.ListLikesForPet(t.Context(), int32(petID))
+		assert.NoError(t, err)
+		assert.Len(t, likes, 3)
+
+		formData := url.Values{"like_ids": {
+			strconv.Itoa(int(likes[2].ID)),
+			strconv.Itoa(int(likes[0].ID)),
+			strconv.Itoa(int(likes[1].ID)),
+		}}
+		request, _ := http.NewRequest(http.MethodPost, fmt.Sprintf("/dashboard/pet/%d/likes/order", petID), strings.NewReader(formData.Encode()))
+		request.Header.Add("Content-Type", "application/x-www-form-urlencoded")
+		request.SetPathValue("id", strconv.Itoa(petID))
+		request.AddCookie(cookies[0])
+		response := httptest.NewRecorder()
+		apiCfg.CheckAuthMiddleware(apiCfg.HandlePostLikesOrder)(response, request)
+
+		assert.Equal(t, 204, response.Result().StatusCode)
+
+		reordered, err := apiCfg.Db.ListLikesForPet(t.Context(), int32(petID))
+		assert.NoError(t, err)
+		assert.Equal(t, "sniffing", reordered[0].Name)
+		assert.Equal(t, "cheese", reordered[1].Name)
+	})
+}
+
+func TestHandlePostTrainingSessionReinforcers(t *testing.T) {
+	t.Run("Records the reinforcers used in a session", func(t *testing.T) {
+		cookies := signUserUp(randTestEmail(), testPassword)
+		petID := createPetForUser(t, cookies[0], "Biscuit")
+
+		postLike(cookies[0], petID, url.Values{
+			"name":       {"chicken"},
+			"category":   {"food"},
+			"value_tier": {"high"},
+		})
+
+		apiCfg := createConfig()
+		likes, _ := apiCfg.Db.ListLikesForPet(t.Context(), int32(petID))
+
+		result := postTrainingSession(cookies[0], petID, url.Values{
+			"started_at": {"2025-05-01T07:45"},
+			"like_ids":   {strconv.Itoa(int(likes[0].ID))},
+		})
+		assert.Equal(t, 302, result.StatusCode)
+
+		response := getTrainingSessions(cookies[0], petID)
+		assert.Contains(t, response.Body.String(), "Reinforced with chicken")
+	})
+}
@@ -1,33 +1,154 @@
 package api
 
 import (
+	"context"
+	"errors"
+	"log/slog"
 	"net/http"
+	"time"
 
 	"github.com/ctiller15/tailscribe/internal/auth"
+	"github.com/ctiller15/tailscribe/internal/database"
+	"github.com/google/uuid"
 )
 
 type authorizedHandler func(w http.ResponseWriter, r *http.Request, userID int)
 
+type userIDContextKey struct{}
+
+// How long a rotated refresh token is still accepted. Pages that fire several
+// requests at once all present the same token, and only the first rotates it.
+const refreshTokenGrace = 5 * time.Second
+
+var (
+	errNoRefreshToken      = errors.New("no refresh token")
+	errRefreshTokenInvalid = errors.New("refresh token unknown or expired")
+	errRefreshTokenReused  = errors.New("refresh token reused")
+	errSessionRevoked      = errors.New("session revoked")
+	errNotAdmin            = errors.New("not an admin")
+)
+
+// The user an authenticated request is from, for logging.
+func currentUserID(r *http.Request) (int, bool) {
+	userID, ok := r.Context().Value(userIDContextKey{}).(int)
+	return userID, ok
+}
+
+// Marks the request as coming from the user's session.
+func withSession(r *http.Request, sessionID uuid.UUID, userID int) *http.Request {
+	return withSessionID(r.WithContext(context.WithValue(r.Context(), userIDContextKey{}, userID)), sessionID)
+}
+
 func (a *APIConfig) CheckAuthMiddleware(handler authorizedHandler) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		// check user's cookie
 		jwtCookie := r.CookiesNamed("token")
 
-		if len(jwtCookie) == 0 {
-			a.Logger.Error("invalid request, user unauthorized, no token.")
-			// TODO: attempt to refresh before redirecting and potentially create a new token.
-			http.Redirect(w, r, "/login", http.StatusUnauthorized)
-			return
+		if len(jwtCookie) > 0 {
+			user_id, sessionID, err := auth.ValidateSessionJWT(jwtCookie[0].Value, a.Env.Secret)
+			if err == nil {
+				// A signed out session or deleted account is rejected at once, not when its access token runs out.
+				session, ok := a.activeSession(r.Context(), sessionID, user_id)
+				if !ok {
+					a.serveError(w, r, unauthorized(errSessionRevoked))
+					return
+				}
+
+				a.touchSession(r, session)
+				handler(w, withSession(r, session.ID, user_id), user_id)
+				return
+			}
 		}
 
-		tokenString := jwtCookie[0].Value
-		user_id, err := auth.ValidateJWT(tokenString, a.Env.Secret)
+		// The access token is missing or expired, so try to renew it before sending the user to log in.
+		session, err := a.refreshSession(w, r)
 		if err != nil {
-			a.Logger.Error("invalid request, user token invalid")
-			http.Redirect(w, r, "/login", http.StatusUnauthorized)
+			a.serveError(w, r, unauthorized(err))
 			return
 		}
 
-		handler(w, r, user_id)
+		handler(w, withSession(r, session.ID, int(session.UserID)), int(session.UserID))
+	}
+}
+
+// Exchanges the refresh_token cookie for a new access token and refresh token.
+// Each refresh token works once. Presenting one that was already used means it
+// has been copied, so the whole session is signed out, unless it was rotated
+// within refreshTokenGrace.
+func (a *APIConfig) refreshSession(w http.ResponseWriter, r *http.Request) (database.Session, error) {
+	ctx := r.Context()
+	refreshCookie, err := r.Cookie("refresh_token")
+	if err != nil {
+		return database.Session{}, errNoRefreshToken
+	}
+
+	stored, err := a.Db.GetRefreshTokenByHash(ctx, auth.HashToken(refreshCookie.Value))
+	if err != nil {
+		if isNotFound(err) {
+			return database.Session{}, errRefreshTokenInvalid
+		}
+		return database.Session{}, err
+	}
+
+	if time.Now().After(stored.ExpiresAt) {
+		return database.Session{}, errRefreshTokenInvalid
+	}
+
+	session, ok := a.activeSession(ctx, stored.FamilyID, int(stored.UserID))
+	if !ok {
+		return database.Session{}, errRefreshTokenInvalid
 	}
+
+	if stored.RevokedAt.Valid {
+		if time.Since(stored.RevokedAt.Time) < refreshTokenGrace {
+			// Another request rotated it moments ago, and its response carries the new cookies.
+			a.touchSession(r, session)
+			return session, nil
+		}
+
+		a.Logger.Warn("refresh token reused, revoking its session", slog.Int("user_id", int(stored.UserID)))
+		_, err = a.revokeSession(ctx, stored.UserID, stored.FamilyID)
+		if err != nil {
+			return database.Session{}, err
+		}
+		return database.Session{}, errRefreshTokenReused
+	}
+
+	revoked, err := a.Db.RevokeRefreshToken(ctx, stored.ID)
+	if err != nil {
+		return database.Session{}, err
+	}
+
+	// A concurrent request rotated it between the lookup and now.
+	if revoked == 0 {
+		a.touchSession(r, session)
+		return session, nil
+	}
+
+	err = a.attachSessionCookies(ctx, &w, stored.UserID, stored.FamilyID)
+	if err != nil {
+		return database.Session{}, err
+	}
+
+	a.touchSession(r, session)
+
+	return session, nil
+}
+
+// Like CheckAuthMiddleware, but only lets admins through. Everyone else gets a 404.
+func (a *APIConfig) CheckAdminMiddleware(handler authorizedHandler) http.HandlerFunc {
+	return a.CheckAuthMiddleware(func(w http.ResponseWriter, r *http.Request, userID int) {
+		user, err := a.Db.GetUser(r.Context(), int32(userID))
+		if err != nil {
+			a.serverError(w, r, "error loading user", err)
+			return
+		}
+
+		if !user.IsAdmin {
+			a.serveError(w, r, &AppError{Status: http.StatusNotFound, Err: errNotAdmin})
+			return
+		}
+
+		handler(w, r, userID)
+	})
 }
@@ -0,0 +1,151 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/ctiller15/tailscribe/internal/auth"
+	"github.com/stretchr/testify/assert"
+)
+
+func findCookie(cookies []*http.Cookie, name string) *http.Cookie {
+	for _, cookie := range cookies {
+		if cookie.Name == name {
+			return cookie
+		}
+	}
+	return nil
+}
+
+// Calls a protected handler with the given cookies, returning the response
+// and the user id the handler saw (or -1 if it wasn't called).
+func callProtected(cookies ...*http.Cookie) (*httptest.ResponseRecorder, int) {
+	request, _ := http.NewRequest(http.MethodGet, "/dashboard", nil)
+	for _, cookie := range cookies {
+		request.AddCookie(cookie)
+	}
+	response := httptest.NewRecorder()
+
+	seenUserID := -1
+	apiCfg := createConfig()
+	apiCfg.CheckAuthMiddleware(func(w http.ResponseWriter, r *http.Request, userID int) {
+		seenUserID = userID
+	})(response, request)
+
+	return response, seenUserID
+}
+
+// Moves the token's rotation into the past, beyond refreshTokenGrace.
+func backdateRefreshToken(t *testing.T, cookie *http.Cookie, by time.Duration) {
+	_, err := TestDB.Exec(
+		"UPDATE refresh_tokens SET revoked_at = revoked_at - make_interval(secs => $2) WHERE token_hash = $1",
+		auth.HashToken(cookie.Value), by.Seconds(),
+	)
+	assert.NoError(t, err)
+}
+
+func TestCheckAuthMiddleware(t *testing.T) {
+	t.Run("Passes a valid access token through", func(t *testing.T) {
+		cookies := signUserUp(randTestEmail(), testPassword)
+
+		response, userID := callProtected(findCookie(cookies, "token"))
+
+		assert.Equal(t, 200, response.Result().StatusCode)
+		assert.NotEqual(t, -1, userID)
+	})
+
+	t.Run("Rejects a request with no tokens", func(t *testing.T) {
+		response, userID := callProtected()
+
+		assert.Equal(t, 401, response.Result().StatusCode)
+		assert.Equal(t, -1, userID)
+		assert.Contains(t, response.Body.String(), `href="/login"`)
+	})
+
+	t.Run("Renews the session from the refresh token", func(t *testing.T) {
+		cookies := signUserUp(randTestEmail(), testPassword)
+		_, expectedUserID := callProtected(findCookie(cookies, "token"))
+
+		response, userID := callProtected(findCookie(cookies, "refresh_token"))
+
+		assert.Equal(t, 200, response.Result().StatusCode)
+		assert.Equal(t, expectedUserID, userID)
+
+		renewed := response.Result().Cookies()
+		assert.NotNil(t, findCookie(renewed, "token"))
+		rotated := findCookie(renewed, "refresh_token")
+		assert.NotNil(t, rotated)
+		assert.NotEqual(t, findCookie(cookies, "refresh_token").Value, rotated.Value)
+	})
+
+	t.Run("Ignores an invalid access token when the refresh token is good", func(t *testing.T) {
+		cookies := signUserUp(randTestEmail(), testPassword)
+
+		response, userID := callProtected(
+			&http.Cookie{Name: "token", Value: "not-a-jwt"},
+			findCookie(cookies, "refresh_token"),
+		)
+
+		assert.Equal(t, 200, response.Result().StatusCode)
+		assert.NotEqual(t, -1, userID)
+	})
+
+	t.Run("Rejects an unknown refresh token", func(t *testing.T) {
+		response, userID := callProtected(&http.Cookie{Name: "refresh_token", Value: "deadbeef"})
+
+		assert.Equal(t, 401, response.Result().StatusCode)
+		assert.Equal(t, -1, userID)
+	})
+
+	t.Run("Accepts a refresh token rotated moments ago by a parallel request", func(t *testing.T) {
+		cookies := signUserUp(randTestEmail(), testPassword)
+		original := findCookie(cookies, "refresh_token")
+
+		response, _ := callProtected(original)
+		rotated := findCookie(response.Result().Cookies(), "refresh_token")
+
+		response, userID := callProtected(original)
+		assert.Equal(t, 200, response.Result().StatusCode)
+		assert.NotEqual(t, -1, userID)
+
+		// The session survives, so the rotated token still works.
+		response, _ = callProtected(rotated)
+		assert.Equal(t, 200, response.Result().StatusCode)
+	})
+
+	t.Run("Revokes the whole family when a refresh token is reused", func(t *testing.T) {
+		cookies := signUserUp(randTestEmail(), testPassword)
+		original := findCookie(cookies, "refresh_token")
+
+		response, _ := callProtected(original)
+		rotated := findCookie(response.Result().Cookies(), "refresh_token")
+		backdateRefreshToken(t, original, time.Minute)
+
+		response, userID := callProtected(original)
+		assert.Equal(t, 401, response.Result().StatusCode)
+		assert.Equal(t, -1, userID)
+
+		// The token issued before the reuse was spotted is revoked too.
+		response, userID = callProtected(rotated)
+		assert.Equal(t, 401, response.Result().StatusCode)
+		assert.Equal(t, -1, userID)
+	})
+}
+
+func TestHandlePostLogoutRevokesRefreshToken(t *testing.T) {
+	cookies := signUserUp(randTestEmail(), testPassword)
+
+	request, _ := http.NewRequest(http.MethodPost, "/logout", nil)
+	request.AddCookie(findCookie(cookies, "refresh_token"))
+	response := httptest.NewRecorder()
+	apiCfg := createConfig()
+	apiCfg.HandlePostLogout(response, request)
+
+	assert.Equal(t, http.StatusFound, response.Result().StatusCode)
+
+	response, userID := callProtected(findCookie(cookies, "refresh_token"))
+	assert.Equal(t, 401, response.Result().StatusCode)
+	assert.Equal(t, -1, userID)
+}
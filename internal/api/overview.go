@@ -0,0 +1,110 @@
+package api
+
+import (
+	"database/sql"
+	"net/http"
+
+	"github.com/ctiller15/tailscribe/internal/database"
+)
+
+// One pet on the dashboard overview.
+type PetSummary struct {
+	Pet              database.Pet
+	PermissionsLevel int32
+	LastSessionAt    sql.NullTime
+	OpenGoals        int64
+}
+
+type DashboardPageData struct {
+	BasePageData
+	Pets []PetSummary
+	// Pets the user has hidden from their dashboard.
+	HiddenPets []PetSummary
+	// Pets the user has archived, e.g. after they've passed away or been rehomed.
+	ArchivedPets []PetSummary
+}
+
+// Sorts the user's pets into the dashboard's sections. Archived pets go to
+// the archived section even when they are also hidden.
+func groupPetSummaries(pets []database.ListPetsForUserRow, summaries []PetSummary) DashboardPageData {
+	data := DashboardPageData{}
+	for i, pet := range pets {
+		switch {
+		case !pet.Active:
+			data.ArchivedPets = append(data.ArchivedPets, summaries[i])
+		case pet.Hidden:
+			data.HiddenPets = append(data.HiddenPets, summaries[i])
+		default:
+			data.Pets = append(data.Pets, summaries[i])
+		}
+	}
+
+	return data
+}
+
+func summarizePet(pet database.ListPetsForUserRow) PetSummary {
+	return PetSummary{
+		Pet:              pet.Pet,
+		PermissionsLevel: pet.PermissionsLevel,
+		LastSessionAt:    pet.LastSessionAt,
+		OpenGoals:        pet.OpenGoals,
+	}
+}
+
+func (a *APIConfig) HandleGetDashboard(w http.ResponseWriter, r *http.Request, user_id int) {
+	pets, err := a.Db.ListPetsForUser(r.Context(), int32(user_id))
+	if err != nil {
+		a.serverError(w, r, "error listing pets", err)
+		return
+	}
+
+	summaries := make([]PetSummary, 0, len(pets))
+	for _, pet := range pets {
+		summaries = append(summaries, summarizePet(pet))
+	}
+
+	data := groupPetSummaries(pets, summaries)
+	data.BasePageData = basePageData(r, "TailScribe - My Pets")
+
+	a.render(w, r, http.StatusOK, "pets_overview", data)
+}
+
+// Hides or shows a pet on the user's own dashboard. Other people linked to the pet are unaffected.
+func (a *APIConfig) HandlePostPetHidden(w http.ResponseWriter, r *http.Request, user_id int) {
+	pet, _, ok := a.linkedPetFromPath(w, r, user_id, PermissionsViewer)
+	if !ok {
+		return
+	}
+
+	err := a.Db.UpdateUserPetHidden(r.Context(), database.UpdateUserPetHiddenParams{
+		Userid: int32(user_id),
+		Petid:  pet.ID,
+		Hidden: r.FormValue("hidden") == "on",
+	})
+	if err != nil {
+		a.serverError(w, r, "error updating pet hidden", err)
+		return
+	}
+
+	http.Redirect(w, r, "/dashboard", http.StatusFound)
+}
+
+// Archives or restores a pet on the user's own dashboard.
+func (a *APIConfig) HandlePostPetArchived(w http.ResponseWriter, r *http.Request, user_id int) {
+	pet, _, ok := a.linkedPetFromPath(w, r, user_id, PermissionsViewer)
+	if !ok {
+		return
+	}
+
+	err := a.Db.UpdateUserPetActive(r.Context(), database.UpdateUserPetActiveParams{
+		Userid: int32(user_id),
+		Petid:  pet.ID,
+		Active: r.FormValue("archived") != "on",
+	})
+	if err != nil {
+		a.serverError(w, r, "error updating pet archived", err)
+		return
+	}
+
+	http.Redirect(w, r, "/dashboard", http.StatusFound)
+}
@@ -0,0 +1,140 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/ctiller15/tailscribe/internal/database"
+	"github.com/stretchr/testify/assert"
+)
+
+func getDashboard(authCookie *http.Cookie) *httptest.ResponseRecorder {
+	request, _ := http.NewRequest(http.MethodGet, "/dashboard", nil)
+	request.AddCookie(authCookie)
+	response := httptest.NewRecorder()
+
+	apiCfg := createConfig()
+	apiCfg.CheckAuthMiddleware(apiCfg.HandleGetDashboard)(response, request)
+
+	return response
+}
+
+func postPetToggle(authCookie *http.Cookie, petID int, toggle string, formData url.Values) *httptest.ResponseRecorder {
+	request, _ := http.NewRequest(http.MethodPost, "/dashboard/pet/"+strconv.Itoa(petID)+"/"+toggle, strings.NewReader(formData.Encode()))
+	request.Header.Add("Content-Type", "application/x-www-form-urlencoded")
+	request.SetPathValue("id", strconv.Itoa(petID))
+	request.AddCookie(authCookie)
+	response := httptest.NewRecorder()
+
+	apiCfg := createConfig()
+	handler := apiCfg.HandlePostPetHidden
+	if toggle == "archived" {
+		handler = apiCfg.HandlePostPetArchived
+	}
+	apiCfg.CheckAuthMiddleware(handler)(response, request)
+
+	return response
+}
+
+func TestGroupPetSummaries(t *testing.T) {
+	pets := []database.ListPetsForUserRow{
+		{Pet: database.Pet{ID: 1}, Active: true},
+		{Pet: database.Pet{ID: 2}, Active: true, Hidden: true},
+		{Pet: database.Pet{ID: 3}, Active: false},
+		{Pet: database.Pet{ID: 4}, Active: false, Hidden: true},
+	}
+	summaries := make([]PetSummary, len(pets))
+	for i, pet := range pets {
+		summaries[i] = PetSummary{Pet: pet.Pet}
+	}
+
+	data := groupPetSummaries(pets, summaries)
+
+	assert.Equal(t, []PetSummary{summaries[0]}, data.Pets)
+	assert.Equal(t, []PetSummary{summaries[1]}, data.HiddenPets)
+	assert.Equal(t, []PetSummary{summaries[2], summaries[3]}, data.ArchivedPets)
+}
+
+func TestListPetsForUserSummaries(t *testing.T) {
+	cookies := signUserUp(randTestEmail(), testPassword)
+	_, userID := callProtected(cookies[0])
+	busyID := createPetForUser(t, cookies[0], "Biscuit")
+	createPetForUser(t, cookies[0], "Pepper")
+
+	postTrainingSession(cookies[0], busyID, url.Values{"started_at": {"2025-04-12T08:00"}})
+	postTrainingSession(cookies[0], busyID, url.Values{"started_at": {"2025-04-19T08:00"}})
+	for _, description := range []string{"Loose-lead walking", "Reliable recall"} {
+		postGoalForm(cookies[0], "/dashboard/pet/"+strconv.Itoa(busyID)+"/goals", map[string]string{"id": strconv.Itoa(busyID)},
+			url.Values{"description": {description}}, createConfig().HandlePostGoal)
+	}
+
+	pets, err := DbQueries.ListPetsForUser(t.Context(), int32(userID))
+	assert.NoError(t, err)
+	assert.Len(t, pets, 2)
+
+	busy, idle := pets[0], pets[1]
+	assert.Equal(t, "2025-04-19", busy.LastSessionAt.Time.Format("2006-01-02"))
+	assert.Equal(t, int64(2), busy.OpenGoals)
+	assert.False(t, idle.LastSessionAt.Valid)
+	assert.Equal(t, int64(0), idle.OpenGoals)
+}
+
+func TestHandleGetDashboard(t *testing.T) {
+	t.Run("Fails to show the dashboard when unauthorized", func(t *testing.T) {
+		request, _ := http.NewRequest(http.MethodGet, "/dashboard", nil)
+		response := httptest.NewRecorder()
+
+		apiCfg := createConfig()
+		apiCfg.CheckAuthMiddleware(apiCfg.HandleGetDashboard)(response, request)
+
+		assert.Equal(t, 401, response.Result().StatusCode)
+	})
+
+	t.Run("Lists the user's pets with their last session", func(t *testing.T) {
+		cookies := signUserUp(randTestEmail(), testPassword)
+		petID := createPetForUser(t, cookies[0], "Biscuit")
+
+		postTrainingSession(cookies[0], petID, url.Values{
+			"started_at": {"2025-04-12T08:00"},
+		})
+
+		response := getDashboard(cookies[0])
+
+		assert.Equal(t, 200, response.Result().StatusCode)
+		body := response.Body.String()
+		assert.Contains(t, body, "Biscuit")
+		assert.Contains(t, body, "Apr 12, 2025")
+	})
+
+	t.Run("Collapses hidden and archived pets into their own sections", func(t *testing.T) {
+		cookies := signUserUp(randTestEmail(), testPassword)
+		hiddenID := createPetForUser(t, cookies[0], "Pepper")
+		archivedID := createPetForUser(t, cookies[0], "Clover")
+
+		response := postPetToggle(cookies[0], hiddenID, "hidden", url.Values{"hidden": {"on"}})
+		assert.Equal(t, http.StatusFound, response.Result().StatusCode)
+		response = postPetToggle(cookies[0], archivedID, "archived", url.Values{"archived": {"on"}})
+		assert.Equal(t, http.StatusFound, response.Result().StatusCode)
+
+		body := getDashboard(cookies[0]).Body.String()
+		hiddenSection := body[strings.Index(body, `class="hidden-pets"`):]
+		archivedSection := body[strings.Index(body, `class="archived-pets"`):]
+		assert.Contains(t, hiddenSection, "Pepper")
+		assert.Contains(t, archivedSection, "Clover")
+		assert.NotContains(t, archivedSection, "Pepper")
+	})
+
+	t.Run("Doesn't list pets the user isn't linked to", func(t *testing.T) {
+		ownerCookies := signUserUp(randTestEmail(), testPassword)
+		createPetForUser(t, ownerCookies[0], "Nutmeg")
+
+		strangerCookies := signUserUp(randTestEmail(), testPassword)
+		body := getDashboard(strangerCookies[0]).Body.String()
+
+		assert.NotContains(t, body, "Nutmeg")
+	})
+}
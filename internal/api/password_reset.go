@@ -0,0 +1,229 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/mail"
+	"strings"
+	"time"
+
+	"github.com/ctiller15/tailscribe/internal/auth"
+	"github.com/ctiller15/tailscribe/internal/database"
+	"github.com/ctiller15/tailscribe/internal/mailer"
+	"github.com/ctiller15/tailscribe/internal/ratelimit"
+)
+
+// How long a password reset link works for.
+const resetPasswordTTL = time.Hour
+
+type ForgotPasswordForm struct {
+	Email string
+	// Set once the form is accepted. The page says the same thing whether or
+	// not the email belongs to an account.
+	Sent  bool
+	Valid bool
+}
+
+type ForgotPasswordPageData struct {
+	BasePageData
+	ForgotPasswordForm
+}
+
+type ResetPasswordForm struct {
+	Password        string
+	ConfirmPassword string
+	Valid           bool
+	Errors          map[string]string
+}
+
+type ResetPasswordPageData struct {
+	BasePageData
+	Token string
+	// The link is unknown, already used, or expired.
+	Expired bool
+	Done    bool
+	ResetPasswordForm
+}
+
+func (f *ResetPasswordForm) validate(policy auth.PasswordPolicy) bool {
+	f.Errors = map[string]string{}
+
+	if f.Password == "" {
+		f.Errors["password"] = "Enter a new password."
+	} else if problem := passwordProblem(policy, f.Password); problem != "" {
+		f.Errors["password"] = problem
+	} else if f.Password != f.ConfirmPassword {
+		f.Errors["confirm_password"] = "The passwords don't match."
+	}
+
+	f.Valid = len(f.Errors) == 0
+	return f.Valid
+}
+
+func resetPasswordEmail(to, link string) mailer.Message {
+	return mailer.Message{
+		To:      to,
+		Subject: "Reset your TailScribe password",
+		Body: fmt.Sprintf("Someone asked to reset the password for your TailScribe account.\n\n"+
+			"Follow this link within the next hour to choose a new one:\n\n%s\n\n"+
+			"If it wasn't you, you can ignore this email.\n", link),
+	}
+}
+
+func (a *APIConfig) renderForgotPassword(w http.ResponseWriter, r *http.Request, status int, form ForgotPasswordForm) {
+	data := ForgotPasswordPageData{
+		BasePageData:       basePageData(r, "TailScribe - Forgot Password"),
+		ForgotPasswordForm: form,
+	}
+
+	a.render(w, r, status, "forgot_password", data)
+}
+
+func (a *APIConfig) renderResetPassword(w http.ResponseWriter, r *http.Request, status int, data ResetPasswordPageData) {
+	data.BasePageData = basePageData(r, "TailScribe - Reset Password")
+
+	a.render(w, r, status, "reset_password", data)
+}
+
+func (a *APIConfig) HandleGetForgotPassword(w http.ResponseWriter, r *http.Request) {
+	a.renderForgotPassword(w, r, http.StatusOK, ForgotPasswordForm{Valid: true})
+}
+
+// Answers with the same page whatever happens once the email is well formed,
+// so it can't be used to find out which addresses have accounts.
+func (a *APIConfig) HandlePostForgotPassword(w http.ResponseWriter, r *http.Request) {
+	form := ForgotPasswordForm{
+		Email: strings.TrimSpace(r.FormValue("email")),
+	}
+
+	_, err := mail.ParseAddress(form.Email)
+	if err != nil {
+		a.renderForgotPassword(w, r, http.StatusBadRequest, form)
+		return
+	}
+
+	err = a.sendPasswordReset(r.Context(), clientIP(r), form.Email)
+	if err != nil {
+		a.Logger.Error("error sending password reset", slog.String("error", err.Error()))
+	}
+
+	form.Valid = true
+	form.Sent = true
+	a.renderForgotPassword(w, r, http.StatusOK, form)
+}
+
+// Emails a reset link if email belongs to an account, unless the address or
+// the email has asked too often.
+func (a *APIConfig) sendPasswordReset(ctx context.Context, ip, email string) error {
+	wait, err := attemptAll(ctx, map[*ratelimit.Limiter]string{
+		a.RateLimits.PasswordResetByIP:    ip,
+		a.RateLimits.PasswordResetByEmail: normalizeEmailKey(email),
+	})
+	if err != nil {
+		return fmt.Errorf("checking rate limit: %w", err)
+	}
+	if wait > 0 {
+		a.Logger.Warn("password reset rate limited", slog.String("ip", ip))
+		return nil
+	}
+
+	user, err := a.Db.GetUserByEmail(ctx, email)
+	if isNotFound(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("loading user: %w", err)
+	}
+
+	token, err := auth.MakeResetPasswordToken()
+	if err != nil {
+		return fmt.Errorf("creating reset token: %w", err)
+	}
+
+	// Storing a new token replaces any earlier one, so only the latest link works.
+	err = a.Db.UpdateUserResetPasswordToken(ctx, database.UpdateUserResetPasswordTokenParams{
+		ID:                   user.ID,
+		ResetPasswordToken:   sql.NullString{String: auth.HashToken(token), Valid: true},
+		ResetPasswordExpires: sql.NullTime{Time: time.Now().Add(resetPasswordTTL), Valid: true},
+	})
+	if err != nil {
+		return fmt.Errorf("storing reset token: %w", err)
+	}
+
+	err = a.Mailer.Send(ctx, resetPasswordEmail(user.Email.String, a.siteURL("/reset-password/"+token)))
+	if err != nil {
+		return fmt.Errorf("sending reset email: %w", err)
+	}
+
+	return nil
+}
+
+func (a *APIConfig) HandleGetResetPassword(w http.ResponseWriter, r *http.Request) {
+	token := r.PathValue("token")
+
+	_, err := a.Db.GetUserByResetPasswordToken(r.Context(), sql.NullString{String: auth.HashToken(token), Valid: true})
+	if err != nil {
+		if !isNotFound(err) {
+			a.serverError(w, r, "error loading reset token", err)
+			return
+		}
+		a.renderResetPassword(w, r, http.StatusNotFound, ResetPasswordPageData{Expired: true})
+		return
+	}
+
+	a.renderResetPassword(w, r, http.StatusOK, ResetPasswordPageData{
+		Token:             token,
+		ResetPasswordForm: ResetPasswordForm{Valid: true},
+	})
+}
+
+func (a *APIConfig) HandlePostResetPassword(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	token := r.PathValue("token")
+	form := ResetPasswordForm{
+		Password:        r.FormValue("password"),
+		ConfirmPassword: r.FormValue("confirm_password"),
+	}
+
+	if !form.validate(a.Passwords) {
+		a.renderResetPassword(w, r, http.StatusBadRequest, ResetPasswordPageData{
+			Token:             token,
+			ResetPasswordForm: form,
+		})
+		return
+	}
+
+	hashedPassword, err := a.Passwords.HashPassword(form.Password)
+	if err != nil {
+		a.serverError(w, r, "error hashing password", err)
+		return
+	}
+
+	// Clears the token as it sets the password, so each link works once.
+	user, err := a.Db.ResetUserPassword(ctx, database.ResetUserPasswordParams{
+		ResetPasswordToken: sql.NullString{String: auth.HashToken(token), Valid: true},
+		Password:           sql.NullString{String: hashedPassword, Valid: true},
+	})
+	if err != nil {
+		if !isNotFound(err) {
+			a.serverError(w, r, "error resetting password", err)
+			return
+		}
+		a.renderResetPassword(w, r, http.StatusNotFound, ResetPasswordPageData{Expired: true})
+		return
+	}
+
+	// Sign out everywhere, in case the old password was how someone else got in.
+	err = revokeAllSessions(ctx, &a.Db, user.ID)
+	if err != nil {
+		a.serverError(w, r, "error revoking sessions", err)
+		return
+	}
+
+	a.clearSessionCookies(w)
+
+	a.renderResetPassword(w, r, http.StatusOK, ResetPasswordPageData{Done: true})
+}
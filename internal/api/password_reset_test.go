@@ -0,0 +1,171 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/ctiller15/tailscribe/internal/auth"
+	"github.com/ctiller15/tailscribe/internal/mailer"
+	"github.com/stretchr/testify/assert"
+)
+
+var resetLinkPattern = regexp.MustCompile(`/reset-password/([0-9a-f]+)`)
+
+// Requests a reset for email, sending mail into dir, and returns the token
+// from the emailed link ("" when no email was sent).
+func requestPasswordReset(t *testing.T, dir, email string) string {
+	formData := url.Values{"email": {email}}
+	request, _ := http.NewRequest(http.MethodPost, "/forgot-password", strings.NewReader(formData.Encode()))
+	request.Header.Add("Content-Type", "application/x-www-form-urlencoded")
+	// Links must come from BASE_URL, not a Host header an attacker picked.
+	request.Host = "evil.example"
+	response := httptest.NewRecorder()
+
+	apiCfg := createConfig()
+	apiCfg.Mailer = &mailer.FileMailer{Dir: dir}
+	apiCfg.HandlePostForgotPassword(response, request)
+	assert.Equal(t, 200, response.Result().StatusCode)
+
+	files, _ := filepath.Glob(filepath.Join(dir, "*.eml"))
+	if len(files) == 0 {
+		return ""
+	}
+
+	contents, err := os.ReadFile(files[len(files)-1])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	match := resetLinkPattern.FindStringSubmatch(string(contents))
+	if match == nil {
+		t.Fatalf("no reset link in %q", contents)
+	}
+	assert.Contains(t, string(contents), TestEnvVars.BaseURL+"/reset-password/")
+	assert.NotContains(t, string(contents), "evil.example")
+	return match[1]
+}
+
+func postResetPassword(token, password, confirmPassword string) *httptest.ResponseRecorder {
+	formData := url.Values{
+		"password":         {password},
+		"confirm_password": {confirmPassword},
+	}
+	request, _ := http.NewRequest(http.MethodPost, "/reset-password/"+token, strings.NewReader(formData.Encode()))
+	request.Header.Add("Content-Type", "application/x-www-form-urlencoded")
+	request.SetPathValue("token", token)
+	response := httptest.NewRecorder()
+
+	apiCfg := createConfig()
+	apiCfg.HandlePostResetPassword(response, request)
+
+	return response
+}
+
+func postLogin(email, password string) *httptest.ResponseRecorder {
+	formData := url.Values{
+		"email":    {email},
+		"password": {password},
+	}
+	request, _ := http.NewRequest(http.MethodPost, "/login", strings.NewReader(formData.Encode()))
+	request.Header.Add("Content-Type", "application/x-www-form-urlencoded")
+	response := httptest.NewRecorder()
+
+	apiCfg := createConfig()
+	apiCfg.HandlePostLogin(response, request)
+
+	return response
+}
+
+func TestResetPasswordFormValidate(t *testing.T) {
+	policy := auth.DefaultPasswordPolicy()
+
+	form := ResetPasswordForm{Password: "", ConfirmPassword: ""}
+	assert.False(t, form.validate(policy))
+	assert.Contains(t, form.Errors, "password")
+
+	form = ResetPasswordForm{Password: "hunter22", ConfirmPassword: "hunter23"}
+	assert.False(t, form.validate(policy))
+	assert.Contains(t, form.Errors, "confirm_password")
+
+	form = ResetPasswordForm{Password: "letmein", ConfirmPassword: "letmein"}
+	assert.False(t, form.validate(policy))
+	assert.Equal(t, "Use at least 8 characters.", form.Errors["password"])
+
+	form = ResetPasswordForm{Password: "password1", ConfirmPassword: "password1"}
+	assert.False(t, form.validate(policy))
+	assert.Contains(t, form.Errors, "password")
+
+	form = ResetPasswordForm{Password: "hunter22", ConfirmPassword: "hunter22"}
+	assert.True(t, form.validate(policy))
+}
+
+func TestPasswordReset(t *testing.T) {
+	t.Run("Doesn't send email for unknown addresses", func(t *testing.T) {
+		dir := t.TempDir()
+
+		token := requestPasswordReset(t, dir, randTestEmail())
+
+		assert.Equal(t, "", token)
+	})
+
+	t.Run("Rejects a malformed email", func(t *testing.T) {
+		formData := url.Values{"email": {"not an email"}}
+		request, _ := http.NewRequest(http.MethodPost, "/forgot-password", strings.NewReader(formData.Encode()))
+		request.Header.Add("Content-Type", "application/x-www-form-urlencoded")
+		response := httptest.NewRecorder()
+
+		apiCfg := createConfig()
+		apiCfg.HandlePostForgotPassword(response, request)
+
+		assert.Equal(t, 400, response.Result().StatusCode)
+	})
+
+	t.Run("Resets the password once and signs out other sessions", func(t *testing.T) {
+		email := randTestEmail()
+		cookies := signUserUp(email, testPassword)
+		token := requestPasswordReset(t, t.TempDir(), email)
+		assert.NotEqual(t, "", token)
+
+		response := postResetPassword(token, "newpassword456", "newpassword456")
+		assert.Equal(t, 200, response.Result().StatusCode)
+
+		assert.Equal(t, 401, postLogin(email, testPassword).Result().StatusCode)
+		assert.Equal(t, 302, postLogin(email, "newpassword456").Result().StatusCode)
+
+		response, userID := callProtected(findCookie(cookies, "refresh_token"))
+		assert.Equal(t, 401, response.Result().StatusCode)
+		assert.Equal(t, -1, userID)
+
+		// The link only works once.
+		response = postResetPassword(token, "anotherpassword", "anotherpassword")
+		assert.Equal(t, 404, response.Result().StatusCode)
+	})
+
+	t.Run("Only the latest link works", func(t *testing.T) {
+		email := randTestEmail()
+		signUserUp(email, testPassword)
+		dir := t.TempDir()
+		first := requestPasswordReset(t, dir, email)
+		second := requestPasswordReset(t, dir, email)
+
+		assert.Equal(t, 404, postResetPassword(first, "newpassword456", "newpassword456").Result().StatusCode)
+		assert.Equal(t, 200, postResetPassword(second, "newpassword456", "newpassword456").Result().StatusCode)
+	})
+
+	t.Run("Rejects mismatched passwords", func(t *testing.T) {
+		email := randTestEmail()
+		signUserUp(email, testPassword)
+		token := requestPasswordReset(t, t.TempDir(), email)
+
+		response := postResetPassword(token, "newpassword456", "newpassword789")
+
+		assert.Equal(t, 400, response.Result().StatusCode)
+		assert.Equal(t, 302, postLogin(email, testPassword).Result().StatusCode)
+	})
+}
@@ -0,0 +1,48 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+
+	"github.com/ctiller15/tailscribe/internal/auth"
+	"github.com/ctiller15/tailscribe/internal/database"
+)
+
+// Explains why the policy won't take a new password, or "" if it will.
+func passwordProblem(policy auth.PasswordPolicy, password string) string {
+	err := policy.ValidatePassword(password)
+	switch {
+	case err == nil:
+		return ""
+	case errors.Is(err, auth.ErrPasswordTooShort):
+		return fmt.Sprintf("Use at least %d characters.", policy.MinLength)
+	case errors.Is(err, auth.ErrPasswordTooLong):
+		return "That password is too long."
+	case errors.Is(err, auth.ErrPasswordTooCommon):
+		return "That password is too common. Choose one that's harder to guess."
+	default:
+		return "Choose a different password."
+	}
+}
+
+// Replaces a hash made under old settings, now that the password is known.
+// Logins still work if it fails, so errors are only logged.
+func (a *APIConfig) rehashPassword(ctx context.Context, user database.User, password string) {
+	hashedPassword, err := a.Passwords.HashPassword(password)
+	if err != nil {
+		a.Logger.Error("error rehashing password", slog.String("error", err.Error()))
+		return
+	}
+
+	err = a.Db.RehashUserPassword(ctx, database.RehashUserPasswordParams{
+		Password:    sql.NullString{String: hashedPassword, Valid: true},
+		ID:          user.ID,
+		OldPassword: user.Password,
+	})
+	if err != nil {
+		a.Logger.Error("error storing rehashed password", slog.String("error", err.Error()))
+	}
+}
@@ -0,0 +1,39 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/ctiller15/tailscribe/internal/auth"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPasswordPolicyOnSignup(t *testing.T) {
+	response := postSignupWith(createConfig(), "203.0.113.50:1000", randTestEmail(), "Password1")
+
+	assert.Equal(t, http.StatusBadRequest, response.Result().StatusCode)
+	assert.Contains(t, response.Body.String(), "too common")
+	assert.Nil(t, findCookie(response.Result().Cookies(), "token"))
+}
+
+func TestLoginRehashesPassword(t *testing.T) {
+	email := randTestEmail()
+	signUserUp(email, testPassword)
+
+	apiCfg := createConfig()
+	apiCfg.Passwords.Algorithm = auth.PasswordArgon2id
+	apiCfg.Passwords.Argon2 = auth.Argon2Params{Time: 1, Memory: 64, Threads: 1}
+
+	response := postLoginWith(apiCfg, "203.0.113.51:1000", email, testPassword)
+	assert.Equal(t, http.StatusFound, response.Result().StatusCode)
+
+	user, err := apiCfg.Db.GetUserByEmail(context.Background(), email)
+	assert.NoError(t, err)
+	assert.True(t, strings.HasPrefix(user.Password.String, "$argon2id$"))
+
+	// The new hash works, and the old settings still read it.
+	response = postLoginWith(createConfig(), "203.0.113.51:1000", email, testPassword)
+	assert.Equal(t, http.StatusFound, response.Result().StatusCode)
+}
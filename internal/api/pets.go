@@ -0,0 +1,453 @@
+package api
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/ctiller15/tailscribe/internal/database"
+)
+
+// Permission levels stored in UserPets.permissions_level. Each level can do
+// everything the levels below it can.
+const (
+	// Can see the pet and everything recorded about it.
+	PermissionsViewer int32 = 1
+	// Can also log training sessions.
+	PermissionsLogger int32 = 2
+	// Can also edit the pet's profile, skills, goals, titles and likes.
+	PermissionsCoOwner int32 = 3
+	// Can also manage who has access to the pet.
+	PermissionsOwner int32 = 4
+)
+
+// How many sessions the pet dashboard shows.
+const recentSessionsLimit = 5
+
+// Matches the VARCHAR size of pet.about_text.
+const maxAboutTextLength = 1000
+
+// Longest slug generated from a pet's name, before any numbering.
+const maxSlugLength = 40
+
+// How many numbered slugs to try before falling back to a random suffix.
+const maxSlugAttempts = 20
+
+// Values accepted for pet.sex.
+var PetSexes = []string{"female", "male", "unknown"}
+
+type PetDashboardPageData struct {
+	BasePageData
+	Pet            database.Pet
+	DisplayName    string
+	Age            string
+	RecentSessions []TrainingSessionView
+	Skills         []database.Skill
+	Likes          []database.Like
+	OverdueGoals   []database.Goal
+	// Goals achieved within recentlyAchievedGoalsWindow.
+	RecentlyAchievedGoals []database.Goal
+	Titles                []database.Title
+	// The viewer's UserPets.permissions_level, to hide what they can't change.
+	PermissionsLevel int32
+}
+
+type EditPetForm struct {
+	Name             string
+	DateOfBirth      string
+	DateOfBirthExact bool
+	Image            string
+	AboutText        string
+	Species          string
+	Breed            string
+	Sex              string
+	Valid            bool
+	Errors           map[string]string
+}
+
+type EditPetPageData struct {
+	BasePageData
+	Pet   database.Pet
+	Sexes []string
+	EditPetForm
+}
+
+func editPetFormFromRequest(r *http.Request) EditPetForm {
+	return EditPetForm{
+		Name:             strings.TrimSpace(r.FormValue("name")),
+		DateOfBirth:      strings.TrimSpace(r.FormValue("date_of_birth")),
+		DateOfBirthExact: r.FormValue("date_of_birth_exact") == "on",
+		Image:            strings.TrimSpace(r.FormValue("image")),
+		AboutText:        strings.TrimSpace(r.FormValue("about_text")),
+		Species:          strings.TrimSpace(r.FormValue("species")),
+		Breed:            strings.TrimSpace(r.FormValue("breed")),
+		Sex:              r.FormValue("sex"),
+	}
+}
+
+func editPetFormFromPet(pet database.Pet) EditPetForm {
+	form := EditPetForm{
+		Name:             pet.Name,
+		DateOfBirthExact: pet.Dateofbirthexact.Bool,
+		Image:            pet.Imageurl.String,
+		AboutText:        pet.AboutText.String,
+		Species:          pet.Species.String,
+		Breed:            pet.Breed.String,
+		Sex:              pet.Sex.String,
+		Valid:            true,
+	}
+	if pet.Dateofbirth.Valid {
+		form.DateOfBirth = pet.Dateofbirth.Time.Format(dateInputLayout)
+	}
+
+	return form
+}
+
+func nullString(value string) sql.NullString {
+	return sql.NullString{String: value, Valid: value != ""}
+}
+
+// Validates the form into UpdatePet params for the given pet.
+func (f *EditPetForm) validate(petID int32, now time.Time) (database.UpdatePetParams, bool) {
+	f.Errors = map[string]string{}
+	params := database.UpdatePetParams{
+		ID:        petID,
+		Name:      f.Name,
+		Imageurl:  nullString(f.Image),
+		AboutText: nullString(f.AboutText),
+		Species:   nullString(f.Species),
+		Breed:     nullString(f.Breed),
+		Sex:       nullString(f.Sex),
+	}
+
+	if f.Name == "" {
+		f.Errors["name"] = "Enter your pet's name."
+	}
+
+	if f.DateOfBirth != "" {
+		dateOfBirth, err := time.Parse(dateInputLayout, f.DateOfBirth)
+		if err != nil {
+			f.Errors["date_of_birth"] = "Enter a valid date of birth."
+		} else if dateOfBirth.After(now) {
+			f.Errors["date_of_birth"] = "Date of birth can't be in the future."
+		}
+		params.Dateofbirth = sql.NullTime{Time: dateOfBirth, Valid: err == nil}
+		params.Dateofbirthexact = sql.NullBool{Bool: f.DateOfBirthExact, Valid: err == nil}
+	}
+
+	if f.Image != "" {
+		imageUrl, err := url.Parse(f.Image)
+		if err != nil || (imageUrl.Scheme != "https" && imageUrl.Scheme != "http") {
+			f.Errors["image"] = "Images must be a web address."
+		}
+	}
+
+	if utf8.RuneCountInString(f.AboutText) > maxAboutTextLength {
+		f.Errors["about_text"] = fmt.Sprintf("Keep the description under %d characters.", maxAboutTextLength)
+	}
+
+	if f.Sex != "" && !slices.Contains(PetSexes, f.Sex) {
+		f.Errors["sex"] = "Choose female, male or unknown."
+	}
+
+	f.Valid = len(f.Errors) == 0
+	return params, f.Valid
+}
+
+// Describes how old a pet is, e.g. "3 years" or "about 5 months" when the
+// date of birth is only an estimate. Empty when the date of birth is unknown.
+func PetAge(pet database.Pet, now time.Time) string {
+	if !pet.Dateofbirth.Valid {
+		return ""
+	}
+
+	born := pet.Dateofbirth.Time
+	months := (now.Year()-born.Year())*12 + int(now.Month()-born.Month())
+	if now.Day() < born.Day() {
+		months--
+	}
+
+	var age string
+	switch {
+	case months < 1:
+		return "under a month"
+	case months < 12:
+		age = plural(months, "month")
+	default:
+		age = plural(months/12, "year")
+	}
+
+	if !pet.Dateofbirthexact.Bool {
+		age = "about " + age
+	}
+
+	return age
+}
+
+func plural(count int, unit string) string {
+	if count == 1 {
+		return fmt.Sprintf("1 %s", unit)
+	}
+
+	return fmt.Sprintf("%d %ss", count, unit)
+}
+
+// Turns a pet's name into a URL friendly slug, e.g. "Sir Barks-a-Lot!" becomes "sir-barks-a-lot".
+func Slugify(name string) string {
+	var slug strings.Builder
+	dash := false
+	for _, c := range strings.ToLower(name) {
+		if (c >= 'a' && c <= 'z') || (c >= '0' && c <= '9') {
+			if dash && slug.Len() > 0 {
+				slug.WriteByte('-')
+			}
+			slug.WriteRune(c)
+			dash = false
+		} else {
+			dash = true
+		}
+
+		if slug.Len() >= maxSlugLength {
+			break
+		}
+	}
+
+	if slug.Len() == 0 {
+		return "pet"
+	}
+
+	return slug.String()
+}
+
+// Creates a pet under a slug made from its name. Taken slugs are numbered,
+// so the second "Rex" becomes "rex-2". The slug never changes afterwards.
+func (a *APIConfig) createPetWithSlug(ctx context.Context, name string, imageUrl sql.NullString) (database.Pet, error) {
+	base := Slugify(name)
+	for attempt := 1; attempt <= maxSlugAttempts; attempt++ {
+		slug := base
+		if attempt > 1 {
+			slug = fmt.Sprintf("%s-%d", base, attempt)
+		}
+
+		pet, err := a.Db.CreatePet(ctx, database.CreatePetParams{
+			Name:     name,
+			Imageurl: imageUrl,
+			Slug:     slug,
+		})
+		if !isUniqueViolation(err) {
+			return pet, err
+		}
+	}
+
+	suffix := make([]byte, 4)
+	_, err := rand.Read(suffix)
+	if err != nil {
+		return database.Pet{}, err
+	}
+
+	return a.Db.CreatePet(ctx, database.CreatePetParams{
+		Name:     name,
+		Imageurl: imageUrl,
+		Slug:     fmt.Sprintf("%s-%s", base, hex.EncodeToString(suffix)),
+	})
+}
+
+// Reads a numeric id out of the named path wildcard.
+func pathID(r *http.Request, name string) (int32, error) {
+	id, err := strconv.ParseInt(r.PathValue(name), 10, 32)
+	if err != nil {
+		return 0, err
+	}
+
+	return int32(id), nil
+}
+
+// Loads a pet along with the caller's UserPets link to it.
+// Returns sql.ErrNoRows when the user is not linked to the pet.
+func (a *APIConfig) getLinkedPet(ctx context.Context, userID int, petID int32) (database.Pet, database.Userpet, error) {
+	userPet, err := a.Db.GetUserPet(ctx, database.GetUserPetParams{
+		Userid: int32(userID),
+		Petid:  petID,
+	})
+	if err != nil {
+		return database.Pet{}, database.Userpet{}, err
+	}
+
+	pet, err := a.Db.GetPet(ctx, petID)
+	if err != nil {
+		return database.Pet{}, database.Userpet{}, err
+	}
+
+	return pet, userPet, nil
+}
+
+// Resolves the {id} path value to a pet the user is linked to with at least
+// minLevel permissions. Writes a 404 (or 500) and returns false when the pet
+// can't be found, or a 403 when the user's access is too low.
+func (a *APIConfig) linkedPetFromPath(w http.ResponseWriter, r *http.Request, userID int, minLevel int32) (database.Pet, database.Userpet, bool) {
+	petID, err := pathID(r, "id")
+	if err != nil {
+		a.serveError(w, r, errNotFound)
+		return database.Pet{}, database.Userpet{}, false
+	}
+
+	pet, userPet, err := a.getLinkedPet(r.Context(), userID, petID)
+	if err != nil {
+		if isNotFound(err) {
+			a.serveError(w, r, errNotFound)
+		} else {
+			a.serverError(w, r, "error loading pet", err)
+		}
+		return database.Pet{}, database.Userpet{}, false
+	}
+
+	if userPet.PermissionsLevel < minLevel {
+		a.serveError(w, r, forbidden("You need more access to this pet to do that. Ask one of its owners."))
+		return database.Pet{}, database.Userpet{}, false
+	}
+
+	return pet, userPet, true
+}
+
+func (a *APIConfig) HandleGetPetDashboard(w http.ResponseWriter, r *http.Request, user_id int) {
+	ctx := r.Context()
+	pet, userPet, ok := a.linkedPetFromPath(w, r, user_id, PermissionsViewer)
+	if !ok {
+		return
+	}
+
+	recentSessions, err := a.listRecentTrainingSessionViews(ctx, pet.ID, recentSessionsLimit)
+	if err != nil {
+		a.serverError(w, r, "error listing recent training sessions", err)
+		return
+	}
+
+	skills, err := a.Db.ListSkillsForPet(ctx, pet.ID)
+	if err != nil {
+		a.serverError(w, r, "error listing skills", err)
+		return
+	}
+
+	likes, err := a.Db.ListLikesForPet(ctx, pet.ID)
+	if err != nil {
+		a.serverError(w, r, "error listing likes", err)
+		return
+	}
+
+	overdueGoals, err := a.Db.ListOverdueGoalsForPet(ctx, pet.ID)
+	if err != nil {
+		a.serverError(w, r, "error listing overdue goals", err)
+		return
+	}
+
+	achievedGoals, err := a.Db.ListRecentlyAchievedGoalsForPet(ctx, database.ListRecentlyAchievedGoalsForPetParams{
+		PetID: pet.ID,
+		AchievedAt: sql.NullTime{
+			Time:  time.Now().Add(-recentlyAchievedGoalsWindow),
+			Valid: true,
+		},
+	})
+	if err != nil {
+		a.serverError(w, r, "error listing achieved goals", err)
+		return
+	}
+
+	titles, err := a.Db.ListTitlesForPet(ctx, pet.ID)
+	if err != nil {
+		a.serverError(w, r, "error listing titles", err)
+		return
+	}
+
+	data := PetDashboardPageData{
+		BasePageData:   basePageData(r, fmt.Sprintf("TailScribe - %s", pet.Name)),
+		Pet:            pet,
+		DisplayName:    PetDisplayName(pet.Name, titles),
+		Age:            PetAge(pet, time.Now()),
+		RecentSessions: recentSessions,
+		Skills:         skills,
+		Likes:          likes,
+
+		OverdueGoals:          overdueGoals,
+		RecentlyAchievedGoals: achievedGoals,
+		Titles:                titles,
+		PermissionsLevel:      userPet.PermissionsLevel,
+	}
+
+	a.render(w, r, http.StatusOK, "dashboard", data)
+}
+
+func (a *APIConfig) renderEditPet(w http.ResponseWriter, r *http.Request, status int, pet database.Pet, form EditPetForm) {
+	data := EditPetPageData{
+		BasePageData: basePageData(r, fmt.Sprintf("Edit %s", pet.Name)),
+		Pet:          pet,
+		Sexes:        PetSexes,
+		EditPetForm:  form,
+	}
+
+	a.render(w, r, status, "edit_pet", data)
+}
+
+func (a *APIConfig) HandleGetEditPet(w http.ResponseWriter, r *http.Request, user_id int) {
+	pet, _, ok := a.linkedPetFromPath(w, r, user_id, PermissionsCoOwner)
+	if !ok {
+		return
+	}
+
+	a.renderEditPet(w, r, http.StatusOK, pet, editPetFormFromPet(pet))
+}
+
+func (a *APIConfig) HandlePostEditPet(w http.ResponseWriter, r *http.Request, user_id int) {
+	pet, _, ok := a.linkedPetFromPath(w, r, user_id, PermissionsCoOwner)
+	if !ok {
+		return
+	}
+
+	form := editPetFormFromRequest(r)
+	params, valid := form.validate(pet.ID, time.Now())
+	if !valid {
+		a.renderEditPet(w, r, http.StatusBadRequest, pet, form)
+		return
+	}
+
+	_, err := a.Db.UpdatePet(r.Context(), params)
+	if err != nil {
+		a.serverError(w, r, "error updating pet", err)
+		return
+	}
+
+	http.Redirect(w, r, fmt.Sprintf("/dashboard/pet/%d", pet.ID), http.StatusFound)
+}
+
+// Toggles whether the pet has a public profile at /p/{slug}.
+func (a *APIConfig) HandlePostPetPublicVisibility(w http.ResponseWriter, r *http.Request, user_id int) {
+	pet, _, ok := a.linkedPetFromPath(w, r, user_id, PermissionsCoOwner)
+	if !ok {
+		return
+	}
+
+	public := r.FormValue("publicly_viewable") == "on"
+	// Making a profile private is always allowed.
+	if public && !a.checkVerifiedEmail(w, r, user_id) {
+		return
+	}
+
+	err := a.Db.UpdatePetPubliclyViewable(r.Context(), database.UpdatePetPubliclyViewableParams{
+		ID:                 pet.ID,
+		Ispubliclyviewable: public,
+	})
+	if err != nil {
+		a.serverError(w, r, "error updating pet visibility", err)
+		return
+	}
+
+	http.Redirect(w, r, fmt.Sprintf("/dashboard/pet/%d", pet.ID), http.StatusFound)
+}
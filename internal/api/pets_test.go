@@ -0,0 +1,194 @@
+package api
+
+import (
+	"database/sql"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ctiller15/tailscribe/internal/database"
+	"github.com/stretchr/testify/assert"
+)
+
+// Creates a pet through the add new pet handler and returns its id.
+func createPetForUser(t *testing.T, authCookie *http.Cookie, name string) int {
+	formData := url.Values{
+		"name": {name},
+	}
+
+	request, _ := http.NewRequest(http.MethodPost, "/dashboard/add_new_pet", strings.NewReader(formData.Encode()))
+	request.Header.Add("Content-Type", "application/x-www-form-urlencoded")
+	request.AddCookie(authCookie)
+	response := httptest.NewRecorder()
+
+	apiCfg := createConfig()
+	apiCfg.CheckAuthMiddleware(apiCfg.HandlePostAddNewPet)(response, request)
+
+	location := response.Result().Header.Get("Location")
+	petID, err := strconv.Atoi(strings.TrimPrefix(location, "/dashboard/pet/"))
+	if err != nil {
+		t.Fatalf("unexpected pet location %q", location)
+	}
+
+	return petID
+}
+
+func getPetDashboard(authCookie *http.Cookie, petID string) *httptest.ResponseRecorder {
+	request, _ := http.NewRequest(http.MethodGet, fmt.Sprintf("/dashboard/pet/%s", petID), nil)
+	request.SetPathValue("id", petID)
+	request.AddCookie(authCookie)
+	response := httptest.NewRecorder()
+
+	apiCfg := createConfig()
+	apiCfg.CheckAuthMiddleware(apiCfg.HandleGetPetDashboard)(response, request)
+
+	return response
+}
+
+func TestHandleGetPetDashboard(t *testing.T) {
+	t.Run("Fails to find pet dashboard when unauthorized", func(t *testing.T) {
+		request, _ := http.NewRequest(http.MethodGet, "/dashboard/pet/1", nil)
+		request.SetPathValue("id", "1")
+		response := httptest.NewRecorder()
+
+		apiCfg := createConfig()
+		apiCfg.CheckAuthMiddleware(apiCfg.HandleGetPetDashboard)(response, request)
+
+		assert.Equal(t, 401, response.Result().StatusCode)
+	})
+
+	t.Run("Shows the pet and its recent sessions to a linked user", func(t *testing.T) {
+		cookies := signUserUp(randTestEmail(), testPassword)
+		petID := createPetForUser(t, cookies[0], "Maple")
+
+		postTrainingSession(cookies[0], petID, url.Values{
+			"started_at": {"2025-04-12T08:00"},
+			"location":   {"Agility field"},
+		})
+
+		response := getPetDashboard(cookies[0], strconv.Itoa(petID))
+
+		assert.Equal(t, 200, response.Result().StatusCode)
+		body := response.Body.String()
+		assert.Contains(t, body, "Maple")
+		assert.Contains(t, body, "Agility field")
+	})
+
+	t.Run("Returns 404 to users not linked to the pet", func(t *testing.T) {
+		ownerCookies := signUserUp(randTestEmail(), testPassword)
+		petID := createPetForUser(t, ownerCookies[0], "Juniper")
+
+		strangerCookies := signUserUp(randTestEmail(), testPassword)
+		response := getPetDashboard(strangerCookies[0], strconv.Itoa(petID))
+
+		assert.Equal(t, 404, response.Result().StatusCode)
+	})
+
+	t.Run("Returns 404 for a malformed pet id", func(t *testing.T) {
+		cookies := signUserUp(randTestEmail(), testPassword)
+		response := getPetDashboard(cookies[0], "not-a-number")
+
+		assert.Equal(t, 404, response.Result().StatusCode)
+	})
+}
+
+func TestPetAge(t *testing.T) {
+	now := time.Date(2025, 6, 15, 0, 0, 0, 0, time.UTC)
+	born := func(year int, month time.Month, day int, exact bool) database.Pet {
+		return database.Pet{
+			Dateofbirth:      sql.NullTime{Time: time.Date(year, month, day, 0, 0, 0, 0, time.UTC), Valid: true},
+			Dateofbirthexact: sql.NullBool{Bool: exact, Valid: true},
+		}
+	}
+
+	var ageTests = []struct {
+		name     string
+		pet      database.Pet
+		expected string
+	}{
+		{"unknown date of birth", database.Pet{}, ""},
+		{"exact years", born(2022, time.June, 15, true), "3 years"},
+		{"estimated years", born(2022, time.March, 1, false), "about 3 years"},
+		{"not yet a birthday", born(2022, time.June, 16, true), "2 years"},
+		{"puppy", born(2025, time.January, 1, true), "5 months"},
+		{"one month", born(2025, time.May, 10, false), "about 1 month"},
+		{"newborn", born(2025, time.June, 1, false), "under a month"},
+	}
+
+	for _, tt := range ageTests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, PetAge(tt.pet, now))
+		})
+	}
+}
+
+func TestEditPetFormValidate(t *testing.T) {
+	now := time.Date(2025, 6, 15, 0, 0, 0, 0, time.UTC)
+
+	t.Run("Builds update params", func(t *testing.T) {
+		form := EditPetForm{Name: "Maple", DateOfBirth: "2021-04-02", Sex: "female", Breed: "Border Collie"}
+		params, valid := form.validate(3, now)
+		assert.True(t, valid)
+		assert.Equal(t, int32(3), params.ID)
+		assert.True(t, params.Dateofbirth.Valid)
+		assert.Equal(t, sql.NullBool{Bool: false, Valid: true}, params.Dateofbirthexact)
+		assert.False(t, params.Species.Valid)
+	})
+
+	t.Run("Rejects an about text over 1000 characters", func(t *testing.T) {
+		form := EditPetForm{Name: "Maple", AboutText: strings.Repeat("a", 1001)}
+		_, valid := form.validate(3, now)
+		assert.False(t, valid)
+		assert.Contains(t, form.Errors, "about_text")
+	})
+
+	t.Run("Rejects an unknown sex", func(t *testing.T) {
+		form := EditPetForm{Name: "Maple", Sex: "f"}
+		_, valid := form.validate(3, now)
+		assert.False(t, valid)
+		assert.Contains(t, form.Errors, "sex")
+	})
+
+	t.Run("Rejects a date of birth in the future", func(t *testing.T) {
+		form := EditPetForm{Name: "Maple", DateOfBirth: "2025-07-01"}
+		_, valid := form.validate(3, now)
+		assert.False(t, valid)
+		assert.Contains(t, form.Errors, "date_of_birth")
+	})
+}
+
+func TestHandlePostEditPet(t *testing.T) {
+	t.Run("Saves the profile and shows an approximate age", func(t *testing.T) {
+		cookies := signUserUp(randTestEmail(), testPassword)
+		petID := createPetForUser(t, cookies[0], "Maple")
+
+		formData := url.Values{
+			"name":          {"Maple"},
+			"species":       {"Dog"},
+			"breed":         {"Border Collie"},
+			"sex":           {"female"},
+			"date_of_birth": {time.Now().AddDate(-4, -1, 0).Format(dateInputLayout)},
+			"about_text":    {"Loves frisbee."},
+		}
+		request, _ := http.NewRequest(http.MethodPost, fmt.Sprintf("/dashboard/pet/%d/edit", petID), strings.NewReader(formData.Encode()))
+		request.Header.Add("Content-Type", "application/x-www-form-urlencoded")
+		request.SetPathValue("id", strconv.Itoa(petID))
+		request.AddCookie(cookies[0])
+		response := httptest.NewRecorder()
+
+		apiCfg := createConfig()
+		apiCfg.CheckAuthMiddleware(apiCfg.HandlePostEditPet)(response, request)
+
+		assert.Equal(t, 302, response.Result().StatusCode)
+
+		dashboard := getPetDashboard(cookies[0], strconv.Itoa(petID))
+		body := dashboard.Body.String()
+		assert.Contains(t, body, "Border Collie")
+		assert.Contains(t, body, "about 4 years")
+	})
+}
@@ -0,0 +1,92 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/ctiller15/tailscribe/internal/database"
+)
+
+type PublicPetPageData struct {
+	BasePageData
+	Pet         database.Pet
+	DisplayName string
+	Age         string
+	// Sections whose hidden flag is set are left empty.
+	Titles []database.Title
+	Skills []database.Skill
+	Goals  []database.Goal
+	Likes  []database.Like
+}
+
+// Loads the sections of a public profile that the pet's owners haven't hidden.
+func (a *APIConfig) publicPetPageData(r *http.Request, pet database.Pet) (PublicPetPageData, error) {
+	ctx := r.Context()
+	data := PublicPetPageData{
+		BasePageData: basePageData(r, fmt.Sprintf("TailScribe - %s", pet.Name)),
+		Pet:          pet,
+		DisplayName:  pet.Name,
+		Age:          PetAge(pet, time.Now()),
+	}
+
+	if !pet.Titleshidden {
+		titles, err := a.Db.ListTitlesForPet(ctx, pet.ID)
+		if err != nil {
+			return data, err
+		}
+		data.Titles = titles
+		data.DisplayName = PetDisplayName(pet.Name, titles)
+	}
+
+	if !pet.Skillshidden {
+		skills, err := a.Db.ListSkillsForPet(ctx, pet.ID)
+		if err != nil {
+			return data, err
+		}
+		data.Skills = skills
+	}
+
+	if !pet.Goalshidden {
+		goals, err := a.Db.ListGoalsForPet(ctx, pet.ID)
+		if err != nil {
+			return data, err
+		}
+		for _, goal := range goals {
+			if goal.Status != "abandoned" {
+				data.Goals = append(data.Goals, goal)
+			}
+		}
+	}
+
+	if !pet.Likeshidden {
+		likes, err := a.Db.ListLikesForPet(ctx, pet.ID)
+		if err != nil {
+			return data, err
+		}
+		data.Likes = likes
+	}
+
+	return data, nil
+}
+
+// Shows a pet's public profile. Pets that aren't publicly viewable, or whose
+// owners have all deleted their accounts, are a 404, the same as a slug that
+// doesn't exist.
+func (a *APIConfig) HandleGetPublicPetProfile(w http.ResponseWriter, r *http.Request) error {
+	pet, err := a.Db.GetPublicPetBySlug(r.Context(), r.PathValue("slug"))
+	if err != nil {
+		if isNotFound(err) {
+			return errNotFound
+		}
+		return internalError("error loading pet", err)
+	}
+
+	data, err := a.publicPetPageData(r, pet)
+	if err != nil {
+		return internalError("error loading public profile", err)
+	}
+
+	a.render(w, r, http.StatusOK, "public_pet", data)
+	return nil
+}
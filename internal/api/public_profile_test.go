@@ -0,0 +1,116 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/ctiller15/tailscribe/internal/database"
+	"github.com/stretchr/testify/assert"
+)
+
+func getPublicPetProfile(slug string) *httptest.ResponseRecorder {
+	request, _ := http.NewRequest(http.MethodGet, fmt.Sprintf("/p/%s", slug), nil)
+	request.SetPathValue("slug", slug)
+	response := httptest.NewRecorder()
+
+	apiCfg := createConfig()
+	apiCfg.HandleErrors(apiCfg.HandleGetPublicPetProfile)(response, request)
+
+	return response
+}
+
+func TestSlugify(t *testing.T) {
+	var slugTests = []struct {
+		name     string
+		expected string
+	}{
+		{"Rex", "rex"},
+		{"Sir Barks-a-Lot!", "sir-barks-a-lot"},
+		{"  Mr. Whiskers 2 ", "mr-whiskers-2"},
+		{"???", "pet"},
+		{"A very long name that keeps going and going forever", "a-very-long-name-that-keeps-going-and-go"},
+	}
+
+	for _, tt := range slugTests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, Slugify(tt.name))
+		})
+	}
+}
+
+func TestHandleGetPublicPetProfile(t *testing.T) {
+	t.Run("Gives pets with the same name different slugs", func(t *testing.T) {
+		cookies := signUserUp(randTestEmail(), testPassword)
+		firstID := createPetForUser(t, cookies[0], "Public Rex")
+		secondID := createPetForUser(t, cookies[0], "Public Rex")
+
+		apiCfg := createConfig()
+		first, _ := apiCfg.Db.GetPet(t.Context(), int32(firstID))
+		second, _ := apiCfg.Db.GetPet(t.Context(), int32(secondID))
+
+		assert.NotEqual(t, first.Slug, second.Slug)
+	})
+
+	t.Run("Returns 404 for a pet that isn't public", func(t *testing.T) {
+		cookies := signUserUp(randTestEmail(), testPassword)
+		petID := createPetForUser(t, cookies[0], "Hermit")
+
+		apiCfg := createConfig()
+		pet, _ := apiCfg.Db.GetPet(t.Context(), int32(petID))
+
+		response := getPublicPetProfile(pet.Slug)
+		assert.Equal(t, 404, response.Result().StatusCode)
+	})
+
+	t.Run("Leaves out hidden sections", func(t *testing.T) {
+		cookies := signUserUp(randTestEmail(), testPassword)
+		petID := createPetForUser(t, cookies[0], "Showoff")
+
+		postSkill(cookies[0], petID, url.Values{
+			"name":  {"weave poles"},
+			"stage": {"fluent"},
+		})
+		postLike(cookies[0], petID, url.Values{
+			"name":       {"tennis balls"},
+			"category":   {"toy"},
+			"value_tier": {"high"},
+		})
+
+		apiCfg := createConfig()
+		apiCfg.Db.UpdatePetPubliclyViewable(t.Context(), database.UpdatePetPubliclyViewableParams{
+			ID:                 int32(petID),
+			Ispubliclyviewable: true,
+		})
+		apiCfg.Db.UpdatePetSkillsHidden(t.Context(), database.UpdatePetSkillsHiddenParams{
+			ID:           int32(petID),
+			Skillshidden: true,
+		})
+		pet, _ := apiCfg.Db.GetPet(t.Context(), int32(petID))
+
+		response := getPublicPetProfile(pet.Slug)
+		assert.Equal(t, 200, response.Result().StatusCode)
+		body := response.Body.String()
+		assert.Contains(t, body, "tennis balls")
+		assert.NotContains(t, body, "weave poles")
+	})
+	t.Run("Returns 404 once the owner deletes their account", func(t *testing.T) {
+		cookies := signUserUp(randTestEmail(), testPassword)
+		_, userID := callProtected(cookies[0])
+		petID := createPetForUser(t, cookies[0], "Orphan")
+
+		apiCfg := createConfig()
+		apiCfg.Db.UpdatePetPubliclyViewable(t.Context(), database.UpdatePetPubliclyViewableParams{
+			ID:                 int32(petID),
+			Ispubliclyviewable: true,
+		})
+		pet, _ := apiCfg.Db.GetPet(t.Context(), int32(petID))
+		assert.Equal(t, 200, getPublicPetProfile(pet.Slug).Result().StatusCode)
+
+		assert.NoError(t, apiCfg.Db.SoftDeleteUser(t.Context(), int32(userID)))
+
+		assert.Equal(t, 404, getPublicPetProfile(pet.Slug).Result().StatusCode)
+	})
+}
@@ -0,0 +1,161 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"math"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/ctiller15/tailscribe/internal/database"
+	"github.com/ctiller15/tailscribe/internal/ratelimit"
+)
+
+type RateLimiters struct {
+	// Logins from one address, whichever accounts they try.
+	LoginByIP *ratelimit.Limiter
+	// Logins to one account, from anywhere. Cleared by a successful one.
+	LoginByEmail *ratelimit.Limiter
+	// Signup attempts from one address.
+	SignupByIP *ratelimit.Limiter
+	// Two-factor codes for one user. Cleared by a right one.
+	TwoFactor *ratelimit.Limiter
+	// Verification emails resent to one user.
+	VerificationEmail *ratelimit.Limiter
+	// Password reset requests from one address, whichever emails they name.
+	PasswordResetByIP *ratelimit.Limiter
+	// Password reset requests for one email, so nobody can flood its inbox.
+	PasswordResetByEmail *ratelimit.Limiter
+}
+
+func newRateLimiters(store ratelimit.Store) RateLimiters {
+	return RateLimiters{
+		LoginByIP: ratelimit.New(store, "login:ip:", ratelimit.Policy{
+			Free:         20,
+			Backoff:      time.Second,
+			LockoutAfter: 100,
+			Lockout:      time.Hour,
+			Window:       time.Hour,
+		}),
+		LoginByEmail: ratelimit.New(store, "login:email:", ratelimit.Policy{
+			Free:         5,
+			Backoff:      time.Second,
+			LockoutAfter: 10,
+			Lockout:      15 * time.Minute,
+			Window:       time.Hour,
+		}),
+		SignupByIP: ratelimit.New(store, "signup:ip:", ratelimit.Policy{
+			Free:         5,
+			Backoff:      time.Minute,
+			LockoutAfter: 20,
+			Lockout:      24 * time.Hour,
+			Window:       24 * time.Hour,
+		}),
+		TwoFactor: ratelimit.New(store, "two_factor:user:", ratelimit.Policy{
+			Free:         5,
+			Backoff:      time.Second,
+			LockoutAfter: 10,
+			Lockout:      15 * time.Minute,
+			Window:       time.Hour,
+		}),
+		VerificationEmail: ratelimit.New(store, "verification_email:user:", ratelimit.Policy{
+			Free:         3,
+			Backoff:      time.Minute,
+			LockoutAfter: 10,
+			Lockout:      24 * time.Hour,
+			Window:       24 * time.Hour,
+		}),
+		PasswordResetByIP: ratelimit.New(store, "password_reset:ip:", ratelimit.Policy{
+			Free:         10,
+			Backoff:      time.Minute,
+			LockoutAfter: 30,
+			Lockout:      24 * time.Hour,
+			Window:       24 * time.Hour,
+		}),
+		PasswordResetByEmail: ratelimit.New(store, "password_reset:email:", ratelimit.Policy{
+			Free:         3,
+			Backoff:      time.Minute,
+			LockoutAfter: 10,
+			Lockout:      24 * time.Hour,
+			Window:       24 * time.Hour,
+		}),
+	}
+}
+
+func newRateLimitStore(kind string, db *database.Queries) (ratelimit.Store, error) {
+	switch kind {
+	case "", "memory":
+		return ratelimit.NewMemoryStore(), nil
+	case "postgres":
+		return ratelimit.NewPostgresStore(db), nil
+	default:
+		return nil, fmt.Errorf("unknown rate limit store %q", kind)
+	}
+}
+
+// The address the request came from, without its port.
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+func normalizeEmailKey(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+// Counts an attempt with each limiter, returning the longest wait any asks for.
+func attemptAll(ctx context.Context, checks map[*ratelimit.Limiter]string) (time.Duration, error) {
+	longest := time.Duration(0)
+	for limiter, key := range checks {
+		wait, err := limiter.Attempt(ctx, key)
+		if err != nil {
+			return 0, err
+		}
+		longest = max(longest, wait)
+	}
+	return longest, nil
+}
+
+// Describes a wait in words, rounding up so the user never retries too soon.
+func formatWait(wait time.Duration) string {
+	plural := func(n int, unit string) string {
+		if n == 1 {
+			return "1 " + unit
+		}
+		return fmt.Sprintf("%d %ss", n, unit)
+	}
+
+	switch {
+	case wait <= time.Minute:
+		return plural(int(math.Ceil(wait.Seconds())), "second")
+	case wait <= time.Hour:
+		return plural(int(math.Ceil(wait.Minutes())), "minute")
+	default:
+		return plural(int(math.Ceil(wait.Hours())), "hour")
+	}
+}
+
+// Removes stale rate limit counters every interval until ctx is done.
+func (a *APIConfig) RunRateLimitPruner(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			// Every policy forgets attempts within a day.
+			err := a.RateLimitStore.Prune(ctx, time.Now().Add(-24*time.Hour))
+			if err != nil {
+				a.Logger.Error("error pruning rate limits", slog.String("error", err.Error()))
+			}
+		}
+	}
+}
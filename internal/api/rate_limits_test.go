@@ -0,0 +1,193 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ctiller15/tailscribe/internal/mailer"
+	"github.com/stretchr/testify/assert"
+)
+
+func postLoginWith(apiCfg *APIConfig, remoteAddr, email, password string) *httptest.ResponseRecorder {
+	formData := url.Values{
+		"email":    {email},
+		"password": {password},
+	}
+	request, _ := http.NewRequest(http.MethodPost, "/login", strings.NewReader(formData.Encode()))
+	request.Header.Add("Content-Type", "application/x-www-form-urlencoded")
+	request.RemoteAddr = remoteAddr
+	response := httptest.NewRecorder()
+
+	apiCfg.HandlePostLogin(response, request)
+
+	return response
+}
+
+func postSignupWith(apiCfg *APIConfig, remoteAddr, email, password string) *httptest.ResponseRecorder {
+	formData := url.Values{
+		"email":    {email},
+		"password": {password},
+	}
+	request, _ := http.NewRequest(http.MethodPost, "/signup", strings.NewReader(formData.Encode()))
+	request.Header.Add("Content-Type", "application/x-www-form-urlencoded")
+	request.RemoteAddr = remoteAddr
+	response := httptest.NewRecorder()
+
+	apiCfg.HandlePostSignup(response, request)
+
+	return response
+}
+
+func TestFormatWait(t *testing.T) {
+	tests := []struct {
+		wait     time.Duration
+		expected string
+	}{
+		{time.Second, "1 second"},
+		{1500 * time.Millisecond, "2 seconds"},
+		{time.Minute, "60 seconds"},
+		{61 * time.Second, "2 minutes"},
+		{15 * time.Minute, "15 minutes"},
+		{90 * time.Minute, "2 hours"},
+	}
+
+	for _, test := range tests {
+		assert.Equal(t, test.expected, formatWait(test.wait))
+	}
+}
+
+func TestClientIP(t *testing.T) {
+	request, _ := http.NewRequest(http.MethodGet, "/", nil)
+
+	request.RemoteAddr = "203.0.113.7:52100"
+	assert.Equal(t, "203.0.113.7", clientIP(request))
+
+	request.RemoteAddr = "[2001:db8::1]:443"
+	assert.Equal(t, "2001:db8::1", clientIP(request))
+}
+
+func TestLoginRateLimit(t *testing.T) {
+	t.Run("Locks out an account after repeated failures", func(t *testing.T) {
+		email := randTestEmail()
+		signUserUp(email, testPassword)
+		apiCfg := createConfig()
+
+		for range 5 {
+			response := postLoginWith(apiCfg, "203.0.113.1:1000", email, "wrong")
+			assert.Equal(t, 401, response.Result().StatusCode)
+		}
+
+		// Even the right password waits, from any address.
+		response := postLoginWith(apiCfg, "203.0.113.2:1000", email, testPassword)
+		assert.Equal(t, 429, response.Result().StatusCode)
+		assert.NotEmpty(t, response.Result().Header.Get("Retry-After"))
+		assert.Contains(t, response.Body.String(), "Too many attempts")
+	})
+
+	t.Run("A successful login clears the account's failures", func(t *testing.T) {
+		email := randTestEmail()
+		signUserUp(email, testPassword)
+		apiCfg := createConfig()
+
+		for range 4 {
+			postLoginWith(apiCfg, "203.0.113.3:1000", email, "wrong")
+		}
+		response := postLoginWith(apiCfg, "203.0.113.3:1000", email, testPassword)
+		assert.Equal(t, 302, response.Result().StatusCode)
+
+		for range 4 {
+			postLoginWith(apiCfg, "203.0.113.3:1000", email, "wrong")
+		}
+		response = postLoginWith(apiCfg, "203.0.113.3:1000", email, testPassword)
+		assert.Equal(t, 302, response.Result().StatusCode)
+	})
+
+	t.Run("Slows down an address guessing at many accounts", func(t *testing.T) {
+		apiCfg := createConfig()
+
+		for range 20 {
+			postLoginWith(apiCfg, "203.0.113.4:1000", randTestEmail(), "wrong")
+		}
+
+		response := postLoginWith(apiCfg, "203.0.113.4:1000", randTestEmail(), "wrong")
+		assert.Equal(t, 429, response.Result().StatusCode)
+
+		response = postLoginWith(apiCfg, "203.0.113.5:1000", randTestEmail(), "wrong")
+		assert.Equal(t, 401, response.Result().StatusCode)
+	})
+}
+
+func TestSignupRateLimit(t *testing.T) {
+	apiCfg := createConfig()
+
+	for range 5 {
+		response := postSignupWith(apiCfg, "203.0.113.6:1000", randTestEmail(), testPassword)
+		assert.Equal(t, 302, response.Result().StatusCode)
+	}
+
+	response := postSignupWith(apiCfg, "203.0.113.6:1000", randTestEmail(), testPassword)
+	assert.Equal(t, 429, response.Result().StatusCode)
+}
+
+func postForgotPasswordWith(apiCfg *APIConfig, remoteAddr, email string) *httptest.ResponseRecorder {
+	formData := url.Values{"email": {email}}
+	request, _ := http.NewRequest(http.MethodPost, "/forgot-password", strings.NewReader(formData.Encode()))
+	request.Header.Add("Content-Type", "application/x-www-form-urlencoded")
+	request.RemoteAddr = remoteAddr
+	response := httptest.NewRecorder()
+
+	apiCfg.HandlePostForgotPassword(response, request)
+
+	return response
+}
+
+func TestForgotPasswordRateLimit(t *testing.T) {
+	countEmails := func(dir string) int {
+		files, _ := filepath.Glob(filepath.Join(dir, "*.eml"))
+		return len(files)
+	}
+
+	t.Run("Stops emailing one address, without saying so", func(t *testing.T) {
+		email := randTestEmail()
+		signUserUp(email, testPassword)
+		dir := t.TempDir()
+		apiCfg := createConfig()
+		apiCfg.Mailer = &mailer.FileMailer{Dir: dir}
+
+		for i := range 3 {
+			response := postForgotPasswordWith(apiCfg, fmt.Sprintf("203.0.113.%d:1000", 10+i), email)
+			assert.Equal(t, 200, response.Result().StatusCode)
+		}
+		assert.Equal(t, 3, countEmails(dir))
+
+		response := postForgotPasswordWith(apiCfg, "203.0.113.13:1000", email)
+		assert.Equal(t, 200, response.Result().StatusCode)
+		assert.Contains(t, response.Body.String(), email)
+		assert.Equal(t, 3, countEmails(dir))
+	})
+
+	t.Run("Stops an address asking about many emails", func(t *testing.T) {
+		email := randTestEmail()
+		signUserUp(email, testPassword)
+		dir := t.TempDir()
+		apiCfg := createConfig()
+		apiCfg.Mailer = &mailer.FileMailer{Dir: dir}
+
+		for range 10 {
+			postForgotPasswordWith(apiCfg, "203.0.113.14:1000", randTestEmail())
+		}
+
+		response := postForgotPasswordWith(apiCfg, "203.0.113.14:1000", email)
+		assert.Equal(t, 200, response.Result().StatusCode)
+		assert.Equal(t, 0, countEmails(dir))
+
+		postForgotPasswordWith(apiCfg, "203.0.113.15:1000", email)
+		assert.Equal(t, 1, countEmails(dir))
+	})
+}
@@ -0,0 +1,114 @@
+package api
+
+import "net/http"
+
+// Every route the app serves, behind the middleware that applies to all of them.
+func NewRouter(a *APIConfig) http.Handler {
+	static := http.FileServer(http.Dir("./ui/static/"))
+
+	mux := http.NewServeMux()
+
+	mux.Handle("/static/", http.StripPrefix("/static/", static))
+
+	mux.HandleFunc("GET /healthz", a.HandleHealthz)
+	mux.HandleFunc("GET /readyz", a.HandleReadyz)
+	mux.HandleFunc("GET /version", a.HandleVersion)
+
+	mux.HandleFunc("GET /{$}", a.HandleIndex)
+	mux.HandleFunc("GET /signup", a.HandleSignupPage)
+	mux.HandleFunc("POST /signup", a.HandlePostSignup)
+	mux.HandleFunc("GET /login", a.HandleLoginPage)
+	mux.HandleFunc("POST /login", a.HandlePostLogin)
+	mux.HandleFunc("GET /login/two_factor", a.HandleGetTwoFactorLogin)
+	mux.HandleFunc("POST /login/two_factor", a.HandlePostTwoFactorLogin)
+	mux.HandleFunc("POST /logout", a.HandlePostLogout)
+	mux.HandleFunc("GET /forgot-password", a.HandleGetForgotPassword)
+	mux.HandleFunc("POST /forgot-password", a.HandlePostForgotPassword)
+	mux.HandleFunc("GET /reset-password/{token}", a.HandleGetResetPassword)
+	mux.HandleFunc("POST /reset-password/{token}", a.HandlePostResetPassword)
+	mux.HandleFunc("GET /verify-email/{token}", a.HandleGetVerifyEmail)
+	mux.HandleFunc("GET /auth/{provider}/login", a.HandleGetOAuthLogin)
+	mux.HandleFunc("GET /auth/{provider}/callback", a.HandleGetOAuthCallback)
+	mux.Handle("GET /auth/{provider}/link", a.CheckAuthMiddleware(a.HandleGetOAuthLink))
+	mux.Handle("GET /account", a.CheckAuthMiddleware(a.HandleGetAccount))
+	mux.Handle("POST /account/connections/{provider}/unlink", a.CheckAuthMiddleware(a.HandlePostUnlinkProvider))
+	mux.Handle("GET /account/export", a.CheckAuthMiddleware(a.HandleGetDataExport))
+	mux.Handle("GET /account/delete", a.CheckAuthMiddleware(a.HandleGetDeleteAccount))
+	mux.Handle("POST /account/delete", a.CheckAuthMiddleware(a.HandlePostDeleteAccount))
+	mux.Handle("GET /account/sessions", a.CheckAuthMiddleware(a.HandleGetSessions))
+	mux.Handle("POST /account/sessions/revoke-all", a.CheckAuthMiddleware(a.HandlePostRevokeAllSessions))
+	mux.Handle("POST /account/sessions/{sessionID}/revoke", a.CheckAuthMiddleware(a.HandlePostRevokeSession))
+	mux.Handle("POST /account/verify-email", a.CheckAuthMiddleware(a.HandlePostResendVerification))
+	mux.Handle("GET /account/two_factor", a.CheckAuthMiddleware(a.HandleGetTwoFactor))
+	mux.Handle("POST /account/two_factor/setup", a.CheckAuthMiddleware(a.HandlePostTwoFactorSetup))
+	mux.Handle("POST /account/two_factor/enable", a.CheckAuthMiddleware(a.HandlePostTwoFactorEnable))
+	mux.Handle("POST /account/two_factor/recovery_codes", a.CheckAuthMiddleware(a.HandlePostTwoFactorRecoveryCodes))
+	mux.Handle("POST /account/two_factor/disable", a.CheckAuthMiddleware(a.HandlePostTwoFactorDisable))
+	mux.Handle("GET /admin/two_factor", a.CheckAdminMiddleware(a.HandleGetAdminTwoFactor))
+	mux.Handle("POST /admin/two_factor/reset", a.CheckAdminMiddleware(a.HandlePostAdminTwoFactorReset))
+	mux.HandleFunc("/attributions", a.HandleAttributions)
+	mux.HandleFunc("/terms", a.HandleTerms)
+	mux.HandleFunc("/privacy", a.HandlePrivacyPolicy)
+	mux.HandleFunc("/contact", a.HandleContactUs)
+	mux.HandleFunc("GET /p/{slug}", a.HandleErrors(a.HandleGetPublicPetProfile))
+
+	mux.Handle("GET /dashboard", a.CheckAuthMiddleware(a.HandleGetDashboard))
+	mux.Handle("GET /dashboard/add_new_pet", a.CheckAuthMiddleware(a.HandleGetAddNewPet))
+	mux.Handle("POST /dashboard/add_new_pet", a.CheckAuthMiddleware(a.HandlePostAddNewPet))
+	mux.Handle("GET /dashboard/image_auth", a.CheckAuthMiddleware(a.HandleGetImageAuthParams))
+
+	mux.Handle("GET /dashboard/pet/{id}", a.CheckAuthMiddleware(a.HandleGetPetDashboard))
+	mux.Handle("GET /dashboard/pet/{id}/edit", a.CheckAuthMiddleware(a.HandleGetEditPet))
+	mux.Handle("POST /dashboard/pet/{id}/edit", a.CheckAuthMiddleware(a.HandlePostEditPet))
+	mux.Handle("POST /dashboard/pet/{id}/visibility", a.CheckAuthMiddleware(a.HandlePostPetPublicVisibility))
+	mux.Handle("POST /dashboard/pet/{id}/hidden", a.CheckAuthMiddleware(a.HandlePostPetHidden))
+	mux.Handle("POST /dashboard/pet/{id}/archived", a.CheckAuthMiddleware(a.HandlePostPetArchived))
+	mux.Handle("GET /dashboard/pet/{id}/access", a.CheckAuthMiddleware(a.HandleGetPetAccess))
+	mux.Handle("POST /dashboard/pet/{id}/access/invitations", a.CheckAuthMiddleware(a.HandlePostPetInvitation))
+	mux.Handle("POST /dashboard/pet/{id}/access/invitations/{invitationID}/revoke", a.CheckAuthMiddleware(a.HandlePostRevokePetInvitation))
+	mux.Handle("POST /dashboard/pet/{id}/access/{userID}", a.CheckAuthMiddleware(a.HandlePostPetAccess))
+	mux.Handle("POST /dashboard/pet/{id}/access/{userID}/delete", a.CheckAuthMiddleware(a.HandlePostRevokePetAccess))
+	mux.Handle("GET /invitations/{token}", a.CheckAuthMiddleware(a.HandleGetInvitation))
+	mux.Handle("POST /invitations/{token}", a.CheckAuthMiddleware(a.HandlePostAcceptInvitation))
+	mux.Handle("GET /dashboard/pet/{id}/sessions", a.CheckAuthMiddleware(a.HandleGetTrainingSessions))
+	mux.Handle("GET /dashboard/pet/{id}/sessions/new", a.CheckAuthMiddleware(a.HandleGetNewTrainingSession))
+	mux.Handle("POST /dashboard/pet/{id}/sessions", a.CheckAuthMiddleware(a.HandlePostTrainingSession))
+	mux.Handle("GET /dashboard/pet/{id}/sessions/{sessionID}/edit", a.CheckAuthMiddleware(a.HandleGetEditTrainingSession))
+	mux.Handle("POST /dashboard/pet/{id}/sessions/{sessionID}", a.CheckAuthMiddleware(a.HandlePostEditTrainingSession))
+	mux.Handle("POST /dashboard/pet/{id}/sessions/{sessionID}/delete", a.CheckAuthMiddleware(a.HandlePostDeleteTrainingSession))
+
+	mux.Handle("GET /dashboard/pet/{id}/skills", a.CheckAuthMiddleware(a.HandleGetSkills))
+	mux.Handle("POST /dashboard/pet/{id}/skills", a.CheckAuthMiddleware(a.HandlePostSkill))
+	mux.Handle("POST /dashboard/pet/{id}/skills/visibility", a.CheckAuthMiddleware(a.HandlePostSkillsVisibility))
+	mux.Handle("GET /dashboard/pet/{id}/skills/{skillID}", a.CheckAuthMiddleware(a.HandleGetSkill))
+	mux.Handle("POST /dashboard/pet/{id}/skills/{skillID}", a.CheckAuthMiddleware(a.HandlePostEditSkill))
+	mux.Handle("POST /dashboard/pet/{id}/skills/{skillID}/delete", a.CheckAuthMiddleware(a.HandlePostDeleteSkill))
+
+	mux.Handle("GET /dashboard/pet/{id}/goals", a.CheckAuthMiddleware(a.HandleGetGoals))
+	mux.Handle("POST /dashboard/pet/{id}/goals", a.CheckAuthMiddleware(a.HandlePostGoal))
+	mux.Handle("POST /dashboard/pet/{id}/goals/visibility", a.CheckAuthMiddleware(a.HandlePostGoalsVisibility))
+	mux.Handle("GET /dashboard/pet/{id}/goals/{goalID}", a.CheckAuthMiddleware(a.HandleGetGoal))
+	mux.Handle("POST /dashboard/pet/{id}/goals/{goalID}", a.CheckAuthMiddleware(a.HandlePostEditGoal))
+	mux.Handle("POST /dashboard/pet/{id}/goals/{goalID}/notes", a.CheckAuthMiddleware(a.HandlePostGoalProgressNote))
+	mux.Handle("POST /dashboard/pet/{id}/goals/{goalID}/delete", a.CheckAuthMiddleware(a.HandlePostDeleteGoal))
+
+	mux.Handle("GET /dashboard/pet/{id}/likes", a.CheckAuthMiddleware(a.HandleGetLikes))
+	mux.Handle("POST /dashboard/pet/{id}/likes", a.CheckAuthMiddleware(a.HandlePostLike))
+	mux.Handle("POST /dashboard/pet/{id}/likes/order", a.CheckAuthMiddleware(a.HandlePostLikesOrder))
+	mux.Handle("POST /dashboard/pet/{id}/likes/visibility", a.CheckAuthMiddleware(a.HandlePostLikesVisibility))
+	mux.Handle("GET /dashboard/pet/{id}/likes/{likeID}", a.CheckAuthMiddleware(a.HandleGetLike))
+	mux.Handle("POST /dashboard/pet/{id}/likes/{likeID}", a.CheckAuthMiddleware(a.HandlePostEditLike))
+	mux.Handle("POST /dashboard/pet/{id}/likes/{likeID}/delete", a.CheckAuthMiddleware(a.HandlePostDeleteLike))
+	mux.Handle("GET /dashboard/pet/{id}/titles", a.CheckAuthMiddleware(a.HandleGetTitles))
+	mux.Handle("POST /dashboard/pet/{id}/titles", a.CheckAuthMiddleware(a.HandlePostTitle))
+	mux.Handle("POST /dashboard/pet/{id}/titles/visibility", a.CheckAuthMiddleware(a.HandlePostTitlesVisibility))
+	mux.Handle("GET /dashboard/pet/{id}/titles/{titleID}", a.CheckAuthMiddleware(a.HandleGetTitle))
+	mux.Handle("POST /dashboard/pet/{id}/titles/{titleID}", a.CheckAuthMiddleware(a.HandlePostEditTitle))
+	mux.Handle("POST /dashboard/pet/{id}/titles/{titleID}/delete", a.CheckAuthMiddleware(a.HandlePostDeleteTitle))
+
+	// Anything no route above matches.
+	mux.HandleFunc("/", a.HandleErrors(a.HandleNotFound))
+
+	// Every form POST has to carry the CSRF token, including ones registered above.
+	return a.CSRFMiddleware(mux)
+}
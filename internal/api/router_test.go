@@ -0,0 +1,78 @@
+package api
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/ctiller15/tailscribe/internal/auth"
+	"github.com/stretchr/testify/assert"
+)
+
+// Sends the request through every route and middleware, as the server would.
+func serveRouted(request *http.Request) *httptest.ResponseRecorder {
+	response := httptest.NewRecorder()
+	NewRouter(createConfig()).ServeHTTP(response, request)
+	return response
+}
+
+// A form POST carrying a valid CSRF token, plus any other cookies.
+func postRouted(path string, formData url.Values, cookies ...*http.Cookie) *httptest.ResponseRecorder {
+	request, _ := http.NewRequest(http.MethodPost, path, nil)
+	request.Header.Add("Content-Type", "application/x-www-form-urlencoded")
+	request.AddCookie(&http.Cookie{Name: csrfCookie, Value: strings.Repeat("ab", 32)})
+	for _, cookie := range cookies {
+		request.AddCookie(cookie)
+	}
+
+	// The token depends on the session cookie, if there is one.
+	formData.Set(csrfField, auth.MakeCSRFToken(createConfig().csrfBinding(request), TestEnvVars.Secret))
+	request.Body = io.NopCloser(strings.NewReader(formData.Encode()))
+
+	return serveRouted(request)
+}
+
+func TestRouter(t *testing.T) {
+	var routeTests = []struct {
+		method string
+		path   string
+		status int
+	}{
+		{http.MethodGet, "/", http.StatusOK},
+		{http.MethodGet, "/login", http.StatusOK},
+		{http.MethodGet, "/signup", http.StatusOK},
+		{http.MethodGet, "/static/css/styles.css", http.StatusOK},
+		{http.MethodGet, "/no/such/page", http.StatusNotFound},
+		{http.MethodGet, "/dashboard", http.StatusUnauthorized},
+		{http.MethodGet, "/dashboard/image_auth", http.StatusUnauthorized},
+		// Without a CSRF token, a POST never reaches its handler.
+		{http.MethodPost, "/dashboard/add_new_pet", http.StatusForbidden},
+	}
+
+	for _, tt := range routeTests {
+		t.Run(tt.method+" "+tt.path, func(t *testing.T) {
+			request, _ := http.NewRequest(tt.method, tt.path, nil)
+
+			assert.Equal(t, tt.status, serveRouted(request).Result().StatusCode)
+		})
+	}
+
+	t.Run("Signs up and adds a pet", func(t *testing.T) {
+		response := postRouted("/signup", url.Values{"email": {randTestEmail()}, "password": {testPassword}})
+		assert.Equal(t, http.StatusFound, response.Result().StatusCode)
+
+		// Signup lands somewhere that exists.
+		request, _ := http.NewRequest(http.MethodGet, response.Result().Header.Get("Location"), nil)
+		for _, cookie := range response.Result().Cookies() {
+			request.AddCookie(cookie)
+		}
+		assert.Equal(t, http.StatusOK, serveRouted(request).Result().StatusCode)
+
+		response = postRouted("/dashboard/add_new_pet", url.Values{"name": {"Rex"}}, response.Result().Cookies()...)
+		assert.Equal(t, http.StatusFound, response.Result().StatusCode)
+		assert.True(t, strings.HasPrefix(response.Result().Header.Get("Location"), "/dashboard/pet/"))
+	})
+}
@@ -0,0 +1,208 @@
+package api
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/ctiller15/tailscribe/internal/database"
+	"github.com/google/uuid"
+)
+
+// How stale a session's last seen time can get before a request updates it.
+const sessionTouchInterval = 5 * time.Minute
+
+type sessionContextKey struct{}
+
+type SessionView struct {
+	Session database.Session
+	Device  string
+	// The session this page was loaded from.
+	Current bool
+}
+
+type SessionsPageData struct {
+	BasePageData
+	Sessions []SessionView
+}
+
+// The session an authenticated request belongs to.
+func currentSessionID(r *http.Request) uuid.UUID {
+	sessionID, _ := r.Context().Value(sessionContextKey{}).(uuid.UUID)
+	return sessionID
+}
+
+func withSessionID(r *http.Request, sessionID uuid.UUID) *http.Request {
+	return r.WithContext(context.WithValue(r.Context(), sessionContextKey{}, sessionID))
+}
+
+// A rough "Browser on OS" from a user agent, enough for people to recognize their devices.
+func describeDevice(userAgent string) string {
+	browsers := []struct{ token, name string }{
+		{"Edg/", "Edge"},
+		{"OPR/", "Opera"},
+		{"Firefox/", "Firefox"},
+		{"FxiOS/", "Firefox"},
+		{"CriOS/", "Chrome"},
+		{"Chrome/", "Chrome"},
+		{"Safari/", "Safari"},
+	}
+	systems := []struct{ token, name string }{
+		{"Windows", "Windows"},
+		{"Android", "Android"},
+		{"iPhone", "iOS"},
+		{"iPad", "iPadOS"},
+		{"Mac OS X", "macOS"},
+		{"CrOS", "ChromeOS"},
+		{"Linux", "Linux"},
+	}
+
+	browser := ""
+	for _, b := range browsers {
+		if strings.Contains(userAgent, b.token) {
+			browser = b.name
+			break
+		}
+	}
+
+	system := ""
+	for _, s := range systems {
+		if strings.Contains(userAgent, s.token) {
+			system = s.name
+			break
+		}
+	}
+
+	switch {
+	case browser != "" && system != "":
+		return browser + " on " + system
+	case browser != "":
+		return browser
+	case system != "":
+		return system
+	default:
+		return "Unknown device"
+	}
+}
+
+// Loads the session and checks it belongs to the user and is still signed in.
+func (a *APIConfig) activeSession(ctx context.Context, sessionID uuid.UUID, userID int) (database.Session, bool) {
+	session, err := a.Db.GetActiveSession(ctx, sessionID)
+	if err != nil {
+		if !isNotFound(err) {
+			a.Logger.Error("error loading session", slog.String("error", err.Error()))
+		}
+		return database.Session{}, false
+	}
+
+	return session, int(session.UserID) == userID
+}
+
+// Records that the session was used, at most every sessionTouchInterval.
+func (a *APIConfig) touchSession(r *http.Request, session database.Session) {
+	if time.Since(session.LastSeenAt) < sessionTouchInterval {
+		return
+	}
+
+	err := a.Db.TouchSession(r.Context(), database.TouchSessionParams{
+		ID:        session.ID,
+		UserAgent: r.UserAgent(),
+		IpAddress: clientIP(r),
+	})
+	if err != nil {
+		a.Logger.Error("error updating session", slog.String("error", err.Error()))
+	}
+}
+
+// Signs a session out. Its refresh tokens stop working, and so do its access
+// tokens, because every request checks the session. Reports whether the user
+// had that session.
+func (a *APIConfig) revokeSession(ctx context.Context, userID int32, sessionID uuid.UUID) (bool, error) {
+	revoked, err := a.Db.RevokeSession(ctx, database.RevokeSessionParams{
+		ID:     sessionID,
+		UserID: userID,
+	})
+	if err != nil {
+		return false, err
+	}
+	if revoked == 0 {
+		return false, nil
+	}
+
+	return true, a.Db.RevokeRefreshTokenFamily(ctx, sessionID)
+}
+
+// Signs the user out everywhere.
+func revokeAllSessions(ctx context.Context, q *database.Queries, userID int32) error {
+	err := q.RevokeSessionsForUser(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	return q.RevokeRefreshTokensForUser(ctx, userID)
+}
+
+func (a *APIConfig) HandleGetSessions(w http.ResponseWriter, r *http.Request, user_id int) {
+	sessions, err := a.Db.ListActiveSessionsForUser(r.Context(), int32(user_id))
+	if err != nil {
+		a.serverError(w, r, "error listing sessions", err)
+		return
+	}
+
+	current := currentSessionID(r)
+	views := make([]SessionView, 0, len(sessions))
+	for _, session := range sessions {
+		views = append(views, SessionView{
+			Session: session,
+			Device:  describeDevice(session.UserAgent),
+			Current: session.ID == current,
+		})
+	}
+
+	data := SessionsPageData{
+		BasePageData: basePageData(r, "TailScribe - Where You're Signed In"),
+		Sessions:     views,
+	}
+
+	a.render(w, r, http.StatusOK, "sessions", data)
+}
+
+func (a *APIConfig) HandlePostRevokeSession(w http.ResponseWriter, r *http.Request, user_id int) {
+	sessionID, err := uuid.Parse(r.PathValue("sessionID"))
+	if err != nil {
+		a.serveError(w, r, errNotFound)
+		return
+	}
+
+	revoked, err := a.revokeSession(r.Context(), int32(user_id), sessionID)
+	if err != nil {
+		a.serverError(w, r, "error revoking session", err)
+		return
+	}
+	if !revoked {
+		a.serveError(w, r, errNotFound)
+		return
+	}
+
+	if sessionID == currentSessionID(r) {
+		a.clearSessionCookies(w)
+		http.Redirect(w, r, "/login", http.StatusFound)
+		return
+	}
+
+	http.Redirect(w, r, "/account/sessions", http.StatusFound)
+}
+
+func (a *APIConfig) HandlePostRevokeAllSessions(w http.ResponseWriter, r *http.Request, user_id int) {
+	err := revokeAllSessions(r.Context(), &a.Db, int32(user_id))
+	if err != nil {
+		a.serverError(w, r, "error revoking sessions", err)
+		return
+	}
+
+	a.clearSessionCookies(w)
+
+	http.Redirect(w, r, "/login", http.StatusFound)
+}
@@ -0,0 +1,139 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/ctiller15/tailscribe/internal/auth"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func sessionIDFromCookies(t *testing.T, cookies []*http.Cookie) uuid.UUID {
+	_, sessionID, err := auth.ValidateSessionJWT(findCookie(cookies, "token").Value, TestEnvVars.Secret)
+	assert.NoError(t, err)
+	return sessionID
+}
+
+func postRevokeSession(cookies []*http.Cookie, sessionID string) *httptest.ResponseRecorder {
+	request, _ := http.NewRequest(http.MethodPost, "/account/sessions/"+sessionID+"/revoke", nil)
+	request.SetPathValue("sessionID", sessionID)
+	for _, cookie := range cookies {
+		request.AddCookie(cookie)
+	}
+	response := httptest.NewRecorder()
+
+	apiCfg := createConfig()
+	apiCfg.CheckAuthMiddleware(apiCfg.HandlePostRevokeSession)(response, request)
+
+	return response
+}
+
+func TestDescribeDevice(t *testing.T) {
+	tests := []struct {
+		userAgent string
+		expected  string
+	}{
+		{"Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36", "Chrome on Windows"},
+		{"Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36 Edg/120.0.0.0", "Edge on Windows"},
+		{"Mozilla/5.0 (X11; Linux x86_64; rv:121.0) Gecko/20100101 Firefox/121.0", "Firefox on Linux"},
+		{"Mozilla/5.0 (iPhone; CPU iPhone OS 17_1 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.1 Mobile/15E148 Safari/604.1", "Safari on iOS"},
+		{"Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.1 Safari/605.1.15", "Safari on macOS"},
+		{"Mozilla/5.0 (Linux; Android 14) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Mobile Safari/537.36", "Chrome on Android"},
+		{"curl/8.4.0", "Unknown device"},
+		{"", "Unknown device"},
+	}
+
+	for _, test := range tests {
+		assert.Equal(t, test.expected, describeDevice(test.userAgent))
+	}
+}
+
+func TestHandleGetSessions(t *testing.T) {
+	email := randTestEmail()
+	cookies := signUserUp(email, testPassword)
+	postLoginWith(createConfig(), "203.0.113.20:1000", email, testPassword)
+
+	request, _ := http.NewRequest(http.MethodGet, "/account/sessions", nil)
+	request.AddCookie(findCookie(cookies, "token"))
+	response := httptest.NewRecorder()
+	apiCfg := createConfig()
+	apiCfg.CheckAuthMiddleware(apiCfg.HandleGetSessions)(response, request)
+
+	assert.Equal(t, 200, response.Result().StatusCode)
+	assert.Contains(t, response.Body.String(), "(this device)")
+	assert.Contains(t, response.Body.String(), "203.0.113.20")
+	assert.Contains(t, response.Body.String(), "/account/sessions/"+sessionIDFromCookies(t, cookies).String()+"/revoke")
+}
+
+func TestRevokeSession(t *testing.T) {
+	t.Run("Signs out another device's access and refresh tokens", func(t *testing.T) {
+		email := randTestEmail()
+		cookies := signUserUp(email, testPassword)
+		other := postLoginWith(createConfig(), "203.0.113.21:1000", email, testPassword).Result().Cookies()
+
+		response := postRevokeSession(cookies, sessionIDFromCookies(t, other).String())
+		assert.Equal(t, http.StatusFound, response.Result().StatusCode)
+		assert.Equal(t, "/account/sessions", response.Result().Header.Get("Location"))
+
+		response, userID := callProtected(findCookie(other, "token"))
+		assert.Equal(t, 401, response.Result().StatusCode)
+		assert.Equal(t, -1, userID)
+
+		response, userID = callProtected(findCookie(other, "refresh_token"))
+		assert.Equal(t, 401, response.Result().StatusCode)
+		assert.Equal(t, -1, userID)
+
+		// This device is still signed in.
+		response, _ = callProtected(findCookie(cookies, "token"))
+		assert.Equal(t, 200, response.Result().StatusCode)
+	})
+
+	t.Run("Signs out of the current device", func(t *testing.T) {
+		cookies := signUserUp(randTestEmail(), testPassword)
+
+		response := postRevokeSession(cookies, sessionIDFromCookies(t, cookies).String())
+		assert.Equal(t, http.StatusFound, response.Result().StatusCode)
+		assert.Equal(t, "/login", response.Result().Header.Get("Location"))
+
+		response, _ = callProtected(findCookie(cookies, "token"))
+		assert.Equal(t, 401, response.Result().StatusCode)
+	})
+
+	t.Run("Can't revoke someone else's session", func(t *testing.T) {
+		cookies := signUserUp(randTestEmail(), testPassword)
+		victim := signUserUp(randTestEmail(), testPassword)
+
+		response := postRevokeSession(cookies, sessionIDFromCookies(t, victim).String())
+		assert.Equal(t, 404, response.Result().StatusCode)
+
+		response, _ = callProtected(findCookie(victim, "token"))
+		assert.Equal(t, 200, response.Result().StatusCode)
+	})
+}
+
+func TestHandlePostRevokeAllSessions(t *testing.T) {
+	email := randTestEmail()
+	cookies := signUserUp(email, testPassword)
+	other := postLoginWith(createConfig(), "203.0.113.22:1000", email, testPassword).Result().Cookies()
+
+	request, _ := http.NewRequest(http.MethodPost, "/account/sessions/revoke-all", nil)
+	request.AddCookie(findCookie(cookies, "token"))
+	response := httptest.NewRecorder()
+	apiCfg := createConfig()
+	apiCfg.CheckAuthMiddleware(apiCfg.HandlePostRevokeAllSessions)(response, request)
+
+	assert.Equal(t, http.StatusFound, response.Result().StatusCode)
+
+	for _, cookie := range []*http.Cookie{
+		findCookie(cookies, "token"),
+		findCookie(cookies, "refresh_token"),
+		findCookie(other, "token"),
+		findCookie(other, "refresh_token"),
+	} {
+		response, userID := callProtected(cookie)
+		assert.Equal(t, 401, response.Result().StatusCode)
+		assert.Equal(t, -1, userID)
+	}
+}
@@ -0,0 +1,357 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/ctiller15/tailscribe/internal/database"
+)
+
+// Proficiency stages a skill moves through, in order.
+var SkillStages = []string{
+	"introduced",
+	"learning",
+	"fluent",
+	"generalized",
+	"proofed",
+}
+
+// How many recent sessions can be credited with a stage change.
+const skillSessionChoicesLimit = 20
+
+type SkillForm struct {
+	Name      string
+	Stage     string
+	SessionID string
+	Valid     bool
+	Errors    map[string]string
+}
+
+type SkillsPageData struct {
+	BasePageData
+	Pet    database.Pet
+	Skills []database.Skill
+	Stages []string
+	SkillForm
+}
+
+type SkillPageData struct {
+	BasePageData
+	Pet      database.Pet
+	Skill    database.Skill
+	History  []database.ListSkillStageChangesRow
+	Sessions []database.TrainingSession
+	Stages   []string
+	SkillForm
+}
+
+func skillFormFromRequest(r *http.Request) SkillForm {
+	return SkillForm{
+		Name:      strings.TrimSpace(r.FormValue("name")),
+		Stage:     r.FormValue("stage"),
+		SessionID: r.FormValue("session_id"),
+	}
+}
+
+func (f *SkillForm) validate() bool {
+	f.Errors = map[string]string{}
+
+	if f.Name == "" {
+		f.Errors["name"] = "Name the behavior being tracked."
+	} else if len(f.Name) > 100 {
+		f.Errors["name"] = "Skill names must be 100 characters or fewer."
+	}
+
+	if !slices.Contains(SkillStages, f.Stage) {
+		f.Errors["stage"] = "Choose a proficiency stage."
+	}
+
+	f.Valid = len(f.Errors) == 0
+	return f.Valid
+}
+
+// Resolves an optional session id from a form to a session belonging to the pet.
+// Returns sql.ErrNoRows when the session isn't the pet's.
+func (a *APIConfig) optionalSessionForPet(ctx context.Context, petID int32, raw string) (sql.NullInt32, error) {
+	if raw == "" {
+		return sql.NullInt32{}, nil
+	}
+
+	sessionID, err := strconv.ParseInt(raw, 10, 32)
+	if err != nil {
+		return sql.NullInt32{}, sql.ErrNoRows
+	}
+
+	session, err := a.Db.GetTrainingSession(ctx, database.GetTrainingSessionParams{
+		ID:    int32(sessionID),
+		PetID: petID,
+	})
+	if err != nil {
+		return sql.NullInt32{}, err
+	}
+
+	return sql.NullInt32{Int32: session.ID, Valid: true}, nil
+}
+
+func (a *APIConfig) recordSkillStageChange(
+	ctx context.Context,
+	skillID int32,
+	fromStage sql.NullString,
+	toStage string,
+	sessionID sql.NullInt32,
+	user_id int,
+) error {
+	_, err := a.Db.CreateSkillStageChange(ctx, database.CreateSkillStageChangeParams{
+		SkillID:   skillID,
+		FromStage: fromStage,
+		ToStage:   toStage,
+		SessionID: sessionID,
+		UserID:    sql.NullInt32{Int32: int32(user_id), Valid: true},
+	})
+
+	return err
+}
+
+func (a *APIConfig) renderSkills(w http.ResponseWriter, r *http.Request, status int, pet database.Pet, form SkillForm) {
+	skills, err := a.Db.ListSkillsForPet(r.Context(), pet.ID)
+	if err != nil {
+		a.serverError(w, r, "error listing skills", err)
+		return
+	}
+
+	data := SkillsPageData{
+		BasePageData: basePageData(r, fmt.Sprintf("%s - Skills", pet.Name)),
+		Pet:          pet,
+		Skills:       skills,
+		Stages:       SkillStages,
+		SkillForm:    form,
+	}
+
+	a.render(w, r, status, "skills", data)
+}
+
+func (a *APIConfig) renderSkill(w http.ResponseWriter, r *http.Request, status int, pet database.Pet, skill database.Skill, form SkillForm) {
+	ctx := r.Context()
+	history, err := a.Db.ListSkillStageChanges(ctx, skill.ID)
+	if err != nil {
+		a.serverError(w, r, "error listing skill history", err)
+		return
+	}
+
+	sessions, err := a.Db.ListRecentTrainingSessionsForPet(ctx, database.ListRecentTrainingSessionsForPetParams{
+		PetID: pet.ID,
+		Limit: skillSessionChoicesLimit,
+	})
+	if err != nil {
+		a.serverError(w, r, "error listing training sessions", err)
+		return
+	}
+
+	data := SkillPageData{
+		BasePageData: basePageData(r, fmt.Sprintf("%s - %s", pet.Name, skill.Name)),
+		Pet:          pet,
+		Skill:        skill,
+		History:      history,
+		Sessions:     sessions,
+		Stages:       SkillStages,
+		SkillForm:    form,
+	}
+
+	a.render(w, r, status, "skill", data)
+}
+
+// Resolves the {skillID} path value to a skill belonging to the pet.
+// Writes a 404 (or 500) and returns false when it can't be found.
+func (a *APIConfig) skillFromPath(w http.ResponseWriter, r *http.Request, pet database.Pet) (database.Skill, bool) {
+	skillID, err := pathID(r, "skillID")
+	if err != nil {
+		a.serveError(w, r, errNotFound)
+		return database.Skill{}, false
+	}
+
+	skill, err := a.Db.GetSkill(r.Context(), database.GetSkillParams{
+		ID:    skillID,
+		PetID: pet.ID,
+	})
+	if err != nil {
+		if isNotFound(err) {
+			a.serveError(w, r, errNotFound)
+		} else {
+			a.serverError(w, r, "error loading skill", err)
+		}
+		return database.Skill{}, false
+	}
+
+	return skill, true
+}
+
+func (a *APIConfig) HandleGetSkills(w http.ResponseWriter, r *http.Request, user_id int) {
+	pet, _, ok := a.linkedPetFromPath(w, r, user_id, PermissionsViewer)
+	if !ok {
+		return
+	}
+
+	a.renderSkills(w, r, http.StatusOK, pet, SkillForm{Stage: SkillStages[0], Valid: true})
+}
+
+func (a *APIConfig) HandlePostSkill(w http.ResponseWriter, r *http.Request, user_id int) {
+	ctx := r.Context()
+	pet, _, ok := a.linkedPetFromPath(w, r, user_id, PermissionsCoOwner)
+	if !ok {
+		return
+	}
+
+	form := skillFormFromRequest(r)
+	if !form.validate() {
+		a.renderSkills(w, r, http.StatusBadRequest, pet, form)
+		return
+	}
+
+	skill, err := a.Db.CreateSkill(ctx, database.CreateSkillParams{
+		PetID: pet.ID,
+		Name:  form.Name,
+		Stage: form.Stage,
+	})
+	if err != nil {
+		if isUniqueViolation(err) {
+			form.Valid = false
+			form.Errors["name"] = fmt.Sprintf("%s already tracks this skill.", pet.Name)
+			a.renderSkills(w, r, http.StatusBadRequest, pet, form)
+			return
+		}
+		a.serverError(w, r, "error creating skill", err)
+		return
+	}
+
+	err = a.recordSkillStageChange(ctx, skill.ID, sql.NullString{}, skill.Stage, sql.NullInt32{}, user_id)
+	if err != nil {
+		a.serverError(w, r, "error recording skill stage", err)
+		return
+	}
+
+	http.Redirect(w, r, fmt.Sprintf("/dashboard/pet/%d/skills", pet.ID), http.StatusFound)
+}
+
+func (a *APIConfig) HandleGetSkill(w http.ResponseWriter, r *http.Request, user_id int) {
+	pet, _, ok := a.linkedPetFromPath(w, r, user_id, PermissionsViewer)
+	if !ok {
+		return
+	}
+
+	skill, ok := a.skillFromPath(w, r, pet)
+	if !ok {
+		return
+	}
+
+	a.renderSkill(w, r, http.StatusOK, pet, skill, SkillForm{
+		Name:  skill.Name,
+		Stage: skill.Stage,
+		Valid: true,
+	})
+}
+
+func (a *APIConfig) HandlePostEditSkill(w http.ResponseWriter, r *http.Request, user_id int) {
+	ctx := r.Context()
+	pet, _, ok := a.linkedPetFromPath(w, r, user_id, PermissionsCoOwner)
+	if !ok {
+		return
+	}
+
+	skill, ok := a.skillFromPath(w, r, pet)
+	if !ok {
+		return
+	}
+
+	form := skillFormFromRequest(r)
+	if !form.validate() {
+		a.renderSkill(w, r, http.StatusBadRequest, pet, skill, form)
+		return
+	}
+
+	sessionID, err := a.optionalSessionForPet(ctx, pet.ID, form.SessionID)
+	if err != nil {
+		if !isNotFound(err) {
+			a.serverError(w, r, "error loading training session", err)
+			return
+		}
+		form.Valid = false
+		form.Errors["session_id"] = "Choose one of this pet's sessions."
+		a.renderSkill(w, r, http.StatusBadRequest, pet, skill, form)
+		return
+	}
+
+	updated, err := a.Db.UpdateSkill(ctx, database.UpdateSkillParams{
+		ID:    skill.ID,
+		PetID: pet.ID,
+		Name:  form.Name,
+		Stage: form.Stage,
+	})
+	if err != nil {
+		if isUniqueViolation(err) {
+			form.Valid = false
+			form.Errors["name"] = fmt.Sprintf("%s already tracks this skill.", pet.Name)
+			a.renderSkill(w, r, http.StatusBadRequest, pet, skill, form)
+			return
+		}
+		a.serverError(w, r, "error updating skill", err)
+		return
+	}
+
+	if updated.Stage != skill.Stage {
+		fromStage := sql.NullString{String: skill.Stage, Valid: true}
+		err = a.recordSkillStageChange(ctx, skill.ID, fromStage, updated.Stage, sessionID, user_id)
+		if err != nil {
+			a.serverError(w, r, "error recording skill stage", err)
+			return
+		}
+	}
+
+	http.Redirect(w, r, fmt.Sprintf("/dashboard/pet/%d/skills/%d", pet.ID, skill.ID), http.StatusFound)
+}
+
+func (a *APIConfig) HandlePostDeleteSkill(w http.ResponseWriter, r *http.Request, user_id int) {
+	pet, _, ok := a.linkedPetFromPath(w, r, user_id, PermissionsCoOwner)
+	if !ok {
+		return
+	}
+
+	skill, ok := a.skillFromPath(w, r, pet)
+	if !ok {
+		return
+	}
+
+	err := a.Db.DeleteSkill(r.Context(), database.DeleteSkillParams{
+		ID:    skill.ID,
+		PetID: pet.ID,
+	})
+	if err != nil {
+		a.serverError(w, r, "error deleting skill", err)
+		return
+	}
+
+	http.Redirect(w, r, fmt.Sprintf("/dashboard/pet/%d/skills", pet.ID), http.StatusFound)
+}
+
+// Toggles whether skills show on the pet's public profile.
+func (a *APIConfig) HandlePostSkillsVisibility(w http.ResponseWriter, r *http.Request, user_id int) {
+	pet, _, ok := a.linkedPetFromPath(w, r, user_id, PermissionsCoOwner)
+	if !ok {
+		return
+	}
+
+	err := a.Db.UpdatePetSkillsHidden(r.Context(), database.UpdatePetSkillsHiddenParams{
+		ID:           pet.ID,
+		Skillshidden: r.FormValue("skills_hidden") == "on",
+	})
+	if err != nil {
+		a.serverError(w, r, "error updating skills visibility", err)
+		return
+	}
+
+	http.Redirect(w, r, fmt.Sprintf("/dashboard/pet/%d/skills", pet.ID), http.StatusFound)
+}
@@ -0,0 +1,119 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func postSkill(authCookie *http.Cookie, petID int, formData url.Values) *http.Response {
+	request, _ := http.NewRequest(http.MethodPost, fmt.Sprintf("/dashboard/pet/%d/skills", petID), strings.NewReader(formData.Encode()))
+	request.Header.Add("Content-Type", "application/x-www-form-urlencoded")
+	request.SetPathValue("id", strconv.Itoa(petID))
+	request.AddCookie(authCookie)
+	response := httptest.NewRecorder()
+
+	apiCfg := createConfig()
+	apiCfg.CheckAuthMiddleware(apiCfg.HandlePostSkill)(response, request)
+
+	return response.Result()
+}
+
+func TestSkillFormValidate(t *testing.T) {
+	t.Run("Accepts a known stage", func(t *testing.T) {
+		form := SkillForm{Name: "recall", Stage: "generalized"}
+		assert.True(t, form.validate())
+	})
+
+	t.Run("Rejects an unknown stage", func(t *testing.T) {
+		form := SkillForm{Name: "recall", Stage: "mastered"}
+		assert.False(t, form.validate())
+		assert.Contains(t, form.Errors, "stage")
+	})
+}
+
+func TestHandlePostSkill(t *testing.T) {
+	t.Run("Tracks a new skill and records its starting stage", func(t *testing.T) {
+		cookies := signUserUp(randTestEmail(), testPassword)
+		petID := createPetForUser(t, cookies[0], "Pepper")
+
+		result := postSkill(cookies[0], petID, url.Values{
+			"name":  {"down stay"},
+			"stage": {"learning"},
+		})
+		assert.Equal(t, 302, result.StatusCode)
+
+		apiCfg := createConfig()
+		skills, err := apiCfg.Db.ListSkillsForPet(t.Context(), int32(petID))
+		assert.NoError(t, err)
+		assert.Len(t, skills, 1)
+		assert.Equal(t, "learning", skills[0].Stage)
+
+		history, err := apiCfg.Db.ListSkillStageChanges(t.Context(), skills[0].ID)
+		assert.NoError(t, err)
+		assert.Len(t, history, 1)
+		assert.False(t, history[0].FromStage.Valid)
+	})
+
+	t.Run("Rejects a duplicate skill", func(t *testing.T) {
+		cookies := signUserUp(randTestEmail(), testPassword)
+		petID := createPetForUser(t, cookies[0], "Pepper")
+
+		formData := url.Values{
+			"name":  {"heel"},
+			"stage": {"introduced"},
+		}
+		postSkill(cookies[0], petID, formData)
+		result := postSkill(cookies[0], petID, formData)
+
+		assert.Equal(t, 400, result.StatusCode)
+	})
+}
+
+func TestHandlePostEditSkill(t *testing.T) {
+	t.Run("Records the session that moved the stage", func(t *testing.T) {
+		cookies := signUserUp(randTestEmail(), testPassword)
+		petID := createPetForUser(t, cookies[0], "Ziggy")
+
+		postSkill(cookies[0], petID, url.Values{
+			"name":  {"recall"},
+			"stage": {"learning"},
+		})
+		postTrainingSession(cookies[0], petID, url.Values{
+			"started_at": {"2025-05-01T07:45"},
+		})
+
+		apiCfg := createConfig()
+		skills, _ := apiCfg.Db.ListSkillsForPet(t.Context(), int32(petID))
+		sessions, _ := apiCfg.Db.ListTrainingSessionsForPet(t.Context(), int32(petID))
+
+		formData := url.Values{
+			"name":       {"recall"},
+			"stage":      {"fluent"},
+			"session_id": {strconv.Itoa(int(sessions[0].ID))},
+		}
+		target := fmt.Sprintf("/dashboard/pet/%d/skills/%d", petID, skills[0].ID)
+		request, _ := http.NewRequest(http.MethodPost, target, strings.NewReader(formData.Encode()))
+		request.Header.Add("Content-Type", "application/x-www-form-urlencoded")
+		request.SetPathValue("id", strconv.Itoa(petID))
+		request.SetPathValue("skillID", strconv.Itoa(int(skills[0].ID)))
+		request.AddCookie(cookies[0])
+		response := httptest.NewRecorder()
+		apiCfg.CheckAuthMiddleware(apiCfg.HandlePostEditSkill)(response, request)
+
+		assert.Equal(t, 302, response.Result().StatusCode)
+
+		history, err := apiCfg.Db.ListSkillStageChanges(t.Context(), skills[0].ID)
+		assert.NoError(t, err)
+		assert.Len(t, history, 2)
+		assert.Equal(t, "fluent", history[0].ToStage)
+		assert.Equal(t, "learning", history[0].FromStage.String)
+		assert.Equal(t, sessions[0].ID, history[0].SessionID.Int32)
+	})
+}
@@ -0,0 +1,378 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ctiller15/tailscribe/internal/auth"
+	"github.com/ctiller15/tailscribe/internal/database"
+	"github.com/ctiller15/tailscribe/internal/oauth"
+)
+
+const oauthStateCookie = "oauth_state"
+
+// How long a user has to finish signing in at the provider.
+const oauthStateTTL = 10 * time.Minute
+
+// What a sign-in started, carried through the provider in a signed cookie.
+type oauthState struct {
+	Provider string
+	// "login" or "link".
+	Mode string
+	// Who is linking. Zero when logging in.
+	UserID int
+	State  string
+	Nonce  string
+}
+
+type ContinuePageData struct {
+	BasePageData
+	Location string
+}
+
+func (s oauthState) encode() string {
+	return strings.Join([]string{s.Provider, s.Mode, strconv.Itoa(s.UserID), s.State, s.Nonce}, "|")
+}
+
+func decodeOAuthState(value string) (oauthState, bool) {
+	parts := strings.Split(value, "|")
+	if len(parts) != 5 {
+		return oauthState{}, false
+	}
+
+	userID, err := strconv.Atoi(parts[2])
+	if err != nil {
+		return oauthState{}, false
+	}
+
+	return oauthState{
+		Provider: parts[0],
+		Mode:     parts[1],
+		UserID:   userID,
+		State:    parts[3],
+		Nonce:    parts[4],
+	}, true
+}
+
+// Providers only accept the redirect_uri registered with them, so it comes
+// from BASE_URL rather than the request, which behind a proxy looks like plain http.
+func (a *APIConfig) oauthCallbackURL(provider oauth.Provider) string {
+	return a.siteURL(fmt.Sprintf("/auth/%s/callback", provider.Name()))
+}
+
+func (a *APIConfig) oauthStateFromCookie(r *http.Request) (oauthState, bool) {
+	cookie, err := r.Cookie(oauthStateCookie)
+	if err != nil {
+		return oauthState{}, false
+	}
+
+	value, ok := auth.VerifySignedValue(cookie.Value, a.Env.Secret)
+	if !ok {
+		return oauthState{}, false
+	}
+
+	return decodeOAuthState(value)
+}
+
+func (a *APIConfig) providerFromPath(w http.ResponseWriter, r *http.Request) (oauth.Provider, bool) {
+	provider, ok := a.Providers[r.PathValue("provider")]
+	if !ok {
+		a.serveError(w, r, errNotFound)
+		return nil, false
+	}
+
+	return provider, true
+}
+
+// Finds the user a provider's subject is linked to.
+func (a *APIConfig) userForIdentity(ctx context.Context, provider oauth.Provider, subject string) (database.User, error) {
+	switch p := provider.(type) {
+	case *oauth.Facebook:
+		return a.Db.GetUserByFacebookID(ctx, nullString(subject))
+	case *oauth.OIDC:
+		return a.Db.GetUserByOIDCIdentity(ctx, database.GetUserByOIDCIdentityParams{
+			OidcIssuer:  nullString(p.Issuer),
+			OidcSubject: nullString(subject),
+		})
+	default:
+		return database.User{}, fmt.Errorf("unsupported provider %q", provider.Name())
+	}
+}
+
+// Links a provider's subject to the user, or unlinks it when subject is empty.
+func setIdentity(ctx context.Context, q *database.Queries, userID int32, provider oauth.Provider, subject string) error {
+	switch p := provider.(type) {
+	case *oauth.Facebook:
+		return q.UpdateUserFacebookID(ctx, database.UpdateUserFacebookIDParams{
+			ID:         userID,
+			FacebookID: nullString(subject),
+		})
+	case *oauth.OIDC:
+		issuer := p.Issuer
+		if subject == "" {
+			issuer = ""
+		}
+		return q.UpdateUserOIDCIdentity(ctx, database.UpdateUserOIDCIdentityParams{
+			ID:          userID,
+			OidcIssuer:  nullString(issuer),
+			OidcSubject: nullString(subject),
+		})
+	default:
+		return fmt.Errorf("unsupported provider %q", provider.Name())
+	}
+}
+
+// Session cookies are SameSite=Strict, so the browser won't send them on a
+// redirect chain that began at the provider. This page finishes the hop with
+// a same-site navigation instead.
+func (a *APIConfig) renderContinue(w http.ResponseWriter, r *http.Request, location string) {
+	data := ContinuePageData{
+		BasePageData: basePageData(r, "TailScribe - Signing In"),
+		Location:     location,
+	}
+
+	a.render(w, r, http.StatusOK, "continue", data)
+}
+
+func (a *APIConfig) startOAuth(w http.ResponseWriter, r *http.Request, provider oauth.Provider, mode string, userID int) {
+	state, err := oauth.RandomString()
+	if err != nil {
+		a.serverError(w, r, "error creating oauth state", err)
+		return
+	}
+
+	nonce, err := oauth.RandomString()
+	if err != nil {
+		a.serverError(w, r, "error creating oauth nonce", err)
+		return
+	}
+
+	authCodeURL, err := provider.AuthCodeURL(r.Context(), state, nonce, a.oauthCallbackURL(provider))
+	if err != nil {
+		a.Logger.Error("error starting oauth", slog.String("provider", provider.Name()), slog.String("error", err.Error()))
+		a.renderLogin(w, r, http.StatusBadGateway, LoginPageData{
+			Error: fmt.Sprintf("We couldn't reach %s. Try again in a little while.", provider.DisplayName()),
+		})
+		return
+	}
+
+	cookieState := oauthState{
+		Provider: provider.Name(),
+		Mode:     mode,
+		UserID:   userID,
+		State:    state,
+		Nonce:    nonce,
+	}
+
+	// Lax rather than Strict, because the provider's redirect back is cross-site.
+	http.SetCookie(w, &http.Cookie{
+		Name:     oauthStateCookie,
+		Value:    auth.SignValue(cookieState.encode(), a.Env.Secret),
+		Path:     "/auth/",
+		MaxAge:   int(oauthStateTTL.Seconds()),
+		HttpOnly: true,
+		Secure:   true,
+		SameSite: http.SameSiteLaxMode,
+	})
+
+	http.Redirect(w, r, authCodeURL, http.StatusFound)
+}
+
+func (a *APIConfig) HandleGetOAuthLogin(w http.ResponseWriter, r *http.Request) {
+	provider, ok := a.providerFromPath(w, r)
+	if !ok {
+		return
+	}
+
+	a.startOAuth(w, r, provider, "login", 0)
+}
+
+func (a *APIConfig) HandleGetOAuthLink(w http.ResponseWriter, r *http.Request, user_id int) {
+	provider, ok := a.providerFromPath(w, r)
+	if !ok {
+		return
+	}
+
+	a.startOAuth(w, r, provider, "link", user_id)
+}
+
+func (a *APIConfig) HandleGetOAuthCallback(w http.ResponseWriter, r *http.Request) {
+	provider, ok := a.providerFromPath(w, r)
+	if !ok {
+		return
+	}
+
+	state, ok := a.oauthStateFromCookie(r)
+
+	// The state only works once.
+	http.SetCookie(w, &http.Cookie{
+		Name:     oauthStateCookie,
+		Value:    "",
+		Path:     "/auth/",
+		MaxAge:   -1,
+		HttpOnly: true,
+	})
+
+	if !ok || state.Provider != provider.Name() || state.State == "" || r.FormValue("state") != state.State {
+		a.renderLogin(w, r, http.StatusBadRequest, LoginPageData{
+			Error: "That sign-in has expired. Please try again.",
+		})
+		return
+	}
+
+	// The user cancelled at the provider.
+	if r.FormValue("error") != "" {
+		if state.Mode == "link" {
+			http.Redirect(w, r, "/account", http.StatusFound)
+		} else {
+			http.Redirect(w, r, "/login", http.StatusFound)
+		}
+		return
+	}
+
+	identity, err := provider.Exchange(r.Context(), r.FormValue("code"), state.Nonce, a.oauthCallbackURL(provider))
+	if err != nil {
+		a.Logger.Error("error finishing oauth", slog.String("provider", provider.Name()), slog.String("error", err.Error()))
+		a.renderLogin(w, r, http.StatusBadGateway, LoginPageData{
+			Error: fmt.Sprintf("We couldn't sign you in with %s. Please try again.", provider.DisplayName()),
+		})
+		return
+	}
+
+	if state.Mode == "link" {
+		a.finishOAuthLink(w, r, provider, identity, state.UserID)
+		return
+	}
+
+	a.finishOAuthLogin(w, r, provider, identity)
+}
+
+// Logs in the user linked to the identity, or signs up a new user without a password.
+func (a *APIConfig) finishOAuthLogin(w http.ResponseWriter, r *http.Request, provider oauth.Provider, identity oauth.Identity) {
+	ctx := r.Context()
+
+	user, err := a.userForIdentity(ctx, provider, identity.Subject)
+	if err != nil && !isNotFound(err) {
+		a.serverError(w, r, "error loading user for identity", err)
+		return
+	}
+
+	if isNotFound(err) {
+		// An address the provider hasn't checked is kept, but has to be verified
+		// like a password signup's before it counts for anything.
+		email := identity.Email
+		verified := email != "" && identity.EmailVerified
+
+		// An existing account is never linked automatically. Its owner has to
+		// log in first, or anyone controlling a matching provider account could take it over.
+		emailTaken := LoginPageData{
+			Error: fmt.Sprintf("%s already has a TailScribe account. Log in with your password, then connect %s from your account page.",
+				email, provider.DisplayName()),
+		}
+
+		if email != "" {
+			_, err = a.Db.GetUserByEmail(ctx, email)
+			if err == nil {
+				a.renderLogin(w, r, http.StatusConflict, emailTaken)
+				return
+			}
+			if !isNotFound(err) {
+				a.serverError(w, r, "error loading user", err)
+				return
+			}
+
+			problem, err := a.deletedAccountProblem(ctx, email)
+			if err != nil {
+				a.serverError(w, r, "error loading deleted user", err)
+				return
+			}
+			if problem != "" {
+				a.renderLogin(w, r, http.StatusConflict, LoginPageData{Error: problem})
+				return
+			}
+		}
+
+		// Both or neither, so a failed link can't leave behind an account nobody can log in to.
+		var linkErr error
+		err = a.inTx(ctx, func(q *database.Queries) error {
+			user, err = q.CreateUser(ctx, database.CreateUserParams{
+				Email:           nullString(email),
+				Password:        sql.NullString{},
+				EmailVerifiedAt: sql.NullTime{Time: time.Now(), Valid: verified},
+			})
+			if err != nil {
+				return err
+			}
+
+			linkErr = setIdentity(ctx, q, user.ID, provider, identity.Subject)
+			return linkErr
+		})
+		switch {
+		case err == nil:
+		case isUniqueViolation(linkErr):
+			// userForIdentity skips deleted users, so one of them still holds this identity.
+			a.renderLogin(w, r, http.StatusConflict, LoginPageData{
+				Error: fmt.Sprintf("That %s account belongs to a TailScribe account that was recently deleted.", provider.DisplayName()),
+			})
+			return
+		case isUniqueViolation(err):
+			a.renderLogin(w, r, http.StatusConflict, emailTaken)
+			return
+		default:
+			a.serverError(w, r, "error creating user", err)
+			return
+		}
+
+		// As with a password signup, a mail problem shouldn't stop the login.
+		if email != "" && !verified {
+			err = a.sendVerificationEmail(ctx, user)
+			if err != nil {
+				a.Logger.Error("error sending verification email", slog.String("error", err.Error()))
+			}
+		}
+	}
+
+	// The provider vouches for the first factor only.
+	if user.TotpEnabledAt.Valid {
+		a.beginTwoFactorLogin(&w, user)
+		a.renderContinue(w, r, "/login/two_factor")
+		return
+	}
+
+	err = a.createAndAttachSessionCookies(&w, r, user)
+	if err != nil {
+		a.serverError(w, r, "error creating session cookies", err)
+		return
+	}
+
+	a.renderContinue(w, r, "/dashboard")
+}
+
+func (a *APIConfig) finishOAuthLink(w http.ResponseWriter, r *http.Request, provider oauth.Provider, identity oauth.Identity, userID int) {
+	ctx := r.Context()
+
+	existing, err := a.userForIdentity(ctx, provider, identity.Subject)
+	if err == nil && existing.ID != int32(userID) {
+		a.renderAccount(w, r, http.StatusConflict, userID,
+			fmt.Sprintf("That %s account is already connected to another TailScribe account.", provider.DisplayName()))
+		return
+	}
+	if err != nil && !isNotFound(err) {
+		a.serverError(w, r, "error loading user for identity", err)
+		return
+	}
+
+	err = setIdentity(ctx, &a.Db, int32(userID), provider, identity.Subject)
+	if err != nil {
+		a.serverError(w, r, "error linking identity", err)
+		return
+	}
+
+	a.renderContinue(w, r, "/account")
+}
@@ -0,0 +1,235 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"testing"
+
+	"github.com/ctiller15/tailscribe/internal/mailer"
+	"github.com/ctiller15/tailscribe/internal/oauth"
+	"github.com/ctiller15/tailscribe/internal/oauth/oauthtest"
+	"github.com/stretchr/testify/assert"
+)
+
+func createSocialConfig(server *oauthtest.Server) *APIConfig {
+	apiCfg := createConfig()
+	apiCfg.Providers = map[string]oauth.Provider{
+		"facebook": server.Facebook(),
+		"oidc":     server.OIDC(),
+	}
+	return apiCfg
+}
+
+// Runs a social login (or, given session cookies, a link) through the mock
+// provider, returning the callback's response.
+func socialSignIn(t *testing.T, server *oauthtest.Server, providerName string, sessionCookies ...*http.Cookie) *httptest.ResponseRecorder {
+	return socialSignInWith(t, createSocialConfig(server), server, providerName, sessionCookies...)
+}
+
+func socialSignInWith(t *testing.T, apiCfg *APIConfig, server *oauthtest.Server, providerName string, sessionCookies ...*http.Cookie) *httptest.ResponseRecorder {
+	mode := "login"
+	if len(sessionCookies) > 0 {
+		mode = "link"
+	}
+
+	request, _ := http.NewRequest(http.MethodGet, "http://tailscribe.test/auth/"+providerName+"/"+mode, nil)
+	request.SetPathValue("provider", providerName)
+	for _, cookie := range sessionCookies {
+		request.AddCookie(cookie)
+	}
+	response := httptest.NewRecorder()
+
+	if mode == "link" {
+		apiCfg.CheckAuthMiddleware(apiCfg.HandleGetOAuthLink)(response, request)
+	} else {
+		apiCfg.HandleGetOAuthLogin(response, request)
+	}
+	assert.Equal(t, http.StatusFound, response.Result().StatusCode)
+
+	// The request came in on another host, but providers get the registered callback.
+	authorizeURL, err := url.Parse(response.Result().Header.Get("Location"))
+	assert.NoError(t, err)
+	assert.Equal(t, TestEnvVars.BaseURL+"/auth/"+providerName+"/callback", authorizeURL.Query().Get("redirect_uri"))
+
+	callback, err := server.Authorize(response.Result().Header.Get("Location"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	callbackRequest, _ := http.NewRequest(http.MethodGet, callback.String(), nil)
+	callbackRequest.SetPathValue("provider", providerName)
+	callbackRequest.AddCookie(findCookie(response.Result().Cookies(), oauthStateCookie))
+	callbackResponse := httptest.NewRecorder()
+	apiCfg.HandleGetOAuthCallback(callbackResponse, callbackRequest)
+
+	return callbackResponse
+}
+
+// The id of the user a response's session cookies belong to.
+func sessionUserID(response *httptest.ResponseRecorder) int {
+	_, userID := callProtected(findCookie(response.Result().Cookies(), "token"))
+	return userID
+}
+
+func TestSocialLogin(t *testing.T) {
+	server := oauthtest.NewServer("client", "secret")
+	defer server.Close()
+
+	t.Run("Signs up a new user without a password and logs them back in", func(t *testing.T) {
+		server.SetUser(oauth.Identity{Subject: randStringBytes(12), Email: randTestEmail(), EmailVerified: true})
+
+		first := socialSignIn(t, server, "oidc")
+		assert.Equal(t, 200, first.Result().StatusCode)
+		assert.Contains(t, first.Body.String(), "/dashboard")
+		userID := sessionUserID(first)
+		assert.NotEqual(t, -1, userID)
+
+		user, err := DbQueries.GetUser(t.Context(), int32(userID))
+		assert.NoError(t, err)
+		assert.False(t, user.Password.Valid)
+
+		second := socialSignIn(t, server, "oidc")
+		assert.Equal(t, userID, sessionUserID(second))
+	})
+
+	t.Run("Asks a Facebook signup to verify its email", func(t *testing.T) {
+		dir := t.TempDir()
+		apiCfg := createSocialConfig(server)
+		apiCfg.Mailer = &mailer.FileMailer{Dir: dir}
+		email := randTestEmail()
+		server.SetUser(oauth.Identity{Subject: randStringBytes(12), Email: email})
+
+		response := socialSignInWith(t, apiCfg, server, "facebook")
+		assert.Equal(t, 200, response.Result().StatusCode)
+
+		user, err := DbQueries.GetUser(t.Context(), int32(sessionUserID(response)))
+		assert.NoError(t, err)
+		assert.Equal(t, email, user.Email.String)
+		assert.False(t, user.EmailVerifiedAt.Valid)
+
+		files, _ := filepath.Glob(filepath.Join(dir, "*.eml"))
+		assert.Len(t, files, 1)
+	})
+
+	t.Run("Won't sign up over an existing account's email", func(t *testing.T) {
+		email := randTestEmail()
+		signUserUp(email, testPassword)
+		server.SetUser(oauth.Identity{Subject: randStringBytes(12), Email: email, EmailVerified: true})
+
+		response := socialSignIn(t, server, "facebook")
+
+		assert.Equal(t, http.StatusConflict, response.Result().StatusCode)
+		assert.Nil(t, findCookie(response.Result().Cookies(), "token"))
+	})
+
+	t.Run("Signs a deleted account's identity up afresh", func(t *testing.T) {
+		server.SetUser(oauth.Identity{Subject: randStringBytes(12)})
+		deletedID := sessionUserID(socialSignIn(t, server, "oidc"))
+		assert.NoError(t, DbQueries.SoftDeleteUser(t.Context(), int32(deletedID)))
+
+		response := socialSignIn(t, server, "oidc")
+
+		assert.Equal(t, 200, response.Result().StatusCode)
+		userID := sessionUserID(response)
+		assert.NotEqual(t, -1, userID)
+		assert.NotEqual(t, deletedID, userID)
+	})
+
+	t.Run("Explains when the email belongs to a deleted account", func(t *testing.T) {
+		email := randTestEmail()
+		server.SetUser(oauth.Identity{Subject: randStringBytes(12), Email: email, EmailVerified: true})
+		userID := sessionUserID(socialSignIn(t, server, "oidc"))
+		assert.NoError(t, DbQueries.SoftDeleteUser(t.Context(), int32(userID)))
+
+		response := socialSignIn(t, server, "oidc")
+
+		assert.Equal(t, http.StatusConflict, response.Result().StatusCode)
+		assert.Contains(t, response.Body.String(), "was deleted, and is kept until")
+	})
+
+	t.Run("Links a provider to a password user", func(t *testing.T) {
+		cookies := signUserUp(randTestEmail(), testPassword)
+		_, userID := callProtected(findCookie(cookies, "token"))
+		server.SetUser(oauth.Identity{Subject: randStringBytes(12), Email: randTestEmail(), EmailVerified: true})
+
+		response := socialSignIn(t, server, "facebook", findCookie(cookies, "token"))
+		assert.Equal(t, 200, response.Result().StatusCode)
+		assert.Contains(t, response.Body.String(), "/account")
+
+		response = socialSignIn(t, server, "facebook")
+		assert.Equal(t, userID, sessionUserID(response))
+	})
+
+	t.Run("Won't link an identity that belongs to someone else", func(t *testing.T) {
+		server.SetUser(oauth.Identity{Subject: randStringBytes(12)})
+		socialSignIn(t, server, "oidc")
+
+		cookies := signUserUp(randTestEmail(), testPassword)
+		response := socialSignIn(t, server, "oidc", findCookie(cookies, "token"))
+
+		assert.Equal(t, http.StatusConflict, response.Result().StatusCode)
+	})
+
+	t.Run("Rejects a callback without the state cookie", func(t *testing.T) {
+		apiCfg := createSocialConfig(server)
+		request, _ := http.NewRequest(http.MethodGet, "http://tailscribe.test/auth/oidc/callback?code=abc&state=def", nil)
+		request.SetPathValue("provider", "oidc")
+		response := httptest.NewRecorder()
+
+		apiCfg.HandleGetOAuthCallback(response, request)
+
+		assert.Equal(t, http.StatusBadRequest, response.Result().StatusCode)
+	})
+
+	t.Run("Returns 404 for an unknown provider", func(t *testing.T) {
+		apiCfg := createSocialConfig(server)
+		request, _ := http.NewRequest(http.MethodGet, "/auth/myspace/login", nil)
+		request.SetPathValue("provider", "myspace")
+		response := httptest.NewRecorder()
+
+		apiCfg.HandleGetOAuthLogin(response, request)
+
+		assert.Equal(t, 404, response.Result().StatusCode)
+	})
+}
+
+func TestHandlePostUnlinkProvider(t *testing.T) {
+	server := oauthtest.NewServer("client", "secret")
+	defer server.Close()
+
+	unlink := func(cookie *http.Cookie, providerName string) *httptest.ResponseRecorder {
+		apiCfg := createSocialConfig(server)
+		request, _ := http.NewRequest(http.MethodPost, "/account/connections/"+providerName+"/unlink", nil)
+		request.SetPathValue("provider", providerName)
+		request.AddCookie(cookie)
+		response := httptest.NewRecorder()
+		apiCfg.CheckAuthMiddleware(apiCfg.HandlePostUnlinkProvider)(response, request)
+		return response
+	}
+
+	t.Run("Won't unlink a passwordless user's only login", func(t *testing.T) {
+		server.SetUser(oauth.Identity{Subject: randStringBytes(12)})
+		login := socialSignIn(t, server, "oidc")
+
+		response := unlink(findCookie(login.Result().Cookies(), "token"), "oidc")
+
+		assert.Equal(t, http.StatusBadRequest, response.Result().StatusCode)
+	})
+
+	t.Run("Unlinks a provider from a password user", func(t *testing.T) {
+		cookies := signUserUp(randTestEmail(), testPassword)
+		subject := randStringBytes(12)
+		server.SetUser(oauth.Identity{Subject: subject})
+		socialSignIn(t, server, "facebook", findCookie(cookies, "token"))
+
+		response := unlink(findCookie(cookies, "token"), "facebook")
+		assert.Equal(t, http.StatusFound, response.Result().StatusCode)
+
+		// The identity is free again, so logging in with it signs up someone new.
+		_, userID := callProtected(findCookie(cookies, "token"))
+		login := socialSignIn(t, server, "facebook")
+		assert.NotEqual(t, userID, sessionUserID(login))
+	})
+}
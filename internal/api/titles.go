@@ -0,0 +1,318 @@
+package api
+
+import (
+	"database/sql"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/ctiller15/tailscribe/internal/database"
+)
+
+// Where a title's abbreviation goes relative to the pet's name.
+const (
+	TitlePrefix = "prefix"
+	TitleSuffix = "suffix"
+)
+
+type TitleForm struct {
+	Name                string
+	Abbreviation        string
+	Position            string
+	Organization        string
+	EarnedOn            string
+	CertificateImageUrl string
+	Valid               bool
+	Errors              map[string]string
+}
+
+type TitlesPageData struct {
+	BasePageData
+	Pet         database.Pet
+	DisplayName string
+	Titles      []database.Title
+	TitleForm
+}
+
+type TitlePageData struct {
+	BasePageData
+	Pet      database.Pet
+	PetTitle database.Title
+	TitleForm
+}
+
+// Validated values from a TitleForm.
+type titleInput struct {
+	Abbreviation        sql.NullString
+	Organization        sql.NullString
+	EarnedOn            time.Time
+	CertificateImageUrl sql.NullString
+}
+
+// Builds a pet's name with its title abbreviations around it, e.g. "CH Rex CGC TKN".
+// Titles are expected in the order they were earned.
+func PetDisplayName(name string, titles []database.Title) string {
+	prefixes := []string{}
+	suffixes := []string{}
+	for _, title := range titles {
+		if !title.Abbreviation.Valid {
+			continue
+		}
+		if title.Position == TitlePrefix {
+			prefixes = append(prefixes, title.Abbreviation.String)
+		} else {
+			suffixes = append(suffixes, title.Abbreviation.String)
+		}
+	}
+
+	parts := append(prefixes, name)
+	parts = append(parts, suffixes...)
+	return strings.Join(parts, " ")
+}
+
+func titleFormFromRequest(r *http.Request) TitleForm {
+	return TitleForm{
+		Name:                strings.TrimSpace(r.FormValue("name")),
+		Abbreviation:        strings.TrimSpace(r.FormValue("abbreviation")),
+		Position:            r.FormValue("position"),
+		Organization:        strings.TrimSpace(r.FormValue("organization")),
+		EarnedOn:            strings.TrimSpace(r.FormValue("earned_on")),
+		CertificateImageUrl: strings.TrimSpace(r.FormValue("certificate_image_url")),
+	}
+}
+
+func titleFormFromTitle(title database.Title) TitleForm {
+	return TitleForm{
+		Name:                title.Name,
+		Abbreviation:        title.Abbreviation.String,
+		Position:            title.Position,
+		Organization:        title.Organization.String,
+		EarnedOn:            title.EarnedOn.Format(dateInputLayout),
+		CertificateImageUrl: title.CertificateImageUrl.String,
+		Valid:               true,
+	}
+}
+
+func (f *TitleForm) validate() (titleInput, bool) {
+	input := titleInput{}
+	f.Errors = map[string]string{}
+
+	if f.Name == "" {
+		f.Errors["name"] = "Enter the title's name."
+	}
+
+	if strings.ContainsAny(f.Abbreviation, " \t") {
+		f.Errors["abbreviation"] = "Abbreviations can't contain spaces."
+	}
+
+	if f.Position != TitlePrefix && f.Position != TitleSuffix {
+		f.Errors["position"] = "Choose whether the title goes before or after the name."
+	}
+
+	earnedOn, err := time.Parse(dateInputLayout, f.EarnedOn)
+	if err != nil {
+		f.Errors["earned_on"] = "Enter the date the title was earned."
+	}
+	input.EarnedOn = earnedOn
+
+	if f.CertificateImageUrl != "" {
+		certificateUrl, err := url.Parse(f.CertificateImageUrl)
+		if err != nil || (certificateUrl.Scheme != "https" && certificateUrl.Scheme != "http") {
+			f.Errors["certificate_image_url"] = "Certificate images must be a web address."
+		}
+	}
+
+	input.Abbreviation = sql.NullString{String: f.Abbreviation, Valid: f.Abbreviation != ""}
+	input.Organization = sql.NullString{String: f.Organization, Valid: f.Organization != ""}
+	input.CertificateImageUrl = sql.NullString{String: f.CertificateImageUrl, Valid: f.CertificateImageUrl != ""}
+
+	f.Valid = len(f.Errors) == 0
+	return input, f.Valid
+}
+
+func (a *APIConfig) renderTitles(w http.ResponseWriter, r *http.Request, status int, pet database.Pet, form TitleForm) {
+	titles, err := a.Db.ListTitlesForPet(r.Context(), pet.ID)
+	if err != nil {
+		a.serverError(w, r, "error listing titles", err)
+		return
+	}
+
+	data := TitlesPageData{
+		BasePageData: basePageData(r, fmt.Sprintf("%s - Titles", pet.Name)),
+		Pet:          pet,
+		DisplayName:  PetDisplayName(pet.Name, titles),
+		Titles:       titles,
+		TitleForm:    form,
+	}
+
+	a.render(w, r, status, "titles", data)
+}
+
+func (a *APIConfig) renderTitle(w http.ResponseWriter, r *http.Request, status int, pet database.Pet, title database.Title, form TitleForm) {
+	data := TitlePageData{
+		BasePageData: basePageData(r, fmt.Sprintf("%s - %s", pet.Name, title.Name)),
+		Pet:          pet,
+		PetTitle:     title,
+		TitleForm:    form,
+	}
+
+	a.render(w, r, status, "title", data)
+}
+
+// Resolves the {titleID} path value to a title belonging to the pet.
+// Writes a 404 (or 500) and returns false when it can't be found.
+func (a *APIConfig) titleFromPath(w http.ResponseWriter, r *http.Request, pet database.Pet) (database.Title, bool) {
+	titleID, err := pathID(r, "titleID")
+	if err != nil {
+		a.serveError(w, r, errNotFound)
+		return database.Title{}, false
+	}
+
+	title, err := a.Db.GetTitle(r.Context(), database.GetTitleParams{
+		ID:    titleID,
+		PetID: pet.ID,
+	})
+	if err != nil {
+		if isNotFound(err) {
+			a.serveError(w, r, errNotFound)
+		} else {
+			a.serverError(w, r, "error loading title", err)
+		}
+		return database.Title{}, false
+	}
+
+	return title, true
+}
+
+func (a *APIConfig) HandleGetTitles(w http.ResponseWriter, r *http.Request, user_id int) {
+	pet, _, ok := a.linkedPetFromPath(w, r, user_id, PermissionsViewer)
+	if !ok {
+		return
+	}
+
+	a.renderTitles(w, r, http.StatusOK, pet, TitleForm{Position: TitleSuffix, Valid: true})
+}
+
+func (a *APIConfig) HandlePostTitle(w http.ResponseWriter, r *http.Request, user_id int) {
+	pet, _, ok := a.linkedPetFromPath(w, r, user_id, PermissionsCoOwner)
+	if !ok {
+		return
+	}
+
+	form := titleFormFromRequest(r)
+	input, valid := form.validate()
+	if !valid {
+		a.renderTitles(w, r, http.StatusBadRequest, pet, form)
+		return
+	}
+
+	_, err := a.Db.CreateTitle(r.Context(), database.CreateTitleParams{
+		PetID:               pet.ID,
+		Name:                form.Name,
+		Abbreviation:        input.Abbreviation,
+		Position:            form.Position,
+		Organization:        input.Organization,
+		EarnedOn:            input.EarnedOn,
+		CertificateImageUrl: input.CertificateImageUrl,
+	})
+	if err != nil {
+		a.serverError(w, r, "error creating title", err)
+		return
+	}
+
+	http.Redirect(w, r, fmt.Sprintf("/dashboard/pet/%d/titles", pet.ID), http.StatusFound)
+}
+
+func (a *APIConfig) HandleGetTitle(w http.ResponseWriter, r *http.Request, user_id int) {
+	pet, _, ok := a.linkedPetFromPath(w, r, user_id, PermissionsViewer)
+	if !ok {
+		return
+	}
+
+	title, ok := a.titleFromPath(w, r, pet)
+	if !ok {
+		return
+	}
+
+	a.renderTitle(w, r, http.StatusOK, pet, title, titleFormFromTitle(title))
+}
+
+func (a *APIConfig) HandlePostEditTitle(w http.ResponseWriter, r *http.Request, user_id int) {
+	pet, _, ok := a.linkedPetFromPath(w, r, user_id, PermissionsCoOwner)
+	if !ok {
+		return
+	}
+
+	title, ok := a.titleFromPath(w, r, pet)
+	if !ok {
+		return
+	}
+
+	form := titleFormFromRequest(r)
+	input, valid := form.validate()
+	if !valid {
+		a.renderTitle(w, r, http.StatusBadRequest, pet, title, form)
+		return
+	}
+
+	_, err := a.Db.UpdateTitle(r.Context(), database.UpdateTitleParams{
+		ID:                  title.ID,
+		PetID:               pet.ID,
+		Name:                form.Name,
+		Abbreviation:        input.Abbreviation,
+		Position:            form.Position,
+		Organization:        input.Organization,
+		EarnedOn:            input.EarnedOn,
+		CertificateImageUrl: input.CertificateImageUrl,
+	})
+	if err != nil {
+		a.serverError(w, r, "error updating title", err)
+		return
+	}
+
+	http.Redirect(w, r, fmt.Sprintf("/dashboard/pet/%d/titles", pet.ID), http.StatusFound)
+}
+
+func (a *APIConfig) HandlePostDeleteTitle(w http.ResponseWriter, r *http.Request, user_id int) {
+	pet, _, ok := a.linkedPetFromPath(w, r, user_id, PermissionsCoOwner)
+	if !ok {
+		return
+	}
+
+	title, ok := a.titleFromPath(w, r, pet)
+	if !ok {
+		return
+	}
+
+	err := a.Db.DeleteTitle(r.Context(), database.DeleteTitleParams{
+		ID:    title.ID,
+		PetID: pet.ID,
+	})
+	if err != nil {
+		a.serverError(w, r, "error deleting title", err)
+		return
+	}
+
+	http.Redirect(w, r, fmt.Sprintf("/dashboard/pet/%d/titles", pet.ID), http.StatusFound)
+}
+
+// Toggles whether titles show on the pet's public profile.
+func (a *APIConfig) HandlePostTitlesVisibility(w http.ResponseWriter, r *http.Request, user_id int) {
+	pet, _, ok := a.linkedPetFromPath(w, r, user_id, PermissionsCoOwner)
+	if !ok {
+		return
+	}
+
+	err := a.Db.UpdatePetTitlesHidden(r.Context(), database.UpdatePetTitlesHiddenParams{
+		ID:           pet.ID,
+		Titleshidden: r.FormValue("titles_hidden") == "on",
+	})
+	if err != nil {
+		a.serverError(w, r, "error updating titles visibility", err)
+		return
+	}
+
+	http.Redirect(w, r, fmt.Sprintf("/dashboard/pet/%d/titles", pet.ID), http.StatusFound)
+}
@@ -0,0 +1,97 @@
+package api
+
+import (
+	"database/sql"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/ctiller15/tailscribe/internal/database"
+	"github.com/stretchr/testify/assert"
+)
+
+func abbreviation(value string) sql.NullString {
+	return sql.NullString{String: value, Valid: true}
+}
+
+func TestPetDisplayName(t *testing.T) {
+	var displayNameTests = []struct {
+		name     string
+		titles   []database.Title
+		expected string
+	}{
+		{
+			"no titles",
+			nil,
+			"Rex",
+		},
+		{
+			"prefixes and suffixes",
+			[]database.Title{
+				{Abbreviation: abbreviation("CH"), Position: TitlePrefix},
+				{Abbreviation: abbreviation("CGC"), Position: TitleSuffix},
+				{Abbreviation: abbreviation("TKN"), Position: TitleSuffix},
+			},
+			"CH Rex CGC TKN",
+		},
+		{
+			"titles without abbreviations are skipped",
+			[]database.Title{
+				{Name: "Therapy Dog", Position: TitleSuffix},
+			},
+			"Rex",
+		},
+	}
+
+	for _, tt := range displayNameTests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, PetDisplayName("Rex", tt.titles))
+		})
+	}
+}
+
+func TestTitleFormValidate(t *testing.T) {
+	t.Run("Rejects a certificate that isn't a web address", func(t *testing.T) {
+		form := TitleForm{
+			Name:                "Canine Good Citizen",
+			Position:            TitleSuffix,
+			EarnedOn:            "2024-09-14",
+			CertificateImageUrl: "javascript:alert(1)",
+		}
+		_, valid := form.validate()
+		assert.False(t, valid)
+		assert.Contains(t, form.Errors, "certificate_image_url")
+	})
+}
+
+func TestHandlePostTitle(t *testing.T) {
+	t.Run("Adds a title to the pet's display name", func(t *testing.T) {
+		cookies := signUserUp(randTestEmail(), testPassword)
+		petID := createPetForUser(t, cookies[0], "Rex")
+
+		formData := url.Values{
+			"name":         {"Canine Good Citizen"},
+			"abbreviation": {"CGC"},
+			"position":     {TitleSuffix},
+			"organization": {"AKC"},
+			"earned_on":    {"2024-09-14"},
+		}
+		request, _ := http.NewRequest(http.MethodPost, fmt.Sprintf("/dashboard/pet/%d/titles", petID), strings.NewReader(formData.Encode()))
+		request.Header.Add("Content-Type", "application/x-www-form-urlencoded")
+		request.SetPathValue("id", strconv.Itoa(petID))
+		request.AddCookie(cookies[0])
+		response := httptest.NewRecorder()
+
+		apiCfg := createConfig()
+		apiCfg.CheckAuthMiddleware(apiCfg.HandlePostTitle)(response, request)
+
+		assert.Equal(t, 302, response.Result().StatusCode)
+
+		dashboard := getPetDashboard(cookies[0], strconv.Itoa(petID))
+		assert.Contains(t, dashboard.Body.String(), "Rex CGC")
+	})
+}
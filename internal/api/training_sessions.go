@@ -0,0 +1,540 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ctiller15/tailscribe/internal/database"
+)
+
+// Matches the value format of an <input type="datetime-local">.
+const sessionTimeLayout = "2006-01-02T15:04"
+
+// Matches the value format of an <input type="date">.
+const dateInputLayout = "2006-01-02"
+
+type TrainingSessionForm struct {
+	StartedAt string
+	Duration  string
+	Location  string
+	Notes     string
+	Behaviors string
+	LikeIDs   []int32
+	Valid     bool
+	Errors    map[string]string
+}
+
+// Reports whether the reinforcer is ticked in the form.
+func (f TrainingSessionForm) HasLike(likeID int32) bool {
+	return slices.Contains(f.LikeIDs, likeID)
+}
+
+type TrainingSessionView struct {
+	database.TrainingSession
+	Behaviors []string
+	// Names of the reinforcers used.
+	Reinforcers []string
+}
+
+type TrainingSessionsPageData struct {
+	BasePageData
+	Pet      database.Pet
+	Sessions []TrainingSessionView
+}
+
+type TrainingSessionFormPageData struct {
+	BasePageData
+	Pet database.Pet
+	// Zero when creating a new session.
+	SessionID int32
+	// The pet's likes, offered as reinforcers.
+	Likes []database.Like
+	TrainingSessionForm
+}
+
+// Validated values from a TrainingSessionForm.
+type trainingSessionInput struct {
+	StartedAt       time.Time
+	DurationMinutes int32
+	Location        sql.NullString
+	Notes           sql.NullString
+	Behaviors       []string
+	LikeIDs         []int32
+}
+
+func trainingSessionFormFromRequest(r *http.Request) TrainingSessionForm {
+	form := TrainingSessionForm{
+		StartedAt: strings.TrimSpace(r.FormValue("started_at")),
+		Duration:  strings.TrimSpace(r.FormValue("duration_minutes")),
+		Location:  strings.TrimSpace(r.FormValue("location")),
+		Notes:     strings.TrimSpace(r.FormValue("notes")),
+		Behaviors: r.FormValue("behaviors"),
+	}
+
+	// ParseForm has already run as part of FormValue.
+	for _, raw := range r.Form["like_ids"] {
+		likeID, err := strconv.ParseInt(raw, 10, 32)
+		if err == nil {
+			form.LikeIDs = append(form.LikeIDs, int32(likeID))
+		}
+	}
+
+	return form
+}
+
+func trainingSessionFormFromSession(session database.TrainingSession, behaviors []string, likes []database.Like) TrainingSessionForm {
+	form := TrainingSessionForm{
+		StartedAt: session.StartedAt.Format(sessionTimeLayout),
+		Duration:  strconv.Itoa(int(session.DurationMinutes)),
+		Location:  session.Location.String,
+		Notes:     session.Notes.String,
+		Behaviors: strings.Join(behaviors, ", "),
+		Valid:     true,
+	}
+	for _, like := range likes {
+		form.LikeIDs = append(form.LikeIDs, like.ID)
+	}
+
+	return form
+}
+
+// Splits a comma separated list of behaviors, dropping blanks and duplicates.
+func parseBehaviors(raw string) []string {
+	seen := map[string]bool{}
+	behaviors := []string{}
+	for _, behavior := range strings.Split(raw, ",") {
+		behavior = strings.TrimSpace(behavior)
+		key := strings.ToLower(behavior)
+		if behavior == "" || seen[key] {
+			continue
+		}
+		seen[key] = true
+		behaviors = append(behaviors, behavior)
+	}
+
+	return behaviors
+}
+
+// Validates the form against the pet's likes. Reinforcers that aren't the pet's are rejected.
+func (f *TrainingSessionForm) validate(petLikes []database.Like) (trainingSessionInput, bool) {
+	input := trainingSessionInput{}
+	f.Errors = map[string]string{}
+
+	startedAt, err := time.Parse(sessionTimeLayout, f.StartedAt)
+	if err != nil {
+		f.Errors["started_at"] = "Enter when the session started."
+	}
+	input.StartedAt = startedAt
+
+	if f.Duration != "" {
+		duration, err := strconv.ParseInt(f.Duration, 10, 32)
+		if err != nil || duration < 0 || duration > 24*60 {
+			f.Errors["duration_minutes"] = "Duration must be between 0 and 1440 minutes."
+		}
+		input.DurationMinutes = int32(duration)
+	}
+
+	input.Location = sql.NullString{String: f.Location, Valid: f.Location != ""}
+	input.Notes = sql.NullString{String: f.Notes, Valid: f.Notes != ""}
+	input.Behaviors = parseBehaviors(f.Behaviors)
+
+	for _, likeID := range f.LikeIDs {
+		owned := slices.ContainsFunc(petLikes, func(like database.Like) bool {
+			return like.ID == likeID
+		})
+		if !owned {
+			f.Errors["like_ids"] = "Choose from this pet's likes."
+			break
+		}
+	}
+	input.LikeIDs = f.LikeIDs
+
+	f.Valid = len(f.Errors) == 0
+	return input, f.Valid
+}
+
+// Replaces the behaviors recorded against a session.
+func setTrainingSessionBehaviors(ctx context.Context, q *database.Queries, sessionID int32, behaviors []string) error {
+	err := q.DeleteTrainingSessionBehaviors(ctx, sessionID)
+	if err != nil {
+		return err
+	}
+
+	for _, behavior := range behaviors {
+		_, err := q.CreateTrainingSessionBehavior(ctx, database.CreateTrainingSessionBehaviorParams{
+			SessionID: sessionID,
+			Name:      behavior,
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Replaces the reinforcers recorded against a session.
+func setTrainingSessionLikes(ctx context.Context, q *database.Queries, sessionID int32, likeIDs []int32) error {
+	err := q.DeleteTrainingSessionLikes(ctx, sessionID)
+	if err != nil {
+		return err
+	}
+
+	for _, likeID := range likeIDs {
+		err := q.AddTrainingSessionLike(ctx, database.AddTrainingSessionLikeParams{
+			SessionID: sessionID,
+			LikeID:    likeID,
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Loads every session for a pet with its behaviors and reinforcers, newest first.
+func (a *APIConfig) listTrainingSessionViews(ctx context.Context, petID int32) ([]TrainingSessionView, error) {
+	sessions, err := a.Db.ListTrainingSessionsForPet(ctx, petID)
+	if err != nil {
+		return nil, err
+	}
+
+	behaviors, err := a.Db.ListTrainingSessionBehaviorsForPet(ctx, petID)
+	if err != nil {
+		return nil, err
+	}
+
+	reinforcers, err := a.Db.ListTrainingSessionLikesForPet(ctx, petID)
+	if err != nil {
+		return nil, err
+	}
+
+	behaviorsBySession := map[int32][]string{}
+	for _, behavior := range behaviors {
+		behaviorsBySession[behavior.SessionID] = append(behaviorsBySession[behavior.SessionID], behavior.Name)
+	}
+
+	reinforcersBySession := map[int32][]string{}
+	for _, reinforcer := range reinforcers {
+		reinforcersBySession[reinforcer.SessionID] = append(reinforcersBySession[reinforcer.SessionID], reinforcer.Name)
+	}
+
+	return trainingSessionViews(sessions, behaviorsBySession, reinforcersBySession), nil
+}
+
+// Loads the latest sessions for a pet with their behaviors and reinforcers,
+// in three queries however many sessions there are.
+func (a *APIConfig) listRecentTrainingSessionViews(ctx context.Context, petID int32, limit int32) ([]TrainingSessionView, error) {
+	sessions, err := a.Db.ListRecentTrainingSessionsForPet(ctx, database.ListRecentTrainingSessionsForPetParams{
+		PetID: petID,
+		Limit: limit,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sessionIDs := make([]int32, 0, len(sessions))
+	for _, session := range sessions {
+		sessionIDs = append(sessionIDs, session.ID)
+	}
+
+	behaviors, err := a.Db.ListTrainingSessionBehaviorsForSessions(ctx, sessionIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	reinforcers, err := a.Db.ListTrainingSessionLikesForSessions(ctx, sessionIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	behaviorsBySession := map[int32][]string{}
+	for _, behavior := range behaviors {
+		behaviorsBySession[behavior.SessionID] = append(behaviorsBySession[behavior.SessionID], behavior.Name)
+	}
+
+	reinforcersBySession := map[int32][]string{}
+	for _, reinforcer := range reinforcers {
+		reinforcersBySession[reinforcer.SessionID] = append(reinforcersBySession[reinforcer.SessionID], reinforcer.Name)
+	}
+
+	return trainingSessionViews(sessions, behaviorsBySession, reinforcersBySession), nil
+}
+
+func trainingSessionViews(sessions []database.TrainingSession, behaviors, reinforcers map[int32][]string) []TrainingSessionView {
+	views := make([]TrainingSessionView, 0, len(sessions))
+	for _, session := range sessions {
+		views = append(views, TrainingSessionView{
+			TrainingSession: session,
+			Behaviors:       behaviors[session.ID],
+			Reinforcers:     reinforcers[session.ID],
+		})
+	}
+
+	return views
+}
+
+func behaviorNames(behaviors []database.TrainingSessionBehavior) []string {
+	names := make([]string, 0, len(behaviors))
+	for _, behavior := range behaviors {
+		names = append(names, behavior.Name)
+	}
+
+	return names
+}
+
+func (a *APIConfig) renderTrainingSessionForm(w http.ResponseWriter, r *http.Request, status int, data TrainingSessionFormPageData) {
+	a.render(w, r, status, "training_session_form", data)
+}
+
+func (a *APIConfig) HandleGetTrainingSessions(w http.ResponseWriter, r *http.Request, user_id int) {
+	pet, _, ok := a.linkedPetFromPath(w, r, user_id, PermissionsViewer)
+	if !ok {
+		return
+	}
+
+	sessions, err := a.listTrainingSessionViews(r.Context(), pet.ID)
+	if err != nil {
+		a.serverError(w, r, "error listing training sessions", err)
+		return
+	}
+
+	data := TrainingSessionsPageData{
+		BasePageData: basePageData(r, fmt.Sprintf("%s - Training Sessions", pet.Name)),
+		Pet:          pet,
+		Sessions:     sessions,
+	}
+
+	a.render(w, r, http.StatusOK, "training_sessions", data)
+}
+
+func (a *APIConfig) HandleGetNewTrainingSession(w http.ResponseWriter, r *http.Request, user_id int) {
+	pet, _, ok := a.linkedPetFromPath(w, r, user_id, PermissionsLogger)
+	if !ok {
+		return
+	}
+
+	likes, err := a.Db.ListLikesForPet(r.Context(), pet.ID)
+	if err != nil {
+		a.serverError(w, r, "error listing likes", err)
+		return
+	}
+
+	a.renderTrainingSessionForm(w, r, http.StatusOK, TrainingSessionFormPageData{
+		BasePageData: basePageData(r, "New Training Session"),
+		Pet:          pet,
+		Likes:        likes,
+		TrainingSessionForm: TrainingSessionForm{
+			StartedAt: time.Now().Format(sessionTimeLayout),
+			Valid:     true,
+		},
+	})
+}
+
+func (a *APIConfig) HandlePostTrainingSession(w http.ResponseWriter, r *http.Request, user_id int) {
+	ctx := r.Context()
+	pet, _, ok := a.linkedPetFromPath(w, r, user_id, PermissionsLogger)
+	if !ok {
+		return
+	}
+
+	likes, err := a.Db.ListLikesForPet(ctx, pet.ID)
+	if err != nil {
+		a.serverError(w, r, "error listing likes", err)
+		return
+	}
+
+	form := trainingSessionFormFromRequest(r)
+	input, valid := form.validate(likes)
+	if !valid {
+		a.renderTrainingSessionForm(w, r, http.StatusBadRequest, TrainingSessionFormPageData{
+			BasePageData:        basePageData(r, "New Training Session"),
+			Pet:                 pet,
+			Likes:               likes,
+			TrainingSessionForm: form,
+		})
+		return
+	}
+
+	// All or nothing, so a failure can't leave a session without its behaviors.
+	err = a.inTx(ctx, func(q *database.Queries) error {
+		session, err := q.CreateTrainingSession(ctx, database.CreateTrainingSessionParams{
+			PetID:           pet.ID,
+			UserID:          sql.NullInt32{Int32: int32(user_id), Valid: true},
+			StartedAt:       input.StartedAt,
+			DurationMinutes: input.DurationMinutes,
+			Location:        input.Location,
+			Notes:           input.Notes,
+		})
+		if err != nil {
+			return err
+		}
+
+		err = setTrainingSessionBehaviors(ctx, q, session.ID, input.Behaviors)
+		if err != nil {
+			return err
+		}
+
+		return setTrainingSessionLikes(ctx, q, session.ID, input.LikeIDs)
+	})
+	if err != nil {
+		a.serverError(w, r, "error creating training session", err)
+		return
+	}
+
+	http.Redirect(w, r, fmt.Sprintf("/dashboard/pet/%d/sessions", pet.ID), http.StatusFound)
+}
+
+// Resolves the {sessionID} path value to a session belonging to the pet.
+// Writes a 404 (or 500) and returns false when it can't be found.
+func (a *APIConfig) trainingSessionFromPath(w http.ResponseWriter, r *http.Request, pet database.Pet) (database.TrainingSession, bool) {
+	sessionID, err := pathID(r, "sessionID")
+	if err != nil {
+		a.serveError(w, r, errNotFound)
+		return database.TrainingSession{}, false
+	}
+
+	session, err := a.Db.GetTrainingSession(r.Context(), database.GetTrainingSessionParams{
+		ID:    sessionID,
+		PetID: pet.ID,
+	})
+	if err != nil {
+		if isNotFound(err) {
+			a.serveError(w, r, errNotFound)
+		} else {
+			a.serverError(w, r, "error loading training session", err)
+		}
+		return database.TrainingSession{}, false
+	}
+
+	return session, true
+}
+
+func (a *APIConfig) HandleGetEditTrainingSession(w http.ResponseWriter, r *http.Request, user_id int) {
+	ctx := r.Context()
+	pet, _, ok := a.linkedPetFromPath(w, r, user_id, PermissionsLogger)
+	if !ok {
+		return
+	}
+
+	session, ok := a.trainingSessionFromPath(w, r, pet)
+	if !ok {
+		return
+	}
+
+	behaviors, err := a.Db.ListTrainingSessionBehaviors(ctx, session.ID)
+	if err != nil {
+		a.serverError(w, r, "error loading training session behaviors", err)
+		return
+	}
+
+	reinforcers, err := a.Db.ListTrainingSessionLikes(ctx, session.ID)
+	if err != nil {
+		a.serverError(w, r, "error loading training session reinforcers", err)
+		return
+	}
+
+	likes, err := a.Db.ListLikesForPet(ctx, pet.ID)
+	if err != nil {
+		a.serverError(w, r, "error listing likes", err)
+		return
+	}
+
+	a.renderTrainingSessionForm(w, r, http.StatusOK, TrainingSessionFormPageData{
+		BasePageData:        basePageData(r, "Edit Training Session"),
+		Pet:                 pet,
+		SessionID:           session.ID,
+		Likes:               likes,
+		TrainingSessionForm: trainingSessionFormFromSession(session, behaviorNames(behaviors), reinforcers),
+	})
+}
+
+func (a *APIConfig) HandlePostEditTrainingSession(w http.ResponseWriter, r *http.Request, user_id int) {
+	ctx := r.Context()
+	pet, _, ok := a.linkedPetFromPath(w, r, user_id, PermissionsLogger)
+	if !ok {
+		return
+	}
+
+	session, ok := a.trainingSessionFromPath(w, r, pet)
+	if !ok {
+		return
+	}
+
+	likes, err := a.Db.ListLikesForPet(ctx, pet.ID)
+	if err != nil {
+		a.serverError(w, r, "error listing likes", err)
+		return
+	}
+
+	form := trainingSessionFormFromRequest(r)
+	input, valid := form.validate(likes)
+	if !valid {
+		a.renderTrainingSessionForm(w, r, http.StatusBadRequest, TrainingSessionFormPageData{
+			BasePageData:        basePageData(r, "Edit Training Session"),
+			Pet:                 pet,
+			SessionID:           session.ID,
+			Likes:               likes,
+			TrainingSessionForm: form,
+		})
+		return
+	}
+
+	// All or nothing, so a failure can't clear the behaviors without saving the new ones.
+	err = a.inTx(ctx, func(q *database.Queries) error {
+		_, err := q.UpdateTrainingSession(ctx, database.UpdateTrainingSessionParams{
+			ID:              session.ID,
+			PetID:           pet.ID,
+			StartedAt:       input.StartedAt,
+			DurationMinutes: input.DurationMinutes,
+			Location:        input.Location,
+			Notes:           input.Notes,
+		})
+		if err != nil {
+			return err
+		}
+
+		err = setTrainingSessionBehaviors(ctx, q, session.ID, input.Behaviors)
+		if err != nil {
+			return err
+		}
+
+		return setTrainingSessionLikes(ctx, q, session.ID, input.LikeIDs)
+	})
+	if err != nil {
+		a.serverError(w, r, "error updating training session", err)
+		return
+	}
+
+	http.Redirect(w, r, fmt.Sprintf("/dashboard/pet/%d/sessions", pet.ID), http.StatusFound)
+}
+
+func (a *APIConfig) HandlePostDeleteTrainingSession(w http.ResponseWriter, r *http.Request, user_id int) {
+	pet, _, ok := a.linkedPetFromPath(w, r, user_id, PermissionsLogger)
+	if !ok {
+		return
+	}
+
+	session, ok := a.trainingSessionFromPath(w, r, pet)
+	if !ok {
+		return
+	}
+
+	err := a.Db.DeleteTrainingSession(r.Context(), database.DeleteTrainingSessionParams{
+		ID:    session.ID,
+		PetID: pet.ID,
+	})
+	if err != nil {
+		a.serverError(w, r, "error deleting training session", err)
+		return
+	}
+
+	http.Redirect(w, r, fmt.Sprintf("/dashboard/pet/%d/sessions", pet.ID), http.StatusFound)
+}
@@ -0,0 +1,139 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func postTrainingSession(authCookie *http.Cookie, petID int, formData url.Values) *http.Response {
+	target := fmt.Sprintf("/dashboard/pet/%d/sessions", petID)
+	request, _ := http.NewRequest(http.MethodPost, target, strings.NewReader(formData.Encode()))
+	request.Header.Add("Content-Type", "application/x-www-form-urlencoded")
+	request.SetPathValue("id", strconv.Itoa(petID))
+	request.AddCookie(authCookie)
+	response := httptest.NewRecorder()
+
+	apiCfg := createConfig()
+	apiCfg.CheckAuthMiddleware(apiCfg.HandlePostTrainingSession)(response, request)
+
+	return response.Result()
+}
+
+func getTrainingSessions(authCookie *http.Cookie, petID int) *httptest.ResponseRecorder {
+	request, _ := http.NewRequest(http.MethodGet, fmt.Sprintf("/dashboard/pet/%d/sessions", petID), nil)
+	request.SetPathValue("id", strconv.Itoa(petID))
+	request.AddCookie(authCookie)
+	response := httptest.NewRecorder()
+
+	apiCfg := createConfig()
+	apiCfg.CheckAuthMiddleware(apiCfg.HandleGetTrainingSessions)(response, request)
+
+	return response
+}
+
+func TestParseBehaviors(t *testing.T) {
+	behaviors := parseBehaviors(" sit, Down,, sit , stay ")
+
+	assert.Equal(t, []string{"sit", "Down", "stay"}, behaviors)
+}
+
+func TestHandlePostTrainingSession(t *testing.T) {
+	t.Run("Fails to log a session when unauthorized", func(t *testing.T) {
+		request, _ := http.NewRequest(http.MethodPost, "/dashboard/pet/1/sessions", nil)
+		request.SetPathValue("id", "1")
+		response := httptest.NewRecorder()
+
+		apiCfg := createConfig()
+		apiCfg.CheckAuthMiddleware(apiCfg.HandlePostTrainingSession)(response, request)
+
+		assert.Equal(t, 401, response.Result().StatusCode)
+	})
+
+	t.Run("Logs a session and lists it for the pet", func(t *testing.T) {
+		cookies := signUserUp(randTestEmail(), testPassword)
+		authCookie := cookies[0]
+		petID := createPetForUser(t, authCookie, "fido")
+
+		result := postTrainingSession(authCookie, petID, url.Values{
+			"started_at":       {"2025-03-01T09:30"},
+			"duration_minutes": {"15"},
+			"location":         {"Back yard"},
+			"behaviors":        {"sit, loose leash walking"},
+			"notes":            {"Great focus today"},
+		})
+		assert.Equal(t, 302, result.StatusCode)
+		assert.Equal(t, fmt.Sprintf("/dashboard/pet/%d/sessions", petID), result.Header.Get("Location"))
+
+		listResponse := getTrainingSessions(authCookie, petID)
+		assert.Equal(t, 200, listResponse.Result().StatusCode)
+		body := listResponse.Body.String()
+		assert.Contains(t, body, "Back yard")
+		assert.Contains(t, body, "loose leash walking")
+		assert.Contains(t, body, "Great focus today")
+	})
+
+	t.Run("Rejects a session without a start time", func(t *testing.T) {
+		cookies := signUserUp(randTestEmail(), testPassword)
+		authCookie := cookies[0]
+		petID := createPetForUser(t, authCookie, "rex")
+
+		result := postTrainingSession(authCookie, petID, url.Values{
+			"duration_minutes": {"15"},
+		})
+		assert.Equal(t, 400, result.StatusCode)
+	})
+
+	t.Run("Hides sessions from users not linked to the pet", func(t *testing.T) {
+		ownerCookies := signUserUp(randTestEmail(), testPassword)
+		petID := createPetForUser(t, ownerCookies[0], "spot")
+
+		strangerCookies := signUserUp(randTestEmail(), testPassword)
+
+		result := postTrainingSession(strangerCookies[0], petID, url.Values{
+			"started_at": {"2025-03-01T09:30"},
+		})
+		assert.Equal(t, 404, result.StatusCode)
+
+		listResponse := getTrainingSessions(strangerCookies[0], petID)
+		assert.Equal(t, 404, listResponse.Result().StatusCode)
+	})
+}
+
+func TestHandlePostDeleteTrainingSession(t *testing.T) {
+	t.Run("Deletes a logged session", func(t *testing.T) {
+		cookies := signUserUp(randTestEmail(), testPassword)
+		authCookie := cookies[0]
+		petID := createPetForUser(t, authCookie, "biscuit")
+
+		postTrainingSession(authCookie, petID, url.Values{
+			"started_at": {"2025-03-01T09:30"},
+			"notes":      {"to be deleted"},
+		})
+
+		apiCfg := createConfig()
+		sessions, err := apiCfg.Db.ListTrainingSessionsForPet(t.Context(), int32(petID))
+		assert.NoError(t, err)
+		assert.Len(t, sessions, 1)
+
+		target := fmt.Sprintf("/dashboard/pet/%d/sessions/%d/delete", petID, sessions[0].ID)
+		request, _ := http.NewRequest(http.MethodPost, target, nil)
+		request.SetPathValue("id", strconv.Itoa(petID))
+		request.SetPathValue("sessionID", strconv.Itoa(int(sessions[0].ID)))
+		request.AddCookie(authCookie)
+		response := httptest.NewRecorder()
+		apiCfg.CheckAuthMiddleware(apiCfg.HandlePostDeleteTrainingSession)(response, request)
+
+		assert.Equal(t, 302, response.Result().StatusCode)
+
+		sessions, err = apiCfg.Db.ListTrainingSessionsForPet(t.Context(), int32(petID))
+		assert.NoError(t, err)
+		assert.Len(t, sessions, 0)
+	})
+}
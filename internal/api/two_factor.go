@@ -0,0 +1,466 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"html/template"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ctiller15/tailscribe/internal/auth"
+	"github.com/ctiller15/tailscribe/internal/database"
+)
+
+const (
+	// Carries a user who has given their password to the two-factor step.
+	twoFactorLoginCookie = "two_factor_login"
+	// How long they have to enter a code before logging in again.
+	twoFactorLoginTTL = 5 * time.Minute
+	// How many recovery codes a user gets at a time.
+	recoveryCodeCount = 10
+)
+
+var errNoPendingTOTP = errors.New("no two-factor enrollment in progress")
+
+type TwoFactorLoginPageData struct {
+	BasePageData
+	Error string
+}
+
+type TwoFactorPageData struct {
+	BasePageData
+	Enabled bool
+	// Set while enrolling, until a code from the new authenticator is confirmed.
+	QRCode template.URL
+	Secret string
+	// Only shown right after they're made.
+	RecoveryCodes          []string
+	RemainingRecoveryCodes int64
+	Errors                 map[string]string
+}
+
+// Starts the second step of logging in, for a user whose password checked out.
+func (a *APIConfig) beginTwoFactorLogin(w *http.ResponseWriter, user database.User) {
+	expiresAt := time.Now().Add(twoFactorLoginTTL)
+	value := fmt.Sprintf("%d|%d", user.ID, expiresAt.Unix())
+
+	http.SetCookie(*w, &http.Cookie{
+		Name:     twoFactorLoginCookie,
+		Value:    auth.SignValue(value, a.Env.Secret),
+		Path:     "/login",
+		Expires:  expiresAt,
+		HttpOnly: true,
+		Secure:   true,
+		SameSite: http.SameSiteStrictMode,
+	})
+}
+
+// The user partway through logging in, if their time to enter a code hasn't run out.
+func (a *APIConfig) pendingTwoFactorUser(r *http.Request) (int32, bool) {
+	cookie, err := r.Cookie(twoFactorLoginCookie)
+	if err != nil {
+		return 0, false
+	}
+
+	value, ok := auth.VerifySignedValue(cookie.Value, a.Env.Secret)
+	if !ok {
+		return 0, false
+	}
+
+	userPart, expiresPart, found := strings.Cut(value, "|")
+	if !found {
+		return 0, false
+	}
+
+	userID, err := strconv.ParseInt(userPart, 10, 32)
+	if err != nil {
+		return 0, false
+	}
+
+	expiresAt, err := strconv.ParseInt(expiresPart, 10, 64)
+	if err != nil || time.Now().Unix() > expiresAt {
+		return 0, false
+	}
+
+	return int32(userID), true
+}
+
+func (a *APIConfig) twoFactorSecret(user database.User) (string, error) {
+	if !user.TotpSecret.Valid {
+		return "", errNoPendingTOTP
+	}
+	return auth.DecryptSecret(user.TotpSecret.String, a.TwoFactorKey)
+}
+
+// Accepts a code from the user's authenticator or one of their recovery codes.
+// Either only works once.
+func (a *APIConfig) checkSecondFactor(ctx context.Context, user database.User, code string) (bool, error) {
+	secret, err := a.twoFactorSecret(user)
+	if err != nil {
+		return false, err
+	}
+
+	step, ok := auth.ValidateTOTP(secret, code, time.Now())
+	if ok {
+		used, err := a.Db.UseUserTOTPStep(ctx, database.UseUserTOTPStepParams{
+			ID:           user.ID,
+			TotpLastStep: sql.NullInt64{Int64: step, Valid: true},
+		})
+		return used == 1, err
+	}
+
+	used, err := a.Db.UseRecoveryCode(ctx, database.UseRecoveryCodeParams{
+		UserID:   user.ID,
+		CodeHash: auth.HashRecoveryCode(code),
+	})
+	return used == 1, err
+}
+
+// Counts a code attempt, returning how long the user should have waited before it.
+func (a *APIConfig) twoFactorAttempt(ctx context.Context, userID int32) (time.Duration, error) {
+	return a.RateLimits.TwoFactor.Attempt(ctx, strconv.Itoa(int(userID)))
+}
+
+func (a *APIConfig) resetTwoFactorLimit(ctx context.Context, userID int32) {
+	err := a.RateLimits.TwoFactor.Reset(ctx, strconv.Itoa(int(userID)))
+	if err != nil {
+		a.Logger.Error("error resetting two-factor rate limit", slog.String("error", err.Error()))
+	}
+}
+
+// Replaces the user's recovery codes with new ones, returning them to show once.
+func (a *APIConfig) replaceRecoveryCodes(ctx context.Context, userID int32) ([]string, error) {
+	codes, err := auth.MakeRecoveryCodes(recoveryCodeCount)
+	if err != nil {
+		return nil, err
+	}
+
+	err = a.Db.DeleteRecoveryCodesForUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, code := range codes {
+		err = a.Db.CreateRecoveryCode(ctx, database.CreateRecoveryCodeParams{
+			UserID:   userID,
+			CodeHash: auth.HashRecoveryCode(code),
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return codes, nil
+}
+
+func (a *APIConfig) renderTwoFactorLogin(w http.ResponseWriter, r *http.Request, status int, errorMessage string) {
+	data := TwoFactorLoginPageData{
+		BasePageData: basePageData(r, "TailScribe - Two-Factor Login"),
+		Error:        errorMessage,
+	}
+
+	a.render(w, r, status, "two_factor_login", data)
+}
+
+func (a *APIConfig) HandleGetTwoFactorLogin(w http.ResponseWriter, r *http.Request) {
+	_, ok := a.pendingTwoFactorUser(r)
+	if !ok {
+		http.Redirect(w, r, "/login", http.StatusFound)
+		return
+	}
+
+	a.renderTwoFactorLogin(w, r, http.StatusOK, "")
+}
+
+func (a *APIConfig) HandlePostTwoFactorLogin(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	userID, ok := a.pendingTwoFactorUser(r)
+	if !ok {
+		http.Redirect(w, r, "/login", http.StatusFound)
+		return
+	}
+
+	wait, err := a.twoFactorAttempt(ctx, userID)
+	if err != nil {
+		a.serverError(w, r, "error checking two-factor rate limit", err)
+		return
+	}
+	if wait > 0 {
+		a.serveError(w, r, tooManyRequests(wait))
+		return
+	}
+
+	user, err := a.Db.GetUser(ctx, userID)
+	if err != nil {
+		a.serverError(w, r, "error loading user", err)
+		return
+	}
+	if user.IsDeleted {
+		http.Redirect(w, r, "/login", http.StatusFound)
+		return
+	}
+
+	// An admin may have turned two-factor off since the password was checked.
+	if user.TotpEnabledAt.Valid {
+		valid, err := a.checkSecondFactor(ctx, user, r.FormValue("code"))
+		if err != nil {
+			a.serverError(w, r, "error checking two-factor code", err)
+			return
+		}
+		if !valid {
+			a.renderTwoFactorLogin(w, r, http.StatusUnauthorized, "That code didn't work. Codes can only be used once.")
+			return
+		}
+	}
+
+	a.resetTwoFactorLimit(ctx, user.ID)
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     twoFactorLoginCookie,
+		Value:    "",
+		Path:     "/login",
+		Expires:  time.Unix(0, 0),
+		HttpOnly: true,
+	})
+
+	err = a.createAndAttachSessionCookies(&w, r, user)
+	if err != nil {
+		a.serverError(w, r, "error creating session cookies", err)
+		return
+	}
+
+	http.Redirect(w, r, "/dashboard", http.StatusFound)
+}
+
+func (a *APIConfig) renderTwoFactor(w http.ResponseWriter, r *http.Request, status int, user database.User, data TwoFactorPageData) {
+	ctx := r.Context()
+	data.BasePageData = basePageData(r, "TailScribe - Two-Factor Authentication")
+	data.Enabled = user.TotpEnabledAt.Valid
+
+	if data.Enabled {
+		remaining, err := a.Db.CountUnusedRecoveryCodes(ctx, user.ID)
+		if err != nil {
+			a.serverError(w, r, "error counting recovery codes", err)
+			return
+		}
+		data.RemainingRecoveryCodes = remaining
+	} else if user.TotpSecret.Valid {
+		secret, err := a.twoFactorSecret(user)
+		if err != nil {
+			a.serverError(w, r, "error decrypting two-factor secret", err)
+			return
+		}
+
+		accountName := user.Email.String
+		if accountName == "" {
+			accountName = fmt.Sprintf("user %d", user.ID)
+		}
+		image, err := auth.TOTPQRCode(auth.TOTPURL(secret, accountName), 200)
+		if err != nil {
+			a.serverError(w, r, "error drawing QR code", err)
+			return
+		}
+
+		data.Secret = secret
+		data.QRCode = template.URL("data:image/png;base64," + base64.StdEncoding.EncodeToString(image))
+	}
+
+	a.render(w, r, status, "two_factor", data)
+}
+
+// Loads the user for a two-factor settings page, or responds with an error.
+func (a *APIConfig) twoFactorUser(w http.ResponseWriter, r *http.Request, userID int) (database.User, bool) {
+	user, err := a.Db.GetUser(r.Context(), int32(userID))
+	if err != nil {
+		a.serverError(w, r, "error loading user", err)
+		return database.User{}, false
+	}
+	return user, true
+}
+
+func (a *APIConfig) HandleGetTwoFactor(w http.ResponseWriter, r *http.Request, user_id int) {
+	user, ok := a.twoFactorUser(w, r, user_id)
+	if !ok {
+		return
+	}
+
+	a.renderTwoFactor(w, r, http.StatusOK, user, TwoFactorPageData{})
+}
+
+// Starts enrollment with a new secret, replacing any unconfirmed one.
+func (a *APIConfig) HandlePostTwoFactorSetup(w http.ResponseWriter, r *http.Request, user_id int) {
+	ctx := r.Context()
+	user, ok := a.twoFactorUser(w, r, user_id)
+	if !ok {
+		return
+	}
+
+	if user.TotpEnabledAt.Valid {
+		http.Redirect(w, r, "/account/two_factor", http.StatusFound)
+		return
+	}
+
+	secret, err := auth.GenerateTOTPSecret()
+	if err != nil {
+		a.serverError(w, r, "error creating two-factor secret", err)
+		return
+	}
+
+	encrypted, err := auth.EncryptSecret(secret, a.TwoFactorKey)
+	if err != nil {
+		a.serverError(w, r, "error encrypting two-factor secret", err)
+		return
+	}
+
+	err = a.Db.SetUserTOTPSecret(ctx, database.SetUserTOTPSecretParams{
+		ID:         user.ID,
+		TotpSecret: sql.NullString{String: encrypted, Valid: true},
+	})
+	if err != nil {
+		a.serverError(w, r, "error storing two-factor secret", err)
+		return
+	}
+
+	http.Redirect(w, r, "/account/two_factor", http.StatusFound)
+}
+
+// Turns two-factor on once the user shows their authenticator has the secret.
+func (a *APIConfig) HandlePostTwoFactorEnable(w http.ResponseWriter, r *http.Request, user_id int) {
+	ctx := r.Context()
+	user, ok := a.twoFactorUser(w, r, user_id)
+	if !ok {
+		return
+	}
+
+	if user.TotpEnabledAt.Valid || !user.TotpSecret.Valid {
+		http.Redirect(w, r, "/account/two_factor", http.StatusFound)
+		return
+	}
+
+	secret, err := a.twoFactorSecret(user)
+	if err != nil {
+		a.serverError(w, r, "error decrypting two-factor secret", err)
+		return
+	}
+
+	step, valid := auth.ValidateTOTP(secret, r.FormValue("code"), time.Now())
+	if !valid {
+		a.renderTwoFactor(w, r, http.StatusBadRequest, user, TwoFactorPageData{
+			Errors: map[string]string{"code": "That code didn't match. Check your device's clock and try the newest code."},
+		})
+		return
+	}
+
+	enabled, err := a.Db.EnableUserTOTP(ctx, database.EnableUserTOTPParams{
+		ID:           user.ID,
+		TotpLastStep: sql.NullInt64{Int64: step, Valid: true},
+	})
+	if err != nil {
+		a.serverError(w, r, "error enabling two-factor", err)
+		return
+	}
+	if enabled == 0 {
+		http.Redirect(w, r, "/account/two_factor", http.StatusFound)
+		return
+	}
+
+	codes, err := a.replaceRecoveryCodes(ctx, user.ID)
+	if err != nil {
+		a.serverError(w, r, "error creating recovery codes", err)
+		return
+	}
+
+	user.TotpEnabledAt = sql.NullTime{Time: time.Now(), Valid: true}
+	a.renderTwoFactor(w, r, http.StatusOK, user, TwoFactorPageData{RecoveryCodes: codes})
+}
+
+// Checks a code for a change to an enabled user's two-factor settings,
+// rendering the settings page with an error if it's wrong.
+func (a *APIConfig) confirmSecondFactor(w http.ResponseWriter, r *http.Request, user database.User) bool {
+	ctx := r.Context()
+
+	wait, err := a.twoFactorAttempt(ctx, user.ID)
+	if err != nil {
+		a.serverError(w, r, "error checking two-factor rate limit", err)
+		return false
+	}
+	if wait > 0 {
+		a.serveError(w, r, tooManyRequests(wait))
+		return false
+	}
+
+	valid, err := a.checkSecondFactor(ctx, user, r.FormValue("code"))
+	if err != nil {
+		a.serverError(w, r, "error checking two-factor code", err)
+		return false
+	}
+	if !valid {
+		a.renderTwoFactor(w, r, http.StatusBadRequest, user, TwoFactorPageData{
+			Errors: map[string]string{"code": "That code didn't work. Codes can only be used once."},
+		})
+		return false
+	}
+
+	a.resetTwoFactorLimit(ctx, user.ID)
+	return true
+}
+
+func (a *APIConfig) HandlePostTwoFactorRecoveryCodes(w http.ResponseWriter, r *http.Request, user_id int) {
+	user, ok := a.twoFactorUser(w, r, user_id)
+	if !ok {
+		return
+	}
+
+	if !user.TotpEnabledAt.Valid {
+		http.Redirect(w, r, "/account/two_factor", http.StatusFound)
+		return
+	}
+
+	if !a.confirmSecondFactor(w, r, user) {
+		return
+	}
+
+	codes, err := a.replaceRecoveryCodes(r.Context(), user.ID)
+	if err != nil {
+		a.serverError(w, r, "error creating recovery codes", err)
+		return
+	}
+
+	a.renderTwoFactor(w, r, http.StatusOK, user, TwoFactorPageData{RecoveryCodes: codes})
+}
+
+// Turns two-factor off, discarding the secret and recovery codes.
+func (a *APIConfig) disableTwoFactor(ctx context.Context, userID int32) error {
+	err := a.Db.DisableUserTOTP(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	return a.Db.DeleteRecoveryCodesForUser(ctx, userID)
+}
+
+func (a *APIConfig) HandlePostTwoFactorDisable(w http.ResponseWriter, r *http.Request, user_id int) {
+	user, ok := a.twoFactorUser(w, r, user_id)
+	if !ok {
+		return
+	}
+
+	// Abandoning an unconfirmed enrollment needs no code.
+	if user.TotpEnabledAt.Valid && !a.confirmSecondFactor(w, r, user) {
+		return
+	}
+
+	err := a.disableTwoFactor(r.Context(), user.ID)
+	if err != nil {
+		a.serverError(w, r, "error disabling two-factor", err)
+		return
+	}
+
+	http.Redirect(w, r, "/account/two_factor", http.StatusFound)
+}
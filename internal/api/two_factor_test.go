@@ -0,0 +1,217 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ctiller15/tailscribe/internal/auth"
+	"github.com/ctiller15/tailscribe/internal/database"
+	"github.com/pquerna/otp/totp"
+	"github.com/stretchr/testify/assert"
+)
+
+var recoveryCodePattern = regexp.MustCompile(`<code>([a-z0-9]{5}-[a-z0-9]{5})</code>`)
+
+func postTwoFactor(cookies []*http.Cookie, path string, handler authorizedHandler, formData url.Values) *httptest.ResponseRecorder {
+	request, _ := http.NewRequest(http.MethodPost, path, strings.NewReader(formData.Encode()))
+	request.Header.Add("Content-Type", "application/x-www-form-urlencoded")
+	for _, cookie := range cookies {
+		request.AddCookie(cookie)
+	}
+	response := httptest.NewRecorder()
+
+	createConfig().CheckAuthMiddleware(handler)(response, request)
+
+	return response
+}
+
+func postTwoFactorLogin(cookie *http.Cookie, code string) *httptest.ResponseRecorder {
+	formData := url.Values{"code": {code}}
+	request, _ := http.NewRequest(http.MethodPost, "/login/two_factor", strings.NewReader(formData.Encode()))
+	request.Header.Add("Content-Type", "application/x-www-form-urlencoded")
+	if cookie != nil {
+		request.AddCookie(cookie)
+	}
+	response := httptest.NewRecorder()
+
+	createConfig().HandlePostTwoFactorLogin(response, request)
+
+	return response
+}
+
+// Signs up a user and turns on two-factor, returning their TOTP secret and recovery codes.
+func signUpWithTwoFactor(t *testing.T, email string) (string, []string) {
+	apiCfg := createConfig()
+	cookies := signUserUp(email, testPassword)
+
+	response := postTwoFactor(cookies, "/account/two_factor/setup", apiCfg.HandlePostTwoFactorSetup, url.Values{})
+	assert.Equal(t, http.StatusFound, response.Result().StatusCode)
+
+	user, err := apiCfg.Db.GetUserByEmail(context.Background(), email)
+	assert.NoError(t, err)
+	secret, err := apiCfg.twoFactorSecret(user)
+	assert.NoError(t, err)
+
+	code, err := totp.GenerateCode(secret, time.Now())
+	assert.NoError(t, err)
+
+	response = postTwoFactor(cookies, "/account/two_factor/enable", apiCfg.HandlePostTwoFactorEnable, url.Values{"code": {code}})
+	assert.Equal(t, http.StatusOK, response.Result().StatusCode)
+
+	codes := []string{}
+	for _, match := range recoveryCodePattern.FindAllStringSubmatch(response.Body.String(), -1) {
+		codes = append(codes, match[1])
+	}
+
+	return secret, codes
+}
+
+func TestPendingTwoFactorUser(t *testing.T) {
+	apiCfg := createConfig()
+
+	response := httptest.NewRecorder()
+	var w http.ResponseWriter = response
+	apiCfg.beginTwoFactorLogin(&w, database.User{ID: 42})
+	cookie := findCookie(response.Result().Cookies(), twoFactorLoginCookie)
+	assert.NotNil(t, cookie)
+
+	request, _ := http.NewRequest(http.MethodGet, "/login/two_factor", nil)
+	request.AddCookie(cookie)
+	userID, ok := apiCfg.pendingTwoFactorUser(request)
+	assert.True(t, ok)
+	assert.Equal(t, int32(42), userID)
+
+	forged := auth.SignValue("42|1", "another secret")
+	request, _ = http.NewRequest(http.MethodGet, "/login/two_factor", nil)
+	request.AddCookie(&http.Cookie{Name: twoFactorLoginCookie, Value: forged})
+	_, ok = apiCfg.pendingTwoFactorUser(request)
+	assert.False(t, ok)
+
+	expired := auth.SignValue("42|1", apiCfg.Env.Secret)
+	request, _ = http.NewRequest(http.MethodGet, "/login/two_factor", nil)
+	request.AddCookie(&http.Cookie{Name: twoFactorLoginCookie, Value: expired})
+	_, ok = apiCfg.pendingTwoFactorUser(request)
+	assert.False(t, ok)
+}
+
+func TestTwoFactorEnrollment(t *testing.T) {
+	t.Run("Stores the secret encrypted and shows recovery codes once it's confirmed", func(t *testing.T) {
+		email := randTestEmail()
+		secret, codes := signUpWithTwoFactor(t, email)
+
+		assert.Len(t, codes, recoveryCodeCount)
+
+		user, err := createConfig().Db.GetUserByEmail(context.Background(), email)
+		assert.NoError(t, err)
+		assert.True(t, user.TotpEnabledAt.Valid)
+		assert.NotContains(t, user.TotpSecret.String, secret)
+	})
+
+	t.Run("Rejects a wrong confirmation code", func(t *testing.T) {
+		apiCfg := createConfig()
+		email := randTestEmail()
+		cookies := signUserUp(email, testPassword)
+		postTwoFactor(cookies, "/account/two_factor/setup", apiCfg.HandlePostTwoFactorSetup, url.Values{})
+
+		response := postTwoFactor(cookies, "/account/two_factor/enable", apiCfg.HandlePostTwoFactorEnable, url.Values{"code": {"000000"}})
+
+		assert.Equal(t, http.StatusBadRequest, response.Result().StatusCode)
+		user, err := apiCfg.Db.GetUserByEmail(context.Background(), email)
+		assert.NoError(t, err)
+		assert.False(t, user.TotpEnabledAt.Valid)
+	})
+}
+
+func TestTwoFactorLogin(t *testing.T) {
+	t.Run("Asks for a code before starting a session", func(t *testing.T) {
+		email := randTestEmail()
+		secret, _ := signUpWithTwoFactor(t, email)
+
+		response := postLoginWith(createConfig(), "203.0.113.30:1000", email, testPassword)
+		assert.Equal(t, http.StatusFound, response.Result().StatusCode)
+		assert.Equal(t, "/login/two_factor", response.Result().Header.Get("Location"))
+		assert.Nil(t, findCookie(response.Result().Cookies(), "token"))
+		pending := findCookie(response.Result().Cookies(), twoFactorLoginCookie)
+		assert.NotNil(t, pending)
+
+		// The code that confirmed enrollment is spent, so use the next one.
+		code, err := totp.GenerateCode(secret, time.Now().Add(30*time.Second))
+		assert.NoError(t, err)
+
+		response = postTwoFactorLogin(pending, code)
+		assert.Equal(t, http.StatusFound, response.Result().StatusCode)
+		assert.Equal(t, "/dashboard", response.Result().Header.Get("Location"))
+		assert.NotNil(t, findCookie(response.Result().Cookies(), "token"))
+
+		response = postTwoFactorLogin(pending, code)
+		assert.Equal(t, http.StatusUnauthorized, response.Result().StatusCode)
+	})
+
+	t.Run("Accepts each recovery code once", func(t *testing.T) {
+		email := randTestEmail()
+		_, codes := signUpWithTwoFactor(t, email)
+
+		response := postLoginWith(createConfig(), "203.0.113.31:1000", email, testPassword)
+		pending := findCookie(response.Result().Cookies(), twoFactorLoginCookie)
+
+		response = postTwoFactorLogin(pending, strings.ToUpper(codes[0]))
+		assert.Equal(t, http.StatusFound, response.Result().StatusCode)
+
+		response = postTwoFactorLogin(pending, codes[0])
+		assert.Equal(t, http.StatusUnauthorized, response.Result().StatusCode)
+	})
+
+	t.Run("Needs the password step first", func(t *testing.T) {
+		response := postTwoFactorLogin(nil, "123456")
+
+		assert.Equal(t, http.StatusFound, response.Result().StatusCode)
+		assert.Equal(t, "/login", response.Result().Header.Get("Location"))
+	})
+}
+
+func TestAdminTwoFactorReset(t *testing.T) {
+	t.Run("Hidden from users who aren't admins", func(t *testing.T) {
+		cookies := signUserUp(randTestEmail(), testPassword)
+		request, _ := http.NewRequest(http.MethodGet, "/admin/two_factor", nil)
+		request.AddCookie(findCookie(cookies, "token"))
+		response := httptest.NewRecorder()
+
+		apiCfg := createConfig()
+		apiCfg.CheckAdminMiddleware(apiCfg.HandleGetAdminTwoFactor)(response, request)
+
+		assert.Equal(t, http.StatusNotFound, response.Result().StatusCode)
+	})
+
+	t.Run("Turns off two-factor and signs the user out", func(t *testing.T) {
+		apiCfg := createConfig()
+		email := randTestEmail()
+		signUpWithTwoFactor(t, email)
+		user, err := apiCfg.Db.GetUserByEmail(context.Background(), email)
+		assert.NoError(t, err)
+
+		formData := url.Values{"email": {email}}
+		request, _ := http.NewRequest(http.MethodPost, "/admin/two_factor/reset", strings.NewReader(formData.Encode()))
+		request.Header.Add("Content-Type", "application/x-www-form-urlencoded")
+		response := httptest.NewRecorder()
+		apiCfg.HandlePostAdminTwoFactorReset(response, request, 1)
+
+		assert.Equal(t, http.StatusOK, response.Result().StatusCode)
+
+		user, err = apiCfg.Db.GetUser(context.Background(), user.ID)
+		assert.NoError(t, err)
+		assert.False(t, user.TotpSecret.Valid)
+		assert.False(t, user.TotpEnabledAt.Valid)
+		remaining, err := apiCfg.Db.CountUnusedRecoveryCodes(context.Background(), user.ID)
+		assert.NoError(t, err)
+		assert.Equal(t, int64(0), remaining)
+
+		response = postLoginWith(createConfig(), "203.0.113.32:1000", email, testPassword)
+		assert.Equal(t, "/dashboard", response.Result().Header.Get("Location"))
+	})
+}
@@ -1,36 +1,29 @@
 package auth
 
 import (
+	"crypto/hmac"
 	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
 	"encoding/hex"
 	"fmt"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
-	"golang.org/x/crypto/bcrypt"
+	"github.com/google/uuid"
 )
 
-func HashPassword(password string) (string, error) {
-	bytes, err := bcrypt.GenerateFromPassword([]byte(password), 14)
-	if err != nil {
-		return "", err
-	}
-
-	return string(bytes), nil
-}
-
-func CheckPasswordHash(password, hash string) bool {
-	err := bcrypt.CompareHashAndPassword([]byte(hash), []byte(password))
-	return err == nil
-}
-
-func MakeJWT(userID int32, tokenSecret string) (string, error) {
+// Access tokens carry the session they belong to as their ID, so revoking the
+// session can reject them before they expire.
+func MakeJWT(userID int32, sessionID uuid.UUID, tokenSecret string) (string, error) {
 	claims := jwt.RegisteredClaims{
 		Issuer:    "tailscribe",
 		IssuedAt:  jwt.NewNumericDate(time.Now()),
 		ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour)),
 		Subject:   strconv.Itoa(int(userID)),
+		ID:        sessionID.String(),
 	}
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
 	tokenString, err := token.SignedString([]byte(tokenSecret))
@@ -42,13 +35,99 @@ func MakeJWT(userID int32, tokenSecret string) (string, error) {
 }
 
 func ValidateJWT(tokenString, tokenSecret string) (int, error) {
-	token, err := jwt.ParseWithClaims(tokenString, &jwt.RegisteredClaims{}, func(token *jwt.Token) (interface{}, error) {
+	userID, _, err := validateJWT(tokenString, tokenSecret)
+	return userID, err
+}
+
+// Validates an access token and returns its user and session.
+func ValidateSessionJWT(tokenString, tokenSecret string) (int, uuid.UUID, error) {
+	userID, claims, err := validateJWT(tokenString, tokenSecret)
+	if err != nil {
+		return -1, uuid.Nil, err
+	}
+
+	sessionID, err := uuid.Parse(claims.ID)
+	if err != nil {
+		return -1, uuid.Nil, fmt.Errorf("invalid session ID: %v", err)
+	}
+
+	return userID, sessionID, nil
+}
+
+func validateJWT(tokenString, tokenSecret string) (int, *jwt.RegisteredClaims, error) {
+	token, err := jwt.ParseWithClaims(tokenString, &jwt.RegisteredClaims{}, jwtKey(tokenSecret))
+	if err != nil {
+		return -1, nil, err
+	}
+
+	claims, ok := token.Claims.(*jwt.RegisteredClaims)
+	if !ok || !token.Valid {
+		return -1, nil, fmt.Errorf("invalid or expired token")
+	}
+
+	userID, err := strconv.Atoi(claims.Subject)
+	if err != nil {
+		return -1, nil, fmt.Errorf("invalid user ID: %v", err)
+	}
+
+	return userID, claims, nil
+}
+
+func jwtKey(tokenSecret string) jwt.Keyfunc {
+	return func(token *jwt.Token) (interface{}, error) {
 		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
 			return nil, fmt.Errorf("unexpected signing method: %v", token.Method)
 		}
 
 		return []byte(tokenSecret), nil
-	})
+	}
+}
+
+// Returns the session of an access token we signed, even once it has expired.
+// Only for telling which session a browser is in, never for letting it in.
+func JWTSessionID(tokenString, tokenSecret string) (uuid.UUID, error) {
+	claims := &jwt.RegisteredClaims{}
+	_, err := jwt.ParseWithClaims(tokenString, claims, jwtKey(tokenSecret), jwt.WithoutClaimsValidation())
+	if err != nil {
+		return uuid.Nil, err
+	}
+
+	return uuid.Parse(claims.ID)
+}
+
+// Audience of pet invitation tokens, so they can't be mistaken for session tokens.
+const invitationAudience = "tailscribe:invitation"
+
+// Invitation links are signed with a key derived from the token secret, so an
+// invitation token never validates as a session JWT (or the reverse).
+func invitationKey(tokenSecret string) []byte {
+	mac := hmac.New(sha256.New, []byte(tokenSecret))
+	mac.Write([]byte(invitationAudience))
+	return mac.Sum(nil)
+}
+
+// Signs an invitation id into a token for an invite link that stops working at expiresAt.
+func MakeInvitationToken(invitationID int32, expiresAt time.Time, tokenSecret string) (string, error) {
+	claims := jwt.RegisteredClaims{
+		Issuer:    "tailscribe",
+		Audience:  jwt.ClaimStrings{invitationAudience},
+		IssuedAt:  jwt.NewNumericDate(time.Now()),
+		ExpiresAt: jwt.NewNumericDate(expiresAt),
+		Subject:   strconv.Itoa(int(invitationID)),
+	}
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	return token.SignedString(invitationKey(tokenSecret))
+}
+
+// Returns the invitation id from a token made by MakeInvitationToken.
+func ValidateInvitationToken(tokenString, tokenSecret string) (int32, error) {
+	token, err := jwt.ParseWithClaims(tokenString, &jwt.RegisteredClaims{}, func(token *jwt.Token) (interface{}, error) {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, fmt.Errorf("unexpected signing method: %v", token.Method)
+		}
+
+		return invitationKey(tokenSecret), nil
+	}, jwt.WithAudience(invitationAudience))
 
 	if err != nil {
 		return -1, err
@@ -59,12 +138,110 @@ func ValidateJWT(tokenString, tokenSecret string) (int, error) {
 		return -1, fmt.Errorf("invalid or expired token")
 	}
 
-	userID, err := strconv.Atoi(claims.Subject)
+	invitationID, err := strconv.ParseInt(claims.Subject, 10, 32)
+	if err != nil {
+		return -1, fmt.Errorf("invalid invitation ID: %v", err)
+	}
+
+	return int32(invitationID), nil
+}
+
+// Audience of email verification tokens, so they can't be mistaken for other tokens.
+const emailVerificationAudience = "tailscribe:email-verification"
+
+type emailVerificationClaims struct {
+	// The address the link was sent to. Changing it makes the link stop working.
+	Email string `json:"email"`
+	jwt.RegisteredClaims
+}
+
+func emailVerificationKey(tokenSecret string) []byte {
+	mac := hmac.New(sha256.New, []byte(tokenSecret))
+	mac.Write([]byte(emailVerificationAudience))
+	return mac.Sum(nil)
+}
+
+// Signs a user and their email into a token for a verification link that stops working at expiresAt.
+func MakeEmailVerificationToken(userID int32, email string, expiresAt time.Time, tokenSecret string) (string, error) {
+	claims := emailVerificationClaims{
+		Email: email,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    "tailscribe",
+			Audience:  jwt.ClaimStrings{emailVerificationAudience},
+			IssuedAt:  jwt.NewNumericDate(time.Now()),
+			ExpiresAt: jwt.NewNumericDate(expiresAt),
+			Subject:   strconv.Itoa(int(userID)),
+		},
+	}
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	return token.SignedString(emailVerificationKey(tokenSecret))
+}
+
+// Returns the user id and email from a token made by MakeEmailVerificationToken.
+func ValidateEmailVerificationToken(tokenString, tokenSecret string) (int32, string, error) {
+	token, err := jwt.ParseWithClaims(tokenString, &emailVerificationClaims{}, func(token *jwt.Token) (interface{}, error) {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, fmt.Errorf("unexpected signing method: %v", token.Method)
+		}
+
+		return emailVerificationKey(tokenSecret), nil
+	}, jwt.WithAudience(emailVerificationAudience))
+
+	if err != nil {
+		return -1, "", err
+	}
+
+	claims, ok := token.Claims.(*emailVerificationClaims)
+	if !ok || !token.Valid {
+		return -1, "", fmt.Errorf("invalid or expired token")
+	}
+
+	userID, err := strconv.ParseInt(claims.Subject, 10, 32)
 	if err != nil {
-		return -1, fmt.Errorf("invalid user ID: %v", err)
+		return -1, "", fmt.Errorf("invalid user ID: %v", err)
 	}
 
-	return userID, nil
+	return int32(userID), claims.Email, nil
+}
+
+// Key for SignValue, derived from the token secret so a signed value is never a valid JWT signature.
+func signedValueKey(tokenSecret string) []byte {
+	mac := hmac.New(sha256.New, []byte(tokenSecret))
+	mac.Write([]byte("tailscribe:signed-value"))
+	return mac.Sum(nil)
+}
+
+// Appends an HMAC to value, so it can be handed to the browser and trusted when it comes back.
+func SignValue(value, tokenSecret string) string {
+	mac := hmac.New(sha256.New, signedValueKey(tokenSecret))
+	mac.Write([]byte(value))
+	return base64.RawURLEncoding.EncodeToString([]byte(value)) + "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// Returns the value from a SignValue result, or false if it was tampered with.
+func VerifySignedValue(signed, tokenSecret string) (string, bool) {
+	encodedValue, encodedMAC, found := strings.Cut(signed, ".")
+	if !found {
+		return "", false
+	}
+
+	value, err := base64.RawURLEncoding.DecodeString(encodedValue)
+	if err != nil {
+		return "", false
+	}
+
+	gotMAC, err := base64.RawURLEncoding.DecodeString(encodedMAC)
+	if err != nil {
+		return "", false
+	}
+
+	mac := hmac.New(sha256.New, signedValueKey(tokenSecret))
+	mac.Write(value)
+	if !hmac.Equal(gotMAC, mac.Sum(nil)) {
+		return "", false
+	}
+
+	return string(value), true
 }
 
 func MakeRefreshToken() (string, error) {
@@ -77,3 +254,34 @@ func MakeRefreshToken() (string, error) {
 	hexString := hex.EncodeToString(key)
 	return hexString, nil
 }
+
+// Reset tokens are random like refresh tokens. Only their hash is stored.
+func MakeResetPasswordToken() (string, error) {
+	return MakeRefreshToken()
+}
+
+// Browsers that aren't signed in are told apart by a random value in a cookie, like a refresh token.
+func MakePreAuthToken() (string, error) {
+	return MakeRefreshToken()
+}
+
+// Key for CSRF tokens, derived from the token secret like the other signing keys.
+func csrfKey(tokenSecret string) []byte {
+	mac := hmac.New(sha256.New, []byte(tokenSecret))
+	mac.Write([]byte("tailscribe:csrf"))
+	return mac.Sum(nil)
+}
+
+// CSRF tokens are an HMAC of what identifies the browser, so they can be
+// worked out again for each request instead of stored, and change with it.
+func MakeCSRFToken(binding, tokenSecret string) string {
+	mac := hmac.New(sha256.New, csrfKey(tokenSecret))
+	mac.Write([]byte(binding))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// Refresh and password reset tokens are stored by hash, so the database alone can't be used to sign in.
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -4,42 +4,19 @@ import (
 	"crypto/rand"
 	"math/big"
 	"testing"
+	"time"
 
+	"github.com/google/uuid"
 	"github.com/stretchr/testify/assert"
 )
 
-// Sanity tests.
-func TestHashPassword(t *testing.T) {
-	var hashTests = []struct {
-		password string
-	}{
-		{
-			"",
-		},
-		{
-			"antidisestablishmenterianism",
-		},
-	}
-
-	for _, tt := range hashTests {
-		t.Run(tt.password, func(t *testing.T) {
-			result, err := HashPassword(tt.password)
-			assert.NoError(t, err)
-
-			passwordMatchesHash := CheckPasswordHash(tt.password, result)
-
-			assert.True(t, passwordMatchesHash)
-		})
-	}
-}
-
 func TestValidateJWT(t *testing.T) {
 	t.Run("happy path", func(t *testing.T) {
 		secret := "test_secret_key"
 		userID, _ := rand.Int(rand.Reader, big.NewInt(64))
 		convertedUserId := int32(userID.Int64())
 
-		tokenString, err := MakeJWT(convertedUserId, secret)
+		tokenString, err := MakeJWT(convertedUserId, uuid.New(), secret)
 		if err != nil {
 			t.Errorf("Failed to create JWT: %v", err)
 		}
@@ -55,7 +32,7 @@ func TestValidateJWT(t *testing.T) {
 		userID, _ := rand.Int(rand.Reader, big.NewInt(64))
 		convertedUserId := int32(userID.Int64())
 
-		tokenString, err := MakeJWT(convertedUserId, secret)
+		tokenString, err := MakeJWT(convertedUserId, uuid.New(), secret)
 		if err != nil {
 			t.Errorf("failed to create jwt: %v", err)
 		}
@@ -66,3 +43,135 @@ func TestValidateJWT(t *testing.T) {
 		}
 	})
 }
+
+func TestValidateSessionJWT(t *testing.T) {
+	secret := "test_secret_key"
+	sessionID := uuid.New()
+
+	tokenString, err := MakeJWT(42, sessionID, secret)
+	assert.NoError(t, err)
+
+	userID, decodedSessionID, err := ValidateSessionJWT(tokenString, secret)
+	assert.NoError(t, err)
+	assert.Equal(t, 42, userID)
+	assert.Equal(t, sessionID, decodedSessionID)
+
+	_, _, err = ValidateSessionJWT(tokenString, "fakeSecret")
+	assert.Error(t, err)
+}
+
+func TestJWTSessionID(t *testing.T) {
+	sessionID := uuid.New()
+	tokenString, err := MakeJWT(42, sessionID, "test_secret_key")
+	assert.NoError(t, err)
+
+	decoded, err := JWTSessionID(tokenString, "test_secret_key")
+	assert.NoError(t, err)
+	assert.Equal(t, sessionID, decoded)
+
+	_, err = JWTSessionID(tokenString, "fakeSecret")
+	assert.Error(t, err)
+}
+
+func TestMakeCSRFToken(t *testing.T) {
+	token := MakeCSRFToken("browser:abc", "test_secret_key")
+
+	assert.Equal(t, token, MakeCSRFToken("browser:abc", "test_secret_key"))
+	assert.NotEqual(t, token, MakeCSRFToken("browser:abd", "test_secret_key"))
+	assert.NotEqual(t, token, MakeCSRFToken("browser:abc", "other_secret"))
+}
+
+func TestValidateInvitationToken(t *testing.T) {
+	secret := "test_secret_key"
+
+	t.Run("happy path", func(t *testing.T) {
+		tokenString, err := MakeInvitationToken(42, time.Now().Add(time.Hour), secret)
+		assert.NoError(t, err)
+
+		invitationID, err := ValidateInvitationToken(tokenString, secret)
+		assert.NoError(t, err)
+		assert.Equal(t, int32(42), invitationID)
+	})
+
+	t.Run("Expired token", func(t *testing.T) {
+		tokenString, _ := MakeInvitationToken(42, time.Now().Add(-time.Minute), secret)
+
+		_, err := ValidateInvitationToken(tokenString, secret)
+		assert.Error(t, err)
+	})
+
+	t.Run("Session tokens are not invitations", func(t *testing.T) {
+		tokenString, _ := MakeJWT(42, uuid.New(), secret)
+
+		_, err := ValidateInvitationToken(tokenString, secret)
+		assert.Error(t, err)
+	})
+
+	t.Run("Invitations are not session tokens", func(t *testing.T) {
+		tokenString, _ := MakeInvitationToken(42, time.Now().Add(time.Hour), secret)
+
+		_, err := ValidateJWT(tokenString, secret)
+		assert.Error(t, err)
+	})
+}
+
+func TestValidateEmailVerificationToken(t *testing.T) {
+	secret := "test_secret_key"
+
+	t.Run("happy path", func(t *testing.T) {
+		tokenString, err := MakeEmailVerificationToken(42, "rex@test.com", time.Now().Add(time.Hour), secret)
+		assert.NoError(t, err)
+
+		userID, email, err := ValidateEmailVerificationToken(tokenString, secret)
+		assert.NoError(t, err)
+		assert.Equal(t, int32(42), userID)
+		assert.Equal(t, "rex@test.com", email)
+	})
+
+	t.Run("Expired token", func(t *testing.T) {
+		tokenString, _ := MakeEmailVerificationToken(42, "rex@test.com", time.Now().Add(-time.Minute), secret)
+
+		_, _, err := ValidateEmailVerificationToken(tokenString, secret)
+		assert.Error(t, err)
+	})
+
+	t.Run("Invitations don't verify emails", func(t *testing.T) {
+		tokenString, _ := MakeInvitationToken(42, time.Now().Add(time.Hour), secret)
+
+		_, _, err := ValidateEmailVerificationToken(tokenString, secret)
+		assert.Error(t, err)
+	})
+}
+
+func TestHashToken(t *testing.T) {
+	token, err := MakeRefreshToken()
+	assert.NoError(t, err)
+
+	hash := HashToken(token)
+
+	assert.Equal(t, hash, HashToken(token))
+	assert.NotEqual(t, token, hash)
+	assert.Len(t, hash, 64)
+
+	otherToken, err := MakeRefreshToken()
+	assert.NoError(t, err)
+	assert.NotEqual(t, hash, HashToken(otherToken))
+}
+
+func TestSignValue(t *testing.T) {
+	signed := SignValue("login|0|state|nonce", "test_secret_key")
+
+	value, ok := VerifySignedValue(signed, "test_secret_key")
+	assert.True(t, ok)
+	assert.Equal(t, "login|0|state|nonce", value)
+
+	_, ok = VerifySignedValue(signed, "other_secret")
+	assert.False(t, ok)
+
+	tampered := SignValue("link|1|state|nonce", "other_secret")
+	_, ok = VerifySignedValue(tampered, "test_secret_key")
+	assert.False(t, ok)
+
+	_, ok = VerifySignedValue("no-signature", "test_secret_key")
+	assert.False(t, ok)
+}
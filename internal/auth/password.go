@@ -0,0 +1,225 @@
+package auth
+
+import (
+	"bufio"
+	"crypto/rand"
+	"crypto/subtle"
+	_ "embed"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Password hashing algorithms a PasswordPolicy can use for new hashes.
+const (
+	PasswordBcrypt   = "bcrypt"
+	PasswordArgon2id = "argon2id"
+)
+
+var (
+	ErrPasswordTooShort  = errors.New("password is too short")
+	ErrPasswordTooLong   = errors.New("password is too long")
+	ErrPasswordTooCommon = errors.New("password is too common")
+)
+
+// bcrypt ignores everything past this many bytes, so longer passwords are refused.
+const bcryptMaxLength = 72
+
+// Stops huge passwords from tying up the server while they're hashed.
+const argon2MaxLength = 1024
+
+//go:embed common_passwords.txt
+var commonPasswordsFile string
+
+// Lowercased passwords from common_passwords.txt.
+var commonPasswords = parseDenylist(commonPasswordsFile)
+
+func parseDenylist(contents string) map[string]bool {
+	denylist := map[string]bool{}
+	scanner := bufio.NewScanner(strings.NewReader(contents))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		denylist[strings.ToLower(line)] = true
+	}
+	return denylist
+}
+
+// Tuning for argon2id. Memory is in KiB.
+type Argon2Params struct {
+	Time    uint32
+	Memory  uint32
+	Threads uint8
+}
+
+// How new passwords are checked and hashed. Hashes made under older settings
+// still verify, and CheckPasswordHash says when they should be replaced.
+type PasswordPolicy struct {
+	// PasswordBcrypt or PasswordArgon2id.
+	Algorithm  string
+	BcryptCost int
+	Argon2     Argon2Params
+	// In characters, not bytes.
+	MinLength int
+}
+
+func DefaultPasswordPolicy() PasswordPolicy {
+	return PasswordPolicy{
+		Algorithm:  PasswordBcrypt,
+		BcryptCost: 14,
+		// The second recommended setting in RFC 9106, for when memory is scarcer.
+		Argon2: Argon2Params{
+			Time:    3,
+			Memory:  64 * 1024,
+			Threads: 4,
+		},
+		MinLength: 8,
+	}
+}
+
+// Reports settings that can't hash anything.
+func (p PasswordPolicy) Check() error {
+	switch p.Algorithm {
+	case PasswordBcrypt:
+		if p.BcryptCost < bcrypt.MinCost || p.BcryptCost > bcrypt.MaxCost {
+			return fmt.Errorf("bcrypt cost %d isn't between %d and %d", p.BcryptCost, bcrypt.MinCost, bcrypt.MaxCost)
+		}
+	case PasswordArgon2id:
+		if p.Argon2.Time < 1 || p.Argon2.Memory < 8*uint32(p.Argon2.Threads) || p.Argon2.Threads < 1 {
+			return fmt.Errorf("argon2id needs a time and threads of at least 1, and 8 KiB of memory per thread")
+		}
+	default:
+		return fmt.Errorf("unknown password algorithm %q", p.Algorithm)
+	}
+
+	if p.MinLength < 1 {
+		return fmt.Errorf("minimum password length %d is less than 1", p.MinLength)
+	}
+
+	return nil
+}
+
+// Checks a new password against the policy, before it's hashed.
+func (p PasswordPolicy) ValidatePassword(password string) error {
+	if len([]rune(password)) < p.MinLength {
+		return ErrPasswordTooShort
+	}
+
+	maxLength := argon2MaxLength
+	if p.Algorithm == PasswordBcrypt {
+		maxLength = bcryptMaxLength
+	}
+	if len(password) > maxLength {
+		return ErrPasswordTooLong
+	}
+
+	if commonPasswords[strings.ToLower(password)] {
+		return ErrPasswordTooCommon
+	}
+
+	return nil
+}
+
+func (p PasswordPolicy) HashPassword(password string) (string, error) {
+	if p.Algorithm == PasswordArgon2id {
+		return hashArgon2id(password, p.Argon2)
+	}
+
+	bytes, err := bcrypt.GenerateFromPassword([]byte(password), p.BcryptCost)
+	if err != nil {
+		return "", err
+	}
+
+	return string(bytes), nil
+}
+
+// Reports whether the password matches the hash, and if so, whether the hash
+// was made under different settings and should be replaced with a fresh one.
+func (p PasswordPolicy) CheckPasswordHash(password, hash string) (match bool, rehash bool) {
+	if strings.HasPrefix(hash, "$"+PasswordArgon2id+"$") {
+		params, salt, key, err := parseArgon2id(hash)
+		if err != nil {
+			return false, false
+		}
+
+		other := argon2.IDKey([]byte(password), salt, params.Time, params.Memory, params.Threads, uint32(len(key)))
+		if subtle.ConstantTimeCompare(key, other) != 1 {
+			return false, false
+		}
+
+		return true, p.Algorithm != PasswordArgon2id || params != p.Argon2
+	}
+
+	err := bcrypt.CompareHashAndPassword([]byte(hash), []byte(password))
+	if err != nil {
+		return false, false
+	}
+
+	cost, err := bcrypt.Cost([]byte(hash))
+	return true, err != nil || p.Algorithm != PasswordBcrypt || cost != p.BcryptCost
+}
+
+const (
+	argon2SaltLength = 16
+	argon2KeyLength  = 32
+)
+
+// Hashes in the PHC string format: $argon2id$v=19$m=65536,t=3,p=4$salt$key
+func hashArgon2id(password string, params Argon2Params) (string, error) {
+	salt := make([]byte, argon2SaltLength)
+	_, err := rand.Read(salt)
+	if err != nil {
+		return "", err
+	}
+
+	key := argon2.IDKey([]byte(password), salt, params.Time, params.Memory, params.Threads, argon2KeyLength)
+
+	return fmt.Sprintf("$%s$v=%d$m=%d,t=%d,p=%d$%s$%s",
+		PasswordArgon2id,
+		argon2.Version,
+		params.Memory,
+		params.Time,
+		params.Threads,
+		base64.RawStdEncoding.EncodeToString(salt),
+		base64.RawStdEncoding.EncodeToString(key),
+	), nil
+}
+
+var errInvalidArgon2Hash = errors.New("invalid argon2id hash")
+
+func parseArgon2id(hash string) (Argon2Params, []byte, []byte, error) {
+	parts := strings.Split(hash, "$")
+	if len(parts) != 6 || parts[1] != PasswordArgon2id {
+		return Argon2Params{}, nil, nil, errInvalidArgon2Hash
+	}
+
+	var version int
+	_, err := fmt.Sscanf(parts[2], "v=%d", &version)
+	if err != nil || version != argon2.Version {
+		return Argon2Params{}, nil, nil, errInvalidArgon2Hash
+	}
+
+	var params Argon2Params
+	_, err = fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &params.Memory, &params.Time, &params.Threads)
+	if err != nil {
+		return Argon2Params{}, nil, nil, errInvalidArgon2Hash
+	}
+
+	salt, err := base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil {
+		return Argon2Params{}, nil, nil, errInvalidArgon2Hash
+	}
+
+	key, err := base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil || len(key) == 0 {
+		return Argon2Params{}, nil, nil, errInvalidArgon2Hash
+	}
+
+	return params, salt, key, nil
+}
@@ -0,0 +1,133 @@
+package auth
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Cheap settings, so the tests don't spend their time hashing.
+func testPasswordPolicy(algorithm string) PasswordPolicy {
+	return PasswordPolicy{
+		Algorithm:  algorithm,
+		BcryptCost: bcrypt.MinCost,
+		Argon2:     Argon2Params{Time: 1, Memory: 64, Threads: 1},
+		MinLength:  8,
+	}
+}
+
+// Sanity tests.
+func TestHashPassword(t *testing.T) {
+	var hashTests = []struct {
+		password string
+	}{
+		{
+			"",
+		},
+		{
+			"antidisestablishmenterianism",
+		},
+	}
+
+	for _, algorithm := range []string{PasswordBcrypt, PasswordArgon2id} {
+		policy := testPasswordPolicy(algorithm)
+		for _, tt := range hashTests {
+			t.Run(algorithm+" "+tt.password, func(t *testing.T) {
+				result, err := policy.HashPassword(tt.password)
+				assert.NoError(t, err)
+
+				passwordMatchesHash, rehash := policy.CheckPasswordHash(tt.password, result)
+				assert.True(t, passwordMatchesHash)
+				assert.False(t, rehash)
+
+				passwordMatchesHash, _ = policy.CheckPasswordHash(tt.password+"x", result)
+				assert.False(t, passwordMatchesHash)
+			})
+		}
+	}
+}
+
+func TestCheckPasswordHashRehash(t *testing.T) {
+	bcryptPolicy := testPasswordPolicy(PasswordBcrypt)
+	argon2Policy := testPasswordPolicy(PasswordArgon2id)
+
+	bcryptHash, err := bcryptPolicy.HashPassword("correct horse")
+	assert.NoError(t, err)
+	argon2Hash, err := argon2Policy.HashPassword("correct horse")
+	assert.NoError(t, err)
+	assert.True(t, strings.HasPrefix(argon2Hash, "$argon2id$v=19$m=64,t=1,p=1$"))
+
+	t.Run("Moving to another algorithm", func(t *testing.T) {
+		match, rehash := argon2Policy.CheckPasswordHash("correct horse", bcryptHash)
+		assert.True(t, match)
+		assert.True(t, rehash)
+
+		match, rehash = bcryptPolicy.CheckPasswordHash("correct horse", argon2Hash)
+		assert.True(t, match)
+		assert.True(t, rehash)
+	})
+
+	t.Run("Raising the bcrypt cost", func(t *testing.T) {
+		stronger := bcryptPolicy
+		stronger.BcryptCost++
+
+		match, rehash := stronger.CheckPasswordHash("correct horse", bcryptHash)
+		assert.True(t, match)
+		assert.True(t, rehash)
+	})
+
+	t.Run("Changing the argon2id parameters", func(t *testing.T) {
+		stronger := argon2Policy
+		stronger.Argon2.Time++
+
+		match, rehash := stronger.CheckPasswordHash("correct horse", argon2Hash)
+		assert.True(t, match)
+		assert.True(t, rehash)
+	})
+
+	t.Run("Never asks to rehash a wrong password", func(t *testing.T) {
+		match, rehash := argon2Policy.CheckPasswordHash("wrong horse", bcryptHash)
+		assert.False(t, match)
+		assert.False(t, rehash)
+	})
+
+	t.Run("Rejects malformed hashes", func(t *testing.T) {
+		match, _ := argon2Policy.CheckPasswordHash("correct horse", "$argon2id$v=19$m=64,t=1,p=1$bad")
+		assert.False(t, match)
+	})
+}
+
+func TestValidatePassword(t *testing.T) {
+	policy := testPasswordPolicy(PasswordBcrypt)
+
+	assert.NoError(t, policy.ValidatePassword("tail-wagging-4-treats"))
+	assert.ErrorIs(t, policy.ValidatePassword("short"), ErrPasswordTooShort)
+	assert.ErrorIs(t, policy.ValidatePassword(""), ErrPasswordTooShort)
+	assert.ErrorIs(t, policy.ValidatePassword("Password123"), ErrPasswordTooCommon)
+	assert.ErrorIs(t, policy.ValidatePassword(strings.Repeat("a", bcryptMaxLength+1)), ErrPasswordTooLong)
+
+	// Length counts characters, not bytes.
+	assert.NoError(t, policy.ValidatePassword("ééééééé1"))
+
+	policy.Algorithm = PasswordArgon2id
+	assert.NoError(t, policy.ValidatePassword(strings.Repeat("ab", bcryptMaxLength)))
+}
+
+func TestPasswordPolicyCheck(t *testing.T) {
+	assert.NoError(t, DefaultPasswordPolicy().Check())
+	assert.NoError(t, testPasswordPolicy(PasswordArgon2id).Check())
+
+	policy := DefaultPasswordPolicy()
+	policy.Algorithm = "md5"
+	assert.Error(t, policy.Check())
+
+	policy = DefaultPasswordPolicy()
+	policy.BcryptCost = 40
+	assert.Error(t, policy.Check())
+
+	policy = DefaultPasswordPolicy()
+	policy.MinLength = 0
+	assert.Error(t, policy.Check())
+}
@@ -0,0 +1,207 @@
+package auth
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base32"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"image/png"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pquerna/otp"
+	"github.com/pquerna/otp/totp"
+)
+
+// Name authenticator apps list accounts under.
+const totpIssuer = "TailScribe"
+
+// How long each code lasts, in seconds.
+const totpPeriod = 30
+
+var totpOpts = totp.ValidateOpts{
+	Period:    totpPeriod,
+	Digits:    otp.DigitsSix,
+	Algorithm: otp.AlgorithmSHA1,
+}
+
+// Makes a new random TOTP secret, base32 encoded as authenticator apps expect.
+func GenerateTOTPSecret() (string, error) {
+	raw := make([]byte, 20)
+	_, err := rand.Read(raw)
+	if err != nil {
+		return "", err
+	}
+
+	return base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString(raw), nil
+}
+
+// The otpauth:// URL an authenticator app scans to add the secret.
+func TOTPURL(secret, accountName string) string {
+	query := url.Values{}
+	query.Set("secret", secret)
+	query.Set("issuer", totpIssuer)
+	query.Set("period", strconv.Itoa(totpPeriod))
+	query.Set("digits", totpOpts.Digits.String())
+	query.Set("algorithm", totpOpts.Algorithm.String())
+
+	u := url.URL{
+		Scheme:   "otpauth",
+		Host:     "totp",
+		Path:     "/" + totpIssuer + ":" + accountName,
+		RawQuery: query.Encode(),
+	}
+	return u.String()
+}
+
+// Draws an otpauth:// URL as a PNG QR code.
+func TOTPQRCode(otpauthURL string, size int) ([]byte, error) {
+	key, err := otp.NewKeyFromURL(otpauthURL)
+	if err != nil {
+		return nil, err
+	}
+
+	img, err := key.Image(size, size)
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	err = png.Encode(&buf, img)
+	if err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+// Checks a code against the secret, allowing a period of clock drift either
+// way. Returns the time step the code belongs to, so the caller can refuse a
+// code that was already used.
+func ValidateTOTP(secret, code string, now time.Time) (int64, bool) {
+	code = strings.ReplaceAll(strings.TrimSpace(code), " ", "")
+	if len(code) != int(totpOpts.Digits) {
+		return 0, false
+	}
+
+	step := now.Unix() / totpPeriod
+	for _, candidate := range []int64{step - 1, step, step + 1} {
+		expected, err := totp.GenerateCodeCustom(secret, time.Unix(candidate*totpPeriod, 0), totpOpts)
+		if err != nil {
+			return 0, false
+		}
+		if subtle.ConstantTimeCompare([]byte(code), []byte(expected)) == 1 {
+			return candidate, true
+		}
+	}
+
+	return 0, false
+}
+
+// Characters in recovery codes, without ones that are easy to misread.
+const recoveryCodeAlphabet = "abcdefghjkmnpqrstuvwxyz23456789"
+
+// Makes n random one-time recovery codes, formatted like "abcde-23456".
+func MakeRecoveryCodes(n int) ([]string, error) {
+	codes := make([]string, 0, n)
+	for range n {
+		raw := make([]byte, 10)
+		_, err := rand.Read(raw)
+		if err != nil {
+			return nil, err
+		}
+
+		code := make([]byte, len(raw))
+		for i, b := range raw {
+			// 256 isn't a multiple of the alphabet size, so a few characters are
+			// very slightly more likely. With 10 characters that costs nothing that matters.
+			code[i] = recoveryCodeAlphabet[int(b)%len(recoveryCodeAlphabet)]
+		}
+		codes = append(codes, string(code[:5])+"-"+string(code[5:]))
+	}
+
+	return codes, nil
+}
+
+// Hashes a recovery code for storage, ignoring case, spaces and dashes.
+func HashRecoveryCode(code string) string {
+	normalized := strings.ToLower(code)
+	normalized = strings.NewReplacer("-", "", " ", "").Replace(normalized)
+	return HashToken(normalized)
+}
+
+var errInvalidCiphertext = errors.New("invalid ciphertext")
+
+// Reads a 32 byte AES key written as 64 hex characters.
+func ParseEncryptionKey(hexKey string) ([]byte, error) {
+	key, err := hex.DecodeString(hexKey)
+	if err != nil {
+		return nil, fmt.Errorf("encryption key isn't hex: %v", err)
+	}
+	if len(key) != 32 {
+		return nil, fmt.Errorf("encryption key is %d bytes, want 32", len(key))
+	}
+	return key, nil
+}
+
+// An encryption key derived from the token secret, for when no separate key is configured.
+func DeriveEncryptionKey(tokenSecret string) []byte {
+	mac := hmac.New(sha256.New, []byte(tokenSecret))
+	mac.Write([]byte("tailscribe:encryption"))
+	return mac.Sum(nil)
+}
+
+// Encrypts a value for storage with AES-256-GCM. The result holds the nonce and ciphertext.
+func EncryptSecret(plaintext string, key []byte) (string, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return "", err
+	}
+
+	nonce := make([]byte, gcm.NonceSize())
+	_, err = rand.Read(nonce)
+	if err != nil {
+		return "", err
+	}
+
+	sealed := gcm.Seal(nonce, nonce, []byte(plaintext), nil)
+	return base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// Decrypts a value from EncryptSecret.
+func DecryptSecret(ciphertext string, key []byte) (string, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return "", err
+	}
+
+	sealed, err := base64.StdEncoding.DecodeString(ciphertext)
+	if err != nil || len(sealed) < gcm.NonceSize() {
+		return "", errInvalidCiphertext
+	}
+
+	plaintext, err := gcm.Open(nil, sealed[:gcm.NonceSize()], sealed[gcm.NonceSize():], nil)
+	if err != nil {
+		return "", errInvalidCiphertext
+	}
+
+	return string(plaintext), nil
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
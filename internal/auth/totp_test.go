@@ -0,0 +1,102 @@
+package auth
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pquerna/otp/totp"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateTOTP(t *testing.T) {
+	secret, err := GenerateTOTPSecret()
+	assert.NoError(t, err)
+
+	now := time.Unix(1_700_000_000, 0)
+	code, err := totp.GenerateCodeCustom(secret, now, totpOpts)
+	assert.NoError(t, err)
+
+	t.Run("Accepts the current code", func(t *testing.T) {
+		step, ok := ValidateTOTP(secret, code, now)
+		assert.True(t, ok)
+		assert.Equal(t, now.Unix()/totpPeriod, step)
+	})
+
+	t.Run("Allows a period of clock drift", func(t *testing.T) {
+		step, ok := ValidateTOTP(secret, code, now.Add(totpPeriod*time.Second))
+		assert.True(t, ok)
+		assert.Equal(t, now.Unix()/totpPeriod, step)
+	})
+
+	t.Run("Rejects an old code", func(t *testing.T) {
+		_, ok := ValidateTOTP(secret, code, now.Add(3*totpPeriod*time.Second))
+		assert.False(t, ok)
+	})
+
+	t.Run("Ignores spaces", func(t *testing.T) {
+		_, ok := ValidateTOTP(secret, " "+code[:3]+" "+code[3:], now)
+		assert.True(t, ok)
+	})
+
+	t.Run("Rejects a wrong code", func(t *testing.T) {
+		_, ok := ValidateTOTP(secret, "12345", now)
+		assert.False(t, ok)
+	})
+}
+
+func TestTOTPURL(t *testing.T) {
+	url := TOTPURL("JBSWY3DPEHPK3PXP", "rex&co@test.com")
+
+	assert.True(t, strings.HasPrefix(url, "otpauth://totp/TailScribe:rex&co@test.com?"))
+	assert.Contains(t, url, "secret=JBSWY3DPEHPK3PXP")
+	assert.Contains(t, url, "issuer=TailScribe")
+	assert.Contains(t, url, "digits=6")
+}
+
+func TestTOTPQRCode(t *testing.T) {
+	secret, err := GenerateTOTPSecret()
+	assert.NoError(t, err)
+
+	image, err := TOTPQRCode(TOTPURL(secret, "rex@test.com"), 200)
+	assert.NoError(t, err)
+	assert.True(t, strings.HasPrefix(string(image), "\x89PNG"))
+}
+
+func TestMakeRecoveryCodes(t *testing.T) {
+	codes, err := MakeRecoveryCodes(10)
+	assert.NoError(t, err)
+	assert.Len(t, codes, 10)
+
+	seen := map[string]bool{}
+	for _, code := range codes {
+		assert.Len(t, code, 11)
+		assert.Equal(t, byte('-'), code[5])
+		assert.False(t, seen[code])
+		seen[code] = true
+	}
+
+	assert.Equal(t, HashRecoveryCode(codes[0]), HashRecoveryCode(" "+strings.ToUpper(strings.Replace(codes[0], "-", "", 1))))
+}
+
+func TestEncryptSecret(t *testing.T) {
+	key, err := ParseEncryptionKey(strings.Repeat("ab", 32))
+	assert.NoError(t, err)
+
+	ciphertext, err := EncryptSecret("JBSWY3DPEHPK3PXP", key)
+	assert.NoError(t, err)
+	assert.NotContains(t, ciphertext, "JBSWY3DPEHPK3PXP")
+
+	plaintext, err := DecryptSecret(ciphertext, key)
+	assert.NoError(t, err)
+	assert.Equal(t, "JBSWY3DPEHPK3PXP", plaintext)
+
+	_, err = DecryptSecret(ciphertext, DeriveEncryptionKey("another secret"))
+	assert.Error(t, err)
+
+	_, err = DecryptSecret("not base64!", key)
+	assert.Error(t, err)
+
+	_, err = ParseEncryptionKey("abcd")
+	assert.Error(t, err)
+}
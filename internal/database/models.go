@@ -7,8 +7,46 @@ package database
 import (
 	"database/sql"
 	"time"
+
+	"github.com/google/uuid"
 )
 
+type Goal struct {
+	ID          int32
+	PetID       int32
+	Description string
+	TargetDate  sql.NullTime
+	Status      string
+	AchievedAt  sql.NullTime
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+}
+
+type GoalProgressNote struct {
+	ID        int32
+	GoalID    int32
+	UserID    sql.NullInt32
+	Note      string
+	CreatedAt time.Time
+}
+
+type GoalSkill struct {
+	GoalID  int32
+	SkillID int32
+}
+
+type Like struct {
+	ID        int32
+	PetID     int32
+	Name      string
+	Category  string
+	ValueTier string
+	Notes     sql.NullString
+	Rank      int32
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
 type Pet struct {
 	ID                 int32
 	Name               string
@@ -26,6 +64,112 @@ type Pet struct {
 	Titleshidden       bool
 	CreatedAt          time.Time
 	UpdatedAt          time.Time
+	Slug               string
+}
+
+type PetInvitation struct {
+	ID               int32
+	PetID            int32
+	Email            string
+	PermissionsLevel int32
+	InvitedBy        sql.NullInt32
+	ExpiresAt        time.Time
+	AcceptedBy       sql.NullInt32
+	AcceptedAt       sql.NullTime
+	RevokedAt        sql.NullTime
+	CreatedAt        time.Time
+	UpdatedAt        time.Time
+}
+
+type RateLimit struct {
+	Key               string
+	Attempts          int32
+	LastAttemptAt     time.Time
+	PreviousAttemptAt sql.NullTime
+}
+
+type RecoveryCode struct {
+	ID        int32
+	UserID    int32
+	CodeHash  string
+	CreatedAt time.Time
+	UsedAt    sql.NullTime
+}
+
+type RefreshToken struct {
+	ID        int32
+	UserID    int32
+	TokenHash string
+	FamilyID  uuid.UUID
+	ExpiresAt time.Time
+	RevokedAt sql.NullTime
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+type Session struct {
+	ID         uuid.UUID
+	UserID     int32
+	UserAgent  string
+	IpAddress  string
+	CreatedAt  time.Time
+	LastSeenAt time.Time
+	RevokedAt  sql.NullTime
+}
+
+type Skill struct {
+	ID        int32
+	PetID     int32
+	Name      string
+	Stage     string
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+type SkillStageChange struct {
+	ID        int32
+	SkillID   int32
+	FromStage sql.NullString
+	ToStage   string
+	SessionID sql.NullInt32
+	UserID    sql.NullInt32
+	ChangedAt time.Time
+}
+
+type Title struct {
+	ID                  int32
+	PetID               int32
+	Name                string
+	Abbreviation        sql.NullString
+	Position            string
+	Organization        sql.NullString
+	EarnedOn            time.Time
+	CertificateImageUrl sql.NullString
+	CreatedAt           time.Time
+	UpdatedAt           time.Time
+}
+
+type TrainingSession struct {
+	ID              int32
+	PetID           int32
+	UserID          sql.NullInt32
+	StartedAt       time.Time
+	DurationMinutes int32
+	Location        sql.NullString
+	Notes           sql.NullString
+	CreatedAt       time.Time
+	UpdatedAt       time.Time
+}
+
+type TrainingSessionLike struct {
+	SessionID int32
+	LikeID    int32
+}
+
+type TrainingSessionBehavior struct {
+	ID        int32
+	SessionID int32
+	Name      string
 }
 
 type User struct {
@@ -37,13 +181,21 @@ type User struct {
 	Password             sql.NullString
 	FacebookID           sql.NullString
 	ResetPasswordToken   sql.NullString
-	ResetPasswordExpires sql.NullString
+	ResetPasswordExpires sql.NullTime
 	IsPremium            bool
 	PremiumLevel         int32
 	StripeCustomerID     sql.NullString
 	IsDeleted            bool
 	CreatedAt            time.Time
 	UpdatedAt            time.Time
+	OidcIssuer           sql.NullString
+	OidcSubject          sql.NullString
+	DeletedAt            sql.NullTime
+	TotpSecret           sql.NullString
+	TotpEnabledAt        sql.NullTime
+	TotpLastStep         sql.NullInt64
+	IsAdmin              bool
+	EmailVerifiedAt      sql.NullTime
 }
 
 type Userpet struct {
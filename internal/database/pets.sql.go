@@ -11,23 +11,25 @@ import (
 )
 
 const createPet = `-- name: CreatePet :one
-INSERT INTO pet(name, imageUrl, created_at, updated_at)
+INSERT INTO pet(name, imageUrl, slug, created_at, updated_at)
 VALUES (
     $1,
     $2,
+    $3,
     NOW(),
     NOW()
 )
-RETURNING id, name, dateofbirth, dateofbirthexact, imageurl, about_text, species, breed, sex, ispubliclyviewable, likeshidden, skillshidden, goalshidden, titleshidden, created_at, updated_at
+RETURNING id, name, dateofbirth, dateofbirthexact, imageurl, about_text, species, breed, sex, ispubliclyviewable, likeshidden, skillshidden, goalshidden, titleshidden, created_at, updated_at, slug
 `
 
 type CreatePetParams struct {
 	Name     string
 	Imageurl sql.NullString
+	Slug     string
 }
 
 func (q *Queries) CreatePet(ctx context.Context, arg CreatePetParams) (Pet, error) {
-	row := q.db.QueryRowContext(ctx, createPet, arg.Name, arg.Imageurl)
+	row := q.db.QueryRowContext(ctx, createPet, arg.Name, arg.Imageurl, arg.Slug)
 	var i Pet
 	err := row.Scan(
 		&i.ID,
@@ -46,6 +48,7 @@ func (q *Queries) CreatePet(ctx context.Context, arg CreatePetParams) (Pet, erro
 		&i.Titleshidden,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.Slug,
 	)
 	return i, err
 }
@@ -99,6 +102,42 @@ func (q *Queries) DeletePets(ctx context.Context) error {
 	return err
 }
 
+const deleteSolePetsForUser = `-- name: DeleteSolePetsForUser :exec
+DELETE FROM pet
+WHERE id IN (
+    SELECT petId
+    FROM UserPets
+    WHERE userId = $1
+)
+AND NOT EXISTS (
+    SELECT 1
+    FROM UserPets other
+    WHERE other.petId = pet.id
+        AND other.userId <> $1
+)
+`
+
+// Deletes the pets nobody but the user is linked to.
+func (q *Queries) DeleteSolePetsForUser(ctx context.Context, userID int32) error {
+	_, err := q.db.ExecContext(ctx, deleteSolePetsForUser, userID)
+	return err
+}
+
+const deleteUserPet = `-- name: DeleteUserPet :exec
+DELETE FROM UserPets
+WHERE userId = $1 AND petId = $2
+`
+
+type DeleteUserPetParams struct {
+	Userid int32
+	Petid  int32
+}
+
+func (q *Queries) DeleteUserPet(ctx context.Context, arg DeleteUserPetParams) error {
+	_, err := q.db.ExecContext(ctx, deleteUserPet, arg.Userid, arg.Petid)
+	return err
+}
+
 const deleteUserPets = `-- name: DeleteUserPets :exec
 DELETE FROM UserPets
 `
@@ -107,3 +146,445 @@ func (q *Queries) DeleteUserPets(ctx context.Context) error {
 	_, err := q.db.ExecContext(ctx, deleteUserPets)
 	return err
 }
+
+const getPet = `-- name: GetPet :one
+SELECT id, name, dateofbirth, dateofbirthexact, imageurl, about_text, species, breed, sex, ispubliclyviewable, likeshidden, skillshidden, goalshidden, titleshidden, created_at, updated_at, slug
+FROM pet
+WHERE id = $1
+`
+
+func (q *Queries) GetPet(ctx context.Context, id int32) (Pet, error) {
+	row := q.db.QueryRowContext(ctx, getPet, id)
+	var i Pet
+	err := row.Scan(
+		&i.ID,
+		&i.Name,
+		&i.Dateofbirth,
+		&i.Dateofbirthexact,
+		&i.Imageurl,
+		&i.AboutText,
+		&i.Species,
+		&i.Breed,
+		&i.Sex,
+		&i.Ispubliclyviewable,
+		&i.Likeshidden,
+		&i.Skillshidden,
+		&i.Goalshidden,
+		&i.Titleshidden,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Slug,
+	)
+	return i, err
+}
+
+const getPublicPetBySlug = `-- name: GetPublicPetBySlug :one
+SELECT id, name, dateofbirth, dateofbirthexact, imageurl, about_text, species, breed, sex, ispubliclyviewable, likeshidden, skillshidden, goalshidden, titleshidden, created_at, updated_at, slug
+FROM pet
+WHERE slug = $1
+    AND ispubliclyviewable
+    AND EXISTS (
+        SELECT 1
+        FROM UserPets
+        JOIN users ON users.id = UserPets.userId
+        WHERE UserPets.petId = pet.id
+            AND UserPets.permissions_level = 4
+            AND users.deleted_at IS NULL
+    )
+`
+
+// Only while an owner's account is still around, so deleting an account takes
+// its pets' profiles down straight away rather than when it's purged.
+func (q *Queries) GetPublicPetBySlug(ctx context.Context, slug string) (Pet, error) {
+	row := q.db.QueryRowContext(ctx, getPublicPetBySlug, slug)
+	var i Pet
+	err := row.Scan(
+		&i.ID,
+		&i.Name,
+		&i.Dateofbirth,
+		&i.Dateofbirthexact,
+		&i.Imageurl,
+		&i.AboutText,
+		&i.Species,
+		&i.Breed,
+		&i.Sex,
+		&i.Ispubliclyviewable,
+		&i.Likeshidden,
+		&i.Skillshidden,
+		&i.Goalshidden,
+		&i.Titleshidden,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Slug,
+	)
+	return i, err
+}
+
+const getUserPet = `-- name: GetUserPet :one
+SELECT userid, petid, permissions_level, active, hidden, created_at, updated_at
+FROM UserPets
+WHERE userId = $1 AND petId = $2
+`
+
+type GetUserPetParams struct {
+	Userid int32
+	Petid  int32
+}
+
+func (q *Queries) GetUserPet(ctx context.Context, arg GetUserPetParams) (Userpet, error) {
+	row := q.db.QueryRowContext(ctx, getUserPet, arg.Userid, arg.Petid)
+	var i Userpet
+	err := row.Scan(
+		&i.Userid,
+		&i.Petid,
+		&i.PermissionsLevel,
+		&i.Active,
+		&i.Hidden,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
+const listPetAccess = `-- name: ListPetAccess :many
+SELECT UserPets.userId, UserPets.permissions_level, users.email
+FROM UserPets
+JOIN users ON users.id = UserPets.userId
+WHERE UserPets.petId = $1
+ORDER BY UserPets.permissions_level DESC, users.email
+`
+
+type ListPetAccessRow struct {
+	Userid           int32
+	PermissionsLevel int32
+	Email            sql.NullString
+}
+
+func (q *Queries) ListPetAccess(ctx context.Context, petid int32) ([]ListPetAccessRow, error) {
+	rows, err := q.db.QueryContext(ctx, listPetAccess, petid)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListPetAccessRow
+	for rows.Next() {
+		var i ListPetAccessRow
+		if err := rows.Scan(&i.Userid, &i.PermissionsLevel, &i.Email); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listPetsForUser = `-- name: ListPetsForUser :many
+SELECT pet.id, pet.name, pet.dateofbirth, pet.dateofbirthexact, pet.imageurl, pet.about_text, pet.species, pet.breed, pet.sex, pet.ispubliclyviewable, pet.likeshidden, pet.skillshidden, pet.goalshidden, pet.titleshidden, pet.created_at, pet.updated_at, pet.slug, UserPets.permissions_level, UserPets.active, UserPets.hidden,
+    (
+        SELECT MAX(training_sessions.started_at)
+        FROM training_sessions
+        WHERE training_sessions.pet_id = pet.id
+    ) AS last_session_at,
+    (
+        SELECT COUNT(*)
+        FROM goals
+        WHERE goals.pet_id = pet.id
+            AND goals.status = 'open'
+    ) AS open_goals
+FROM pet
+JOIN UserPets ON UserPets.petId = pet.id
+WHERE UserPets.userId = $1
+ORDER BY pet.name, pet.id
+`
+
+type ListPetsForUserRow struct {
+	Pet              Pet
+	PermissionsLevel int32
+	Active           bool
+	Hidden           bool
+	LastSessionAt    sql.NullTime
+	OpenGoals        int64
+}
+
+// With what the dashboard shows about each, so it takes one query however many pets there are.
+func (q *Queries) ListPetsForUser(ctx context.Context, userid int32) ([]ListPetsForUserRow, error) {
+	rows, err := q.db.QueryContext(ctx, listPetsForUser, userid)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListPetsForUserRow
+	for rows.Next() {
+		var i ListPetsForUserRow
+		if err := rows.Scan(
+			&i.Pet.ID,
+			&i.Pet.Name,
+			&i.Pet.Dateofbirth,
+			&i.Pet.Dateofbirthexact,
+			&i.Pet.Imageurl,
+			&i.Pet.AboutText,
+			&i.Pet.Species,
+			&i.Pet.Breed,
+			&i.Pet.Sex,
+			&i.Pet.Ispubliclyviewable,
+			&i.Pet.Likeshidden,
+			&i.Pet.Skillshidden,
+			&i.Pet.Goalshidden,
+			&i.Pet.Titleshidden,
+			&i.Pet.CreatedAt,
+			&i.Pet.UpdatedAt,
+			&i.Pet.Slug,
+			&i.PermissionsLevel,
+			&i.Active,
+			&i.Hidden,
+			&i.LastSessionAt,
+			&i.OpenGoals,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const promotePetHeirsForUser = `-- name: PromotePetHeirsForUser :exec
+UPDATE UserPets
+SET permissions_level = 4,
+    updated_at = NOW()
+FROM (
+    SELECT DISTINCT ON (heir.petId) heir.petId, heir.userId
+    FROM UserPets owned
+    JOIN UserPets heir ON heir.petId = owned.petId AND heir.userId <> owned.userId
+    WHERE owned.userId = $1
+        AND owned.permissions_level = 4
+        AND NOT EXISTS (
+            SELECT 1
+            FROM UserPets other_owner
+            WHERE other_owner.petId = owned.petId
+                AND other_owner.userId <> owned.userId
+                AND other_owner.permissions_level = 4
+        )
+    ORDER BY heir.petId, heir.permissions_level DESC, heir.created_at
+) heirs
+WHERE UserPets.petId = heirs.petId
+    AND UserPets.userId = heirs.userId
+`
+
+// Makes someone else the owner of each pet the user is the only owner of,
+// picking the member with the most access who joined first.
+func (q *Queries) PromotePetHeirsForUser(ctx context.Context, userID int32) error {
+	_, err := q.db.ExecContext(ctx, promotePetHeirsForUser, userID)
+	return err
+}
+
+const updatePet = `-- name: UpdatePet :one
+UPDATE pet
+SET name = $2,
+    dateOfBirth = $3,
+    dateOfBirthExact = $4,
+    imageUrl = $5,
+    about_text = $6,
+    species = $7,
+    breed = $8,
+    sex = $9,
+    updated_at = NOW()
+WHERE id = $1
+RETURNING id, name, dateofbirth, dateofbirthexact, imageurl, about_text, species, breed, sex, ispubliclyviewable, likeshidden, skillshidden, goalshidden, titleshidden, created_at, updated_at, slug
+`
+
+type UpdatePetParams struct {
+	ID               int32
+	Name             string
+	Dateofbirth      sql.NullTime
+	Dateofbirthexact sql.NullBool
+	Imageurl         sql.NullString
+	AboutText        sql.NullString
+	Species          sql.NullString
+	Breed            sql.NullString
+	Sex              sql.NullString
+}
+
+func (q *Queries) UpdatePet(ctx context.Context, arg UpdatePetParams) (Pet, error) {
+	row := q.db.QueryRowContext(ctx, updatePet,
+		arg.ID,
+		arg.Name,
+		arg.Dateofbirth,
+		arg.Dateofbirthexact,
+		arg.Imageurl,
+		arg.AboutText,
+		arg.Species,
+		arg.Breed,
+		arg.Sex,
+	)
+	var i Pet
+	err := row.Scan(
+		&i.ID,
+		&i.Name,
+		&i.Dateofbirth,
+		&i.Dateofbirthexact,
+		&i.Imageurl,
+		&i.AboutText,
+		&i.Species,
+		&i.Breed,
+		&i.Sex,
+		&i.Ispubliclyviewable,
+		&i.Likeshidden,
+		&i.Skillshidden,
+		&i.Goalshidden,
+		&i.Titleshidden,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Slug,
+	)
+	return i, err
+}
+
+const updatePetGoalsHidden = `-- name: UpdatePetGoalsHidden :exec
+UPDATE pet
+SET goalsHidden = $2,
+    updated_at = NOW()
+WHERE id = $1
+`
+
+type UpdatePetGoalsHiddenParams struct {
+	ID          int32
+	Goalshidden bool
+}
+
+func (q *Queries) UpdatePetGoalsHidden(ctx context.Context, arg UpdatePetGoalsHiddenParams) error {
+	_, err := q.db.ExecContext(ctx, updatePetGoalsHidden, arg.ID, arg.Goalshidden)
+	return err
+}
+
+const updatePetLikesHidden = `-- name: UpdatePetLikesHidden :exec
+UPDATE pet
+SET likesHidden = $2,
+    updated_at = NOW()
+WHERE id = $1
+`
+
+type UpdatePetLikesHiddenParams struct {
+	ID          int32
+	Likeshidden bool
+}
+
+func (q *Queries) UpdatePetLikesHidden(ctx context.Context, arg UpdatePetLikesHiddenParams) error {
+	_, err := q.db.ExecContext(ctx, updatePetLikesHidden, arg.ID, arg.Likeshidden)
+	return err
+}
+
+const updatePetPubliclyViewable = `-- name: UpdatePetPubliclyViewable :exec
+UPDATE pet
+SET isPubliclyViewable = $2,
+    updated_at = NOW()
+WHERE id = $1
+`
+
+type UpdatePetPubliclyViewableParams struct {
+	ID                 int32
+	Ispubliclyviewable bool
+}
+
+func (q *Queries) UpdatePetPubliclyViewable(ctx context.Context, arg UpdatePetPubliclyViewableParams) error {
+	_, err := q.db.ExecContext(ctx, updatePetPubliclyViewable, arg.ID, arg.Ispubliclyviewable)
+	return err
+}
+
+const updatePetSkillsHidden = `-- name: UpdatePetSkillsHidden :exec
+UPDATE pet
+SET skillsHidden = $2,
+    updated_at = NOW()
+WHERE id = $1
+`
+
+type UpdatePetSkillsHiddenParams struct {
+	ID           int32
+	Skillshidden bool
+}
+
+func (q *Queries) UpdatePetSkillsHidden(ctx context.Context, arg UpdatePetSkillsHiddenParams) error {
+	_, err := q.db.ExecContext(ctx, updatePetSkillsHidden, arg.ID, arg.Skillshidden)
+	return err
+}
+
+const updatePetTitlesHidden = `-- name: UpdatePetTitlesHidden :exec
+UPDATE pet
+SET titlesHidden = $2,
+    updated_at = NOW()
+WHERE id = $1
+`
+
+type UpdatePetTitlesHiddenParams struct {
+	ID           int32
+	Titleshidden bool
+}
+
+func (q *Queries) UpdatePetTitlesHidden(ctx context.Context, arg UpdatePetTitlesHiddenParams) error {
+	_, err := q.db.ExecContext(ctx, updatePetTitlesHidden, arg.ID, arg.Titleshidden)
+	return err
+}
+
+const updateUserPetActive = `-- name: UpdateUserPetActive :exec
+UPDATE UserPets
+SET active = $3,
+    updated_at = NOW()
+WHERE userId = $1 AND petId = $2
+`
+
+type UpdateUserPetActiveParams struct {
+	Userid int32
+	Petid  int32
+	Active bool
+}
+
+func (q *Queries) UpdateUserPetActive(ctx context.Context, arg UpdateUserPetActiveParams) error {
+	_, err := q.db.ExecContext(ctx, updateUserPetActive, arg.Userid, arg.Petid, arg.Active)
+	return err
+}
+
+const updateUserPetHidden = `-- name: UpdateUserPetHidden :exec
+UPDATE UserPets
+SET hidden = $3,
+    updated_at = NOW()
+WHERE userId = $1 AND petId = $2
+`
+
+type UpdateUserPetHiddenParams struct {
+	Userid int32
+	Petid  int32
+	Hidden bool
+}
+
+func (q *Queries) UpdateUserPetHidden(ctx context.Context, arg UpdateUserPetHiddenParams) error {
+	_, err := q.db.ExecContext(ctx, updateUserPetHidden, arg.Userid, arg.Petid, arg.Hidden)
+	return err
+}
+
+const updateUserPetPermissions = `-- name: UpdateUserPetPermissions :exec
+UPDATE UserPets
+SET permissions_level = $3,
+    updated_at = NOW()
+WHERE userId = $1 AND petId = $2
+`
+
+type UpdateUserPetPermissionsParams struct {
+	Userid           int32
+	Petid            int32
+	PermissionsLevel int32
+}
+
+func (q *Queries) UpdateUserPetPermissions(ctx context.Context, arg UpdateUserPetPermissionsParams) error {
+	_, err := q.db.ExecContext(ctx, updateUserPetPermissions, arg.Userid, arg.Petid, arg.PermissionsLevel)
+	return err
+}
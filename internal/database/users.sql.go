@@ -11,23 +11,25 @@ import (
 )
 
 const createUser = `-- name: CreateUser :one
-INSERT INTO users(created_at, updated_at, email, password)
+INSERT INTO users(created_at, updated_at, email, password, email_verified_at)
 VALUES (
     NOW(),
     NOW(),
     $1,
-    $2
+    $2,
+    $3
 )
-RETURNING id, email, username, firstname, lastname, password, facebook_id, reset_password_token, reset_password_expires, is_premium, premium_level, stripe_customer_id, is_deleted, created_at, updated_at
+RETURNING id, email, username, firstname, lastname, password, facebook_id, reset_password_token, reset_password_expires, is_premium, premium_level, stripe_customer_id, is_deleted, created_at, updated_at, oidc_issuer, oidc_subject, deleted_at, totp_secret, totp_enabled_at, totp_last_step, is_admin, email_verified_at
 `
 
 type CreateUserParams struct {
-	Email    sql.NullString
-	Password sql.NullString
+	Email           sql.NullString
+	Password        sql.NullString
+	EmailVerifiedAt sql.NullTime
 }
 
 func (q *Queries) CreateUser(ctx context.Context, arg CreateUserParams) (User, error) {
-	row := q.db.QueryRowContext(ctx, createUser, arg.Email, arg.Password)
+	row := q.db.QueryRowContext(ctx, createUser, arg.Email, arg.Password, arg.EmailVerifiedAt)
 	var i User
 	err := row.Scan(
 		&i.ID,
@@ -45,10 +47,28 @@ func (q *Queries) CreateUser(ctx context.Context, arg CreateUserParams) (User, e
 		&i.IsDeleted,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.OidcIssuer,
+		&i.OidcSubject,
+		&i.DeletedAt,
+		&i.TotpSecret,
+		&i.TotpEnabledAt,
+		&i.TotpLastStep,
+		&i.IsAdmin,
+		&i.EmailVerifiedAt,
 	)
 	return i, err
 }
 
+const deleteUser = `-- name: DeleteUser :exec
+DELETE FROM users
+WHERE id = $1
+`
+
+func (q *Queries) DeleteUser(ctx context.Context, id int32) error {
+	_, err := q.db.ExecContext(ctx, deleteUser, id)
+	return err
+}
+
 const deleteUsers = `-- name: DeleteUsers :exec
 DELETE FROM users
 `
@@ -58,13 +78,127 @@ func (q *Queries) DeleteUsers(ctx context.Context) error {
 	return err
 }
 
+const disableUserTOTP = `-- name: DisableUserTOTP :exec
+UPDATE users
+SET totp_secret = NULL,
+    totp_enabled_at = NULL,
+    totp_last_step = NULL,
+    updated_at = NOW()
+WHERE id = $1
+`
+
+func (q *Queries) DisableUserTOTP(ctx context.Context, id int32) error {
+	_, err := q.db.ExecContext(ctx, disableUserTOTP, id)
+	return err
+}
+
+const enableUserTOTP = `-- name: EnableUserTOTP :execrows
+UPDATE users
+SET totp_enabled_at = NOW(),
+    totp_last_step = $2,
+    updated_at = NOW()
+WHERE id = $1
+    AND totp_secret IS NOT NULL
+    AND totp_enabled_at IS NULL
+`
+
+type EnableUserTOTPParams struct {
+	ID           int32
+	TotpLastStep sql.NullInt64
+}
+
+// Finishes enrollment with the step of the code that confirmed it.
+func (q *Queries) EnableUserTOTP(ctx context.Context, arg EnableUserTOTPParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, enableUserTOTP, arg.ID, arg.TotpLastStep)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const getUser = `-- name: GetUser :one
+SELECT id, email, username, firstname, lastname, password, facebook_id, reset_password_token, reset_password_expires, is_premium, premium_level, stripe_customer_id, is_deleted, created_at, updated_at, oidc_issuer, oidc_subject, deleted_at, totp_secret, totp_enabled_at, totp_last_step, is_admin, email_verified_at
+FROM users
+WHERE id = $1
+`
+
+func (q *Queries) GetUser(ctx context.Context, id int32) (User, error) {
+	row := q.db.QueryRowContext(ctx, getUser, id)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.Email,
+		&i.Username,
+		&i.Firstname,
+		&i.Lastname,
+		&i.Password,
+		&i.FacebookID,
+		&i.ResetPasswordToken,
+		&i.ResetPasswordExpires,
+		&i.IsPremium,
+		&i.PremiumLevel,
+		&i.StripeCustomerID,
+		&i.IsDeleted,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.OidcIssuer,
+		&i.OidcSubject,
+		&i.DeletedAt,
+		&i.TotpSecret,
+		&i.TotpEnabledAt,
+		&i.TotpLastStep,
+		&i.IsAdmin,
+		&i.EmailVerifiedAt,
+	)
+	return i, err
+}
+
+const getDeletedUserByEmail = `-- name: GetDeletedUserByEmail :one
+SELECT id, email, username, firstname, lastname, password, facebook_id, reset_password_token, reset_password_expires, is_premium, premium_level, stripe_customer_id, is_deleted, created_at, updated_at, oidc_issuer, oidc_subject, deleted_at, totp_secret, totp_enabled_at, totp_last_step, is_admin, email_verified_at
+FROM users
+WHERE LOWER(email) = LOWER($1)
+    AND is_deleted
+`
+
+func (q *Queries) GetDeletedUserByEmail(ctx context.Context, email string) (User, error) {
+	row := q.db.QueryRowContext(ctx, getDeletedUserByEmail, email)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.Email,
+		&i.Username,
+		&i.Firstname,
+		&i.Lastname,
+		&i.Password,
+		&i.FacebookID,
+		&i.ResetPasswordToken,
+		&i.ResetPasswordExpires,
+		&i.IsPremium,
+		&i.PremiumLevel,
+		&i.StripeCustomerID,
+		&i.IsDeleted,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.OidcIssuer,
+		&i.OidcSubject,
+		&i.DeletedAt,
+		&i.TotpSecret,
+		&i.TotpEnabledAt,
+		&i.TotpLastStep,
+		&i.IsAdmin,
+		&i.EmailVerifiedAt,
+	)
+	return i, err
+}
+
 const getUserByEmail = `-- name: GetUserByEmail :one
-SELECT id, email, username, firstname, lastname, password, facebook_id, reset_password_token, reset_password_expires, is_premium, premium_level, stripe_customer_id, is_deleted, created_at, updated_at
+SELECT id, email, username, firstname, lastname, password, facebook_id, reset_password_token, reset_password_expires, is_premium, premium_level, stripe_customer_id, is_deleted, created_at, updated_at, oidc_issuer, oidc_subject, deleted_at, totp_secret, totp_enabled_at, totp_last_step, is_admin, email_verified_at
 FROM users
-WHERE email = $1
+WHERE LOWER(email) = LOWER($1)
+    AND NOT is_deleted
 `
 
-func (q *Queries) GetUserByEmail(ctx context.Context, email sql.NullString) (User, error) {
+func (q *Queries) GetUserByEmail(ctx context.Context, email string) (User, error) {
 	row := q.db.QueryRowContext(ctx, getUserByEmail, email)
 	var i User
 	err := row.Scan(
@@ -83,6 +217,371 @@ func (q *Queries) GetUserByEmail(ctx context.Context, email sql.NullString) (Use
 		&i.IsDeleted,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.OidcIssuer,
+		&i.OidcSubject,
+		&i.DeletedAt,
+		&i.TotpSecret,
+		&i.TotpEnabledAt,
+		&i.TotpLastStep,
+		&i.IsAdmin,
+		&i.EmailVerifiedAt,
 	)
 	return i, err
 }
+
+const getUserByFacebookID = `-- name: GetUserByFacebookID :one
+SELECT id, email, username, firstname, lastname, password, facebook_id, reset_password_token, reset_password_expires, is_premium, premium_level, stripe_customer_id, is_deleted, created_at, updated_at, oidc_issuer, oidc_subject, deleted_at, totp_secret, totp_enabled_at, totp_last_step, is_admin, email_verified_at
+FROM users
+WHERE facebook_id = $1
+    AND NOT is_deleted
+`
+
+func (q *Queries) GetUserByFacebookID(ctx context.Context, facebookID sql.NullString) (User, error) {
+	row := q.db.QueryRowContext(ctx, getUserByFacebookID, facebookID)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.Email,
+		&i.Username,
+		&i.Firstname,
+		&i.Lastname,
+		&i.Password,
+		&i.FacebookID,
+		&i.ResetPasswordToken,
+		&i.ResetPasswordExpires,
+		&i.IsPremium,
+		&i.PremiumLevel,
+		&i.StripeCustomerID,
+		&i.IsDeleted,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.OidcIssuer,
+		&i.OidcSubject,
+		&i.DeletedAt,
+		&i.TotpSecret,
+		&i.TotpEnabledAt,
+		&i.TotpLastStep,
+		&i.IsAdmin,
+		&i.EmailVerifiedAt,
+	)
+	return i, err
+}
+
+const getUserByOIDCIdentity = `-- name: GetUserByOIDCIdentity :one
+SELECT id, email, username, firstname, lastname, password, facebook_id, reset_password_token, reset_password_expires, is_premium, premium_level, stripe_customer_id, is_deleted, created_at, updated_at, oidc_issuer, oidc_subject, deleted_at, totp_secret, totp_enabled_at, totp_last_step, is_admin, email_verified_at
+FROM users
+WHERE oidc_issuer = $1
+    AND oidc_subject = $2
+    AND NOT is_deleted
+`
+
+type GetUserByOIDCIdentityParams struct {
+	OidcIssuer  sql.NullString
+	OidcSubject sql.NullString
+}
+
+func (q *Queries) GetUserByOIDCIdentity(ctx context.Context, arg GetUserByOIDCIdentityParams) (User, error) {
+	row := q.db.QueryRowContext(ctx, getUserByOIDCIdentity, arg.OidcIssuer, arg.OidcSubject)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.Email,
+		&i.Username,
+		&i.Firstname,
+		&i.Lastname,
+		&i.Password,
+		&i.FacebookID,
+		&i.ResetPasswordToken,
+		&i.ResetPasswordExpires,
+		&i.IsPremium,
+		&i.PremiumLevel,
+		&i.StripeCustomerID,
+		&i.IsDeleted,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.OidcIssuer,
+		&i.OidcSubject,
+		&i.DeletedAt,
+		&i.TotpSecret,
+		&i.TotpEnabledAt,
+		&i.TotpLastStep,
+		&i.IsAdmin,
+		&i.EmailVerifiedAt,
+	)
+	return i, err
+}
+
+const getUserByResetPasswordToken = `-- name: GetUserByResetPasswordToken :one
+SELECT id, email, username, firstname, lastname, password, facebook_id, reset_password_token, reset_password_expires, is_premium, premium_level, stripe_customer_id, is_deleted, created_at, updated_at, oidc_issuer, oidc_subject, deleted_at, totp_secret, totp_enabled_at, totp_last_step, is_admin, email_verified_at
+FROM users
+WHERE reset_password_token = $1
+    AND reset_password_expires > NOW()
+    AND NOT is_deleted
+`
+
+func (q *Queries) GetUserByResetPasswordToken(ctx context.Context, resetPasswordToken sql.NullString) (User, error) {
+	row := q.db.QueryRowContext(ctx, getUserByResetPasswordToken, resetPasswordToken)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.Email,
+		&i.Username,
+		&i.Firstname,
+		&i.Lastname,
+		&i.Password,
+		&i.FacebookID,
+		&i.ResetPasswordToken,
+		&i.ResetPasswordExpires,
+		&i.IsPremium,
+		&i.PremiumLevel,
+		&i.StripeCustomerID,
+		&i.IsDeleted,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.OidcIssuer,
+		&i.OidcSubject,
+		&i.DeletedAt,
+		&i.TotpSecret,
+		&i.TotpEnabledAt,
+		&i.TotpLastStep,
+		&i.IsAdmin,
+		&i.EmailVerifiedAt,
+	)
+	return i, err
+}
+
+const listUsersToPurge = `-- name: ListUsersToPurge :many
+SELECT id
+FROM users
+WHERE is_deleted
+    AND deleted_at < $1
+`
+
+func (q *Queries) ListUsersToPurge(ctx context.Context, deletedAt sql.NullTime) ([]int32, error) {
+	rows, err := q.db.QueryContext(ctx, listUsersToPurge, deletedAt)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []int32
+	for rows.Next() {
+		var id int32
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		items = append(items, id)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const markUserEmailVerified = `-- name: MarkUserEmailVerified :execrows
+UPDATE users
+SET email_verified_at = COALESCE(email_verified_at, NOW()),
+    updated_at = NOW()
+WHERE id = $1
+    AND LOWER(email) = LOWER($2)
+    AND NOT is_deleted
+`
+
+type MarkUserEmailVerifiedParams struct {
+	ID    int32
+	Email string
+}
+
+// Verifies the user's email, if it's still the address the link was sent to.
+func (q *Queries) MarkUserEmailVerified(ctx context.Context, arg MarkUserEmailVerifiedParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, markUserEmailVerified, arg.ID, arg.Email)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const rehashUserPassword = `-- name: RehashUserPassword :exec
+UPDATE users
+SET password = $1,
+    updated_at = NOW()
+WHERE id = $2
+    AND password = $3
+`
+
+type RehashUserPasswordParams struct {
+	Password    sql.NullString
+	ID          int32
+	OldPassword sql.NullString
+}
+
+// Swaps in a new hash of the same password, unless it changed in the meantime.
+func (q *Queries) RehashUserPassword(ctx context.Context, arg RehashUserPasswordParams) error {
+	_, err := q.db.ExecContext(ctx, rehashUserPassword, arg.Password, arg.ID, arg.OldPassword)
+	return err
+}
+
+const resetUserPassword = `-- name: ResetUserPassword :one
+UPDATE users
+SET password = $2,
+    reset_password_token = NULL,
+    reset_password_expires = NULL,
+    updated_at = NOW()
+WHERE reset_password_token = $1
+    AND reset_password_expires > NOW()
+    AND NOT is_deleted
+RETURNING id, email, username, firstname, lastname, password, facebook_id, reset_password_token, reset_password_expires, is_premium, premium_level, stripe_customer_id, is_deleted, created_at, updated_at, oidc_issuer, oidc_subject, deleted_at, totp_secret, totp_enabled_at, totp_last_step, is_admin, email_verified_at
+`
+
+type ResetUserPasswordParams struct {
+	ResetPasswordToken sql.NullString
+	Password           sql.NullString
+}
+
+func (q *Queries) ResetUserPassword(ctx context.Context, arg ResetUserPasswordParams) (User, error) {
+	row := q.db.QueryRowContext(ctx, resetUserPassword, arg.ResetPasswordToken, arg.Password)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.Email,
+		&i.Username,
+		&i.Firstname,
+		&i.Lastname,
+		&i.Password,
+		&i.FacebookID,
+		&i.ResetPasswordToken,
+		&i.ResetPasswordExpires,
+		&i.IsPremium,
+		&i.PremiumLevel,
+		&i.StripeCustomerID,
+		&i.IsDeleted,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.OidcIssuer,
+		&i.OidcSubject,
+		&i.DeletedAt,
+		&i.TotpSecret,
+		&i.TotpEnabledAt,
+		&i.TotpLastStep,
+		&i.IsAdmin,
+		&i.EmailVerifiedAt,
+	)
+	return i, err
+}
+
+const setUserTOTPSecret = `-- name: SetUserTOTPSecret :exec
+UPDATE users
+SET totp_secret = $2,
+    totp_enabled_at = NULL,
+    totp_last_step = NULL,
+    updated_at = NOW()
+WHERE id = $1
+`
+
+type SetUserTOTPSecretParams struct {
+	ID         int32
+	TotpSecret sql.NullString
+}
+
+// Starts enrollment. Two-factor stays off until EnableUserTOTP.
+func (q *Queries) SetUserTOTPSecret(ctx context.Context, arg SetUserTOTPSecretParams) error {
+	_, err := q.db.ExecContext(ctx, setUserTOTPSecret, arg.ID, arg.TotpSecret)
+	return err
+}
+
+const softDeleteUser = `-- name: SoftDeleteUser :exec
+UPDATE users
+SET is_deleted = TRUE,
+    deleted_at = NOW(),
+    facebook_id = NULL,
+    oidc_issuer = NULL,
+    oidc_subject = NULL,
+    updated_at = NOW()
+WHERE id = $1
+`
+
+// Frees the user's social logins, so the same provider accounts can sign up
+// again. The email stays with them until they're purged, in case they're restored.
+func (q *Queries) SoftDeleteUser(ctx context.Context, id int32) error {
+	_, err := q.db.ExecContext(ctx, softDeleteUser, id)
+	return err
+}
+
+const updateUserFacebookID = `-- name: UpdateUserFacebookID :exec
+UPDATE users
+SET facebook_id = $2,
+    updated_at = NOW()
+WHERE id = $1
+`
+
+type UpdateUserFacebookIDParams struct {
+	ID         int32
+	FacebookID sql.NullString
+}
+
+func (q *Queries) UpdateUserFacebookID(ctx context.Context, arg UpdateUserFacebookIDParams) error {
+	_, err := q.db.ExecContext(ctx, updateUserFacebookID, arg.ID, arg.FacebookID)
+	return err
+}
+
+const updateUserOIDCIdentity = `-- name: UpdateUserOIDCIdentity :exec
+UPDATE users
+SET oidc_issuer = $2,
+    oidc_subject = $3,
+    updated_at = NOW()
+WHERE id = $1
+`
+
+type UpdateUserOIDCIdentityParams struct {
+	ID          int32
+	OidcIssuer  sql.NullString
+	OidcSubject sql.NullString
+}
+
+func (q *Queries) UpdateUserOIDCIdentity(ctx context.Context, arg UpdateUserOIDCIdentityParams) error {
+	_, err := q.db.ExecContext(ctx, updateUserOIDCIdentity, arg.ID, arg.OidcIssuer, arg.OidcSubject)
+	return err
+}
+
+const updateUserResetPasswordToken = `-- name: UpdateUserResetPasswordToken :exec
+UPDATE users
+SET reset_password_token = $2,
+    reset_password_expires = $3,
+    updated_at = NOW()
+WHERE id = $1
+`
+
+type UpdateUserResetPasswordTokenParams struct {
+	ID                   int32
+	ResetPasswordToken   sql.NullString
+	ResetPasswordExpires sql.NullTime
+}
+
+func (q *Queries) UpdateUserResetPasswordToken(ctx context.Context, arg UpdateUserResetPasswordTokenParams) error {
+	_, err := q.db.ExecContext(ctx, updateUserResetPasswordToken, arg.ID, arg.ResetPasswordToken, arg.ResetPasswordExpires)
+	return err
+}
+
+const useUserTOTPStep = `-- name: UseUserTOTPStep :execrows
+UPDATE users
+SET totp_last_step = $2
+WHERE id = $1
+    AND (totp_last_step IS NULL OR totp_last_step < $2)
+`
+
+type UseUserTOTPStepParams struct {
+	ID           int32
+	TotpLastStep sql.NullInt64
+}
+
+// Records an accepted code's time step. Affects no rows if that step, or a
+// later one, was already used.
+func (q *Queries) UseUserTOTPStep(ctx context.Context, arg UseUserTOTPStepParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, useUserTOTPStep, arg.ID, arg.TotpLastStep)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
@@ -0,0 +1,82 @@
+package mailer
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+	"time"
+)
+
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Sends email. Handlers only ever see this interface, so the sink can be swapped per environment.
+type Mailer interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+// Writes messages to the log instead of sending them.
+type LogMailer struct {
+	From   string
+	Logger *slog.Logger
+}
+
+func (m *LogMailer) Send(ctx context.Context, msg Message) error {
+	m.Logger.InfoContext(ctx, "email",
+		slog.String("from", m.From),
+		slog.String("to", msg.To),
+		slog.String("subject", msg.Subject),
+		slog.String("body", msg.Body),
+	)
+	return nil
+}
+
+// Writes each message to its own .eml file in Dir, so it can be read back offline.
+type FileMailer struct {
+	From string
+	Dir  string
+}
+
+func (m *FileMailer) Send(ctx context.Context, msg Message) error {
+	err := os.MkdirAll(m.Dir, 0o755)
+	if err != nil {
+		return err
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "From: %s\r\n", m.From)
+	fmt.Fprintf(&b, "To: %s\r\n", msg.To)
+	fmt.Fprintf(&b, "Subject: %s\r\n", msg.Subject)
+	fmt.Fprintf(&b, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	b.WriteString("\r\n")
+	b.WriteString(msg.Body)
+
+	f, err := os.CreateTemp(m.Dir, fmt.Sprintf("%d-*.eml", time.Now().UnixNano()))
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	_, err = f.WriteString(b.String())
+	return err
+}
+
+// Picks a mailer by sink name: "log" (the default) or "file".
+func New(sink, dir, from string, logger *slog.Logger) (Mailer, error) {
+	switch sink {
+	case "", "log":
+		return &LogMailer{From: from, Logger: logger}, nil
+	case "file":
+		if dir == "" {
+			return nil, fmt.Errorf("the file mailer needs a directory")
+		}
+		return &FileMailer{From: from, Dir: dir}, nil
+	default:
+		return nil, fmt.Errorf("unknown mailer %q", sink)
+	}
+}
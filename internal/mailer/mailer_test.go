@@ -0,0 +1,62 @@
+package mailer
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFileMailer(t *testing.T) {
+	dir := t.TempDir()
+	m := &FileMailer{From: "hello@tailscribe.test", Dir: dir}
+
+	err := m.Send(context.Background(), Message{To: "a@test.com", Subject: "First", Body: "one"})
+	assert.NoError(t, err)
+	err = m.Send(context.Background(), Message{To: "b@test.com", Subject: "Second", Body: "two"})
+	assert.NoError(t, err)
+
+	files, err := filepath.Glob(filepath.Join(dir, "*.eml"))
+	assert.NoError(t, err)
+	assert.Len(t, files, 2)
+
+	contents, err := os.ReadFile(files[0])
+	assert.NoError(t, err)
+	assert.Contains(t, string(contents), "From: hello@tailscribe.test\r\n")
+	assert.Contains(t, string(contents), "To: a@test.com\r\n")
+	assert.Contains(t, string(contents), "Subject: First\r\n")
+	assert.Contains(t, string(contents), "\r\n\r\none")
+}
+
+func TestLogMailer(t *testing.T) {
+	var buf bytes.Buffer
+	m := &LogMailer{Logger: slog.New(slog.NewTextHandler(&buf, nil))}
+
+	err := m.Send(context.Background(), Message{To: "a@test.com", Subject: "Hi", Body: "there"})
+
+	assert.NoError(t, err)
+	assert.Contains(t, buf.String(), "to=a@test.com")
+	assert.Contains(t, buf.String(), "body=there")
+}
+
+func TestNew(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+
+	m, err := New("", "", "", logger)
+	assert.NoError(t, err)
+	assert.IsType(t, &LogMailer{}, m)
+
+	m, err = New("file", t.TempDir(), "", logger)
+	assert.NoError(t, err)
+	assert.IsType(t, &FileMailer{}, m)
+
+	_, err = New("file", "", "", logger)
+	assert.Error(t, err)
+
+	_, err = New("smtp", "", "", logger)
+	assert.Error(t, err)
+}
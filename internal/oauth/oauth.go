@@ -0,0 +1,54 @@
+// Package oauth signs users in through third-party OAuth2 and OpenID Connect providers.
+package oauth
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+	"time"
+)
+
+// Providers that aren't given an HTTP client use this one, so a provider that
+// stops answering can't hold a sign-in open forever.
+var defaultHTTPClient = &http.Client{Timeout: 10 * time.Second}
+
+func httpClientOrDefault(client *http.Client) *http.Client {
+	if client != nil {
+		return client
+	}
+	return defaultHTTPClient
+}
+
+// A user as a provider knows them.
+type Identity struct {
+	// The provider's stable id for the user. Emails can change, this can't.
+	Subject string
+	Email   string
+	// Whether the provider vouches for Email.
+	EmailVerified bool
+	Name          string
+}
+
+type Provider interface {
+	// Short name used in URLs, e.g. "facebook".
+	Name() string
+	// Name shown to users, e.g. "Facebook".
+	DisplayName() string
+	// Where to send the user to sign in. The provider sends them back to
+	// redirectURL with a code and the same state.
+	AuthCodeURL(ctx context.Context, state, nonce, redirectURL string) (string, error)
+	// Trades the code from the redirect for the user's identity.
+	Exchange(ctx context.Context, code, nonce, redirectURL string) (Identity, error)
+}
+
+// Makes a random value for the state and nonce parameters.
+func RandomString() (string, error) {
+	b := make([]byte, 16)
+	_, err := rand.Read(b)
+	if err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,223 @@
+// Package oauthtest runs an in-process OAuth2 and OpenID Connect provider for tests.
+package oauthtest
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ctiller15/tailscribe/internal/oauth"
+	"github.com/golang-jwt/jwt/v5"
+	"golang.org/x/oauth2"
+)
+
+// A fake provider that signs in as whichever User is set, without asking.
+// It speaks enough OpenID Connect for oauth.OIDC and enough Graph API for oauth.Facebook.
+type Server struct {
+	*httptest.Server
+	ClientID     string
+	ClientSecret string
+
+	mu           sync.Mutex
+	user         oauth.Identity
+	grants       map[string]grant
+	accessTokens map[string]oauth.Identity
+}
+
+type grant struct {
+	nonce       string
+	redirectURI string
+	user        oauth.Identity
+}
+
+func NewServer(clientID, clientSecret string) *Server {
+	s := &Server{
+		ClientID:     clientID,
+		ClientSecret: clientSecret,
+		grants:       map[string]grant{},
+		accessTokens: map[string]oauth.Identity{},
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /.well-known/openid-configuration", s.handleDiscovery)
+	mux.HandleFunc("GET /authorize", s.handleAuthorize)
+	mux.HandleFunc("POST /token", s.handleToken)
+	mux.HandleFunc("GET /me", s.handleMe)
+	s.Server = httptest.NewServer(mux)
+
+	return s
+}
+
+// Sets who the next sign-in is for.
+func (s *Server) SetUser(user oauth.Identity) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.user = user
+}
+
+// A Facebook provider that talks to this server.
+func (s *Server) Facebook() *oauth.Facebook {
+	return &oauth.Facebook{
+		ClientID:     s.ClientID,
+		ClientSecret: s.ClientSecret,
+		Endpoint: oauth2.Endpoint{
+			AuthURL:  s.URL + "/authorize",
+			TokenURL: s.URL + "/token",
+		},
+		GraphURL: s.URL,
+	}
+}
+
+// An OpenID Connect provider with this server as its issuer.
+func (s *Server) OIDC() *oauth.OIDC {
+	return oauth.NewOIDC(s.URL, s.ClientID, s.ClientSecret, "Test SSO")
+}
+
+// Follows an authorization URL the way a browser would, returning the
+// callback URL the provider redirects back to.
+func (s *Server) Authorize(authCodeURL string) (*url.URL, error) {
+	client := &http.Client{
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+
+	response, err := client.Get(authCodeURL)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+
+	return response.Location()
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func (s *Server) handleDiscovery(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]string{
+		"issuer":                 s.URL,
+		"authorization_endpoint": s.URL + "/authorize",
+		"token_endpoint":         s.URL + "/token",
+	})
+}
+
+func (s *Server) handleAuthorize(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	if query.Get("client_id") != s.ClientID || query.Get("response_type") != "code" {
+		http.Error(w, "bad authorization request", http.StatusBadRequest)
+		return
+	}
+
+	redirectURI, err := url.Parse(query.Get("redirect_uri"))
+	if err != nil || query.Get("redirect_uri") == "" {
+		http.Error(w, "bad redirect_uri", http.StatusBadRequest)
+		return
+	}
+
+	code, err := oauth.RandomString()
+	if err != nil {
+		http.Error(w, "server error", http.StatusInternalServerError)
+		return
+	}
+
+	s.mu.Lock()
+	s.grants[code] = grant{
+		nonce:       query.Get("nonce"),
+		redirectURI: query.Get("redirect_uri"),
+		user:        s.user,
+	}
+	s.mu.Unlock()
+
+	values := redirectURI.Query()
+	values.Set("code", code)
+	values.Set("state", query.Get("state"))
+	redirectURI.RawQuery = values.Encode()
+
+	http.Redirect(w, r, redirectURI.String(), http.StatusFound)
+}
+
+func (s *Server) handleToken(w http.ResponseWriter, r *http.Request) {
+	clientID, clientSecret, ok := r.BasicAuth()
+	if !ok {
+		clientID, clientSecret = r.FormValue("client_id"), r.FormValue("client_secret")
+	}
+	if clientID != s.ClientID || clientSecret != s.ClientSecret {
+		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "invalid_client"})
+		return
+	}
+
+	s.mu.Lock()
+	g, ok := s.grants[r.FormValue("code")]
+	delete(s.grants, r.FormValue("code"))
+	s.mu.Unlock()
+
+	if !ok || r.FormValue("grant_type") != "authorization_code" || r.FormValue("redirect_uri") != g.redirectURI {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_grant"})
+		return
+	}
+
+	accessToken, err := oauth.RandomString()
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "server_error"})
+		return
+	}
+
+	s.mu.Lock()
+	s.accessTokens[accessToken] = g.user
+	s.mu.Unlock()
+
+	now := time.Now()
+	idToken, err := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"iss":            s.URL,
+		"sub":            g.user.Subject,
+		"aud":            s.ClientID,
+		"iat":            now.Unix(),
+		"exp":            now.Add(time.Hour).Unix(),
+		"nonce":          g.nonce,
+		"email":          g.user.Email,
+		"email_verified": g.user.EmailVerified,
+		"name":           g.user.Name,
+	}).SignedString([]byte(s.ClientSecret))
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "server_error"})
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]any{
+		"access_token": accessToken,
+		"token_type":   "Bearer",
+		"expires_in":   3600,
+		"id_token":     idToken,
+	})
+}
+
+// Answers like the Graph API's /me endpoint.
+func (s *Server) handleMe(w http.ResponseWriter, r *http.Request) {
+	accessToken := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+
+	s.mu.Lock()
+	user, ok := s.accessTokens[accessToken]
+	s.mu.Unlock()
+
+	if !ok {
+		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "invalid_token"})
+		return
+	}
+
+	profile := map[string]string{
+		"id":   user.Subject,
+		"name": user.Name,
+	}
+	if user.Email != "" {
+		profile["email"] = user.Email
+	}
+	writeJSON(w, http.StatusOK, profile)
+}
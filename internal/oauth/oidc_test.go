@@ -0,0 +1,63 @@
+package oauth
+
+import (
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseIDToken(t *testing.T) {
+	provider := NewOIDC("https://issuer.test/", "client", "secret", "")
+	now := time.Now()
+
+	makeToken := func(change func(claims jwt.MapClaims)) string {
+		claims := jwt.MapClaims{
+			"iss":   "https://issuer.test",
+			"sub":   "user-1",
+			"aud":   "client",
+			"exp":   now.Add(time.Hour).Unix(),
+			"nonce": "nonce-1",
+			"email": "a@test.com",
+		}
+		change(claims)
+		token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte("whatever"))
+		assert.NoError(t, err)
+		return token
+	}
+
+	t.Run("Accepts a token for this client", func(t *testing.T) {
+		claims, err := provider.parseIDToken(makeToken(func(jwt.MapClaims) {}), "nonce-1", now)
+
+		assert.NoError(t, err)
+		assert.Equal(t, "user-1", claims.Subject)
+		assert.Equal(t, "a@test.com", claims.Email)
+	})
+
+	var rejectTests = []struct {
+		name   string
+		change func(claims jwt.MapClaims)
+	}{
+		{"another issuer", func(c jwt.MapClaims) { c["iss"] = "https://evil.test" }},
+		{"another client", func(c jwt.MapClaims) { c["aud"] = "someone-else" }},
+		{"expired", func(c jwt.MapClaims) { c["exp"] = now.Add(-time.Minute).Unix() }},
+		{"no expiry", func(c jwt.MapClaims) { delete(c, "exp") }},
+		{"wrong nonce", func(c jwt.MapClaims) { c["nonce"] = "replayed" }},
+		{"no subject", func(c jwt.MapClaims) { delete(c, "sub") }},
+	}
+
+	for _, tt := range rejectTests {
+		t.Run("Rejects a token from "+tt.name, func(t *testing.T) {
+			_, err := provider.parseIDToken(makeToken(tt.change), "nonce-1", now)
+
+			assert.Error(t, err)
+		})
+	}
+
+	t.Run("Rejects garbage", func(t *testing.T) {
+		_, err := provider.parseIDToken("not.a.token", "nonce-1", now)
+
+		assert.Error(t, err)
+	})
+}
@@ -0,0 +1,101 @@
+package oauth_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/ctiller15/tailscribe/internal/oauth"
+	"github.com/ctiller15/tailscribe/internal/oauth/oauthtest"
+	"github.com/stretchr/testify/assert"
+)
+
+const redirectURL = "http://tailscribe.test/auth/callback"
+
+// Runs a provider's whole sign-in against the mock server.
+func signIn(t *testing.T, server *oauthtest.Server, provider oauth.Provider, nonce string) (oauth.Identity, error) {
+	ctx := context.Background()
+	authCodeURL, err := provider.AuthCodeURL(ctx, "state-1", nonce, redirectURL)
+	assert.NoError(t, err)
+
+	callback, err := server.Authorize(authCodeURL)
+	assert.NoError(t, err)
+	assert.Equal(t, "state-1", callback.Query().Get("state"))
+
+	return provider.Exchange(ctx, callback.Query().Get("code"), nonce, redirectURL)
+}
+
+func TestProviders(t *testing.T) {
+	server := oauthtest.NewServer("client", "secret")
+	defer server.Close()
+
+	user := oauth.Identity{Subject: "1234", Email: "fido@test.com", EmailVerified: true, Name: "Fido's Human"}
+	server.SetUser(user)
+
+	t.Run("OIDC", func(t *testing.T) {
+		identity, err := signIn(t, server, server.OIDC(), "nonce-1")
+
+		assert.NoError(t, err)
+		assert.Equal(t, user, identity)
+	})
+
+	t.Run("Facebook", func(t *testing.T) {
+		identity, err := signIn(t, server, server.Facebook(), "")
+
+		// Facebook doesn't vouch for the address.
+		expected := user
+		expected.EmailVerified = false
+		assert.NoError(t, err)
+		assert.Equal(t, expected, identity)
+	})
+
+	t.Run("Codes only work once", func(t *testing.T) {
+		ctx := context.Background()
+		provider := server.OIDC()
+		authCodeURL, _ := provider.AuthCodeURL(ctx, "state-1", "nonce-1", redirectURL)
+		callback, _ := server.Authorize(authCodeURL)
+		code := callback.Query().Get("code")
+
+		_, err := provider.Exchange(ctx, code, "nonce-1", redirectURL)
+		assert.NoError(t, err)
+		_, err = provider.Exchange(ctx, code, "nonce-1", redirectURL)
+		assert.Error(t, err)
+	})
+
+	t.Run("OIDC rejects a mismatched nonce", func(t *testing.T) {
+		ctx := context.Background()
+		provider := server.OIDC()
+		authCodeURL, _ := provider.AuthCodeURL(ctx, "state-1", "nonce-1", redirectURL)
+		callback, _ := server.Authorize(authCodeURL)
+
+		_, err := provider.Exchange(ctx, callback.Query().Get("code"), "nonce-2", redirectURL)
+		assert.Error(t, err)
+	})
+
+	t.Run("OIDC rejects the wrong client secret", func(t *testing.T) {
+		provider := oauth.NewOIDC(server.URL, "client", "wrong", "")
+
+		_, err := signIn(t, server, provider, "nonce-1")
+		assert.Error(t, err)
+	})
+
+	t.Run("OIDC fails cleanly when the issuer is down", func(t *testing.T) {
+		provider := oauth.NewOIDC("http://127.0.0.1:1", "client", "secret", "")
+
+		_, err := provider.AuthCodeURL(context.Background(), "state-1", "nonce-1", redirectURL)
+		assert.Error(t, err)
+	})
+	t.Run("OIDC gives up on an issuer that doesn't answer", func(t *testing.T) {
+		stalled := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			<-r.Context().Done()
+		}))
+		defer stalled.Close()
+		provider := oauth.NewOIDC(stalled.URL, "client", "secret", "")
+		provider.HTTPClient = &http.Client{Timeout: 50 * time.Millisecond}
+
+		_, err := provider.AuthCodeURL(context.Background(), "state-1", "nonce-1", redirectURL)
+		assert.Error(t, err)
+	})
+}
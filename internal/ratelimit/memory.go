@@ -0,0 +1,59 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Keeps counters in process. Each server counts separately, and counts are
+// lost on restart, so use PostgresStore when running more than one server.
+type MemoryStore struct {
+	mu       sync.Mutex
+	attempts map[string]Attempts
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{attempts: map[string]Attempts{}}
+}
+
+func (s *MemoryStore) Get(ctx context.Context, key string) (Attempts, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.attempts[key], nil
+}
+
+func (s *MemoryStore) Record(ctx context.Context, key string, now, windowStart time.Time) (Attempts, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	attempts := s.attempts[key]
+	attempts.Previous = attempts.Last
+	if attempts.Last.Before(windowStart) {
+		attempts.Count = 0
+		attempts.Previous = time.Time{}
+	}
+	attempts.Count++
+	attempts.Last = now
+	s.attempts[key] = attempts
+
+	return attempts, nil
+}
+
+func (s *MemoryStore) Reset(ctx context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.attempts, key)
+	return nil
+}
+
+func (s *MemoryStore) Prune(ctx context.Context, before time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for key, attempts := range s.attempts {
+		if attempts.Last.Before(before) {
+			delete(s.attempts, key)
+		}
+	}
+	return nil
+}
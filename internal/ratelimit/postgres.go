@@ -0,0 +1,52 @@
+package ratelimit
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/ctiller15/tailscribe/internal/database"
+)
+
+// Keeps counters in the rate_limits table, shared by every server.
+type PostgresStore struct {
+	Db *database.Queries
+}
+
+func NewPostgresStore(db *database.Queries) *PostgresStore {
+	return &PostgresStore{Db: db}
+}
+
+func (s *PostgresStore) Get(ctx context.Context, key string) (Attempts, error) {
+	row, err := s.Db.GetRateLimit(ctx, key)
+	if errors.Is(err, sql.ErrNoRows) {
+		return Attempts{}, nil
+	}
+	if err != nil {
+		return Attempts{}, err
+	}
+
+	return Attempts{Count: int(row.Attempts), Last: row.LastAttemptAt, Previous: row.PreviousAttemptAt.Time}, nil
+}
+
+func (s *PostgresStore) Record(ctx context.Context, key string, now, windowStart time.Time) (Attempts, error) {
+	row, err := s.Db.RecordRateLimitAttempt(ctx, database.RecordRateLimitAttemptParams{
+		Key:         key,
+		Now:         now,
+		WindowStart: windowStart,
+	})
+	if err != nil {
+		return Attempts{}, err
+	}
+
+	return Attempts{Count: int(row.Attempts), Last: row.LastAttemptAt, Previous: row.PreviousAttemptAt.Time}, nil
+}
+
+func (s *PostgresStore) Reset(ctx context.Context, key string) error {
+	return s.Db.DeleteRateLimit(ctx, key)
+}
+
+func (s *PostgresStore) Prune(ctx context.Context, before time.Time) error {
+	return s.Db.DeleteStaleRateLimits(ctx, before)
+}
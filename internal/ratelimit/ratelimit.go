@@ -0,0 +1,119 @@
+// Package ratelimit slows down and then locks out repeated attempts, such as
+// failed logins, with exponential backoff.
+package ratelimit
+
+import (
+	"context"
+	"time"
+)
+
+// The attempts recorded against a key within the current window.
+type Attempts struct {
+	Count int
+	Last  time.Time
+	// The attempt before Last, or zero if Last is the first in the window.
+	Previous time.Time
+}
+
+// Keeps attempt counters. Implementations must make Record atomic, so
+// concurrent attempts can't slip past the limit.
+type Store interface {
+	// Returns the attempts for key, or zero Attempts if there are none.
+	Get(ctx context.Context, key string) (Attempts, error)
+	// Counts an attempt at now. Attempts older than windowStart are forgotten first.
+	Record(ctx context.Context, key string, now, windowStart time.Time) (Attempts, error)
+	Reset(ctx context.Context, key string) error
+	// Removes counters with no attempts since before.
+	Prune(ctx context.Context, before time.Time) error
+}
+
+// How quickly attempts are slowed down.
+type Policy struct {
+	// Attempts allowed before any waiting.
+	Free int
+	// The wait after the first attempt past Free, doubling with each one after.
+	Backoff time.Duration
+	// After this many attempts the key is locked out for Lockout.
+	LockoutAfter int
+	Lockout      time.Duration
+	// Attempts are forgotten after this long without another. At least Lockout.
+	Window time.Duration
+}
+
+// How long to wait after the given number of attempts.
+func (p Policy) Delay(count int) time.Duration {
+	if count < p.Free {
+		return 0
+	}
+	if count >= p.LockoutAfter {
+		return p.Lockout
+	}
+
+	delay := p.Backoff
+	for i := p.Free; i < count && delay < p.Lockout; i++ {
+		delay *= 2
+	}
+	return min(delay, p.Lockout)
+}
+
+type Limiter struct {
+	Store  Store
+	Policy Policy
+	// Keeps this limiter's keys apart from others sharing the store.
+	Prefix string
+	Now    func() time.Time
+}
+
+func New(store Store, prefix string, policy Policy) *Limiter {
+	return &Limiter{
+		Store:  store,
+		Policy: policy,
+		Prefix: prefix,
+		Now:    time.Now,
+	}
+}
+
+// Returns how long to wait before key may try again, or zero if it may try now.
+func (l *Limiter) Wait(ctx context.Context, key string) (time.Duration, error) {
+	attempts, err := l.Store.Get(ctx, l.Prefix+key)
+	if err != nil {
+		return 0, err
+	}
+
+	now := l.Now()
+	if attempts.Count == 0 || attempts.Last.Before(now.Add(-l.Policy.Window)) {
+		return 0, nil
+	}
+
+	return max(attempts.Last.Add(l.Policy.Delay(attempts.Count)).Sub(now), 0), nil
+}
+
+// Counts an attempt by key, then returns how long key should have waited before
+// making it, or zero if it was allowed. Counting first gives concurrent attempts
+// different counts, so a burst can't all get in under the same one.
+func (l *Limiter) Attempt(ctx context.Context, key string) (time.Duration, error) {
+	now := l.Now()
+	attempts, err := l.Store.Record(ctx, l.Prefix+key, now, now.Add(-l.Policy.Window))
+	if err != nil {
+		return 0, err
+	}
+
+	earlier := attempts.Count - 1
+	if earlier == 0 {
+		return 0, nil
+	}
+
+	return max(attempts.Previous.Add(l.Policy.Delay(earlier)).Sub(now), 0), nil
+}
+
+// Counts an attempt by key.
+func (l *Limiter) Record(ctx context.Context, key string) error {
+	now := l.Now()
+	_, err := l.Store.Record(ctx, l.Prefix+key, now, now.Add(-l.Policy.Window))
+	return err
+}
+
+// Forgets key's attempts, such as after a successful login.
+func (l *Limiter) Reset(ctx context.Context, key string) error {
+	return l.Store.Reset(ctx, l.Prefix+key)
+}
@@ -0,0 +1,160 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var testPolicy = Policy{
+	Free:         3,
+	Backoff:      time.Second,
+	LockoutAfter: 6,
+	Lockout:      time.Minute,
+	Window:       time.Hour,
+}
+
+// A limiter on a memory store with a clock the test moves by hand.
+func newTestLimiter() (*Limiter, *time.Time) {
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	limiter := New(NewMemoryStore(), "test:", testPolicy)
+	limiter.Now = func() time.Time { return now }
+	return limiter, &now
+}
+
+func TestPolicyDelay(t *testing.T) {
+	tests := []struct {
+		count    int
+		expected time.Duration
+	}{
+		{0, 0},
+		{2, 0},
+		{3, time.Second},
+		{4, 2 * time.Second},
+		{5, 4 * time.Second},
+		{6, time.Minute},
+		{50, time.Minute},
+	}
+
+	for _, test := range tests {
+		assert.Equal(t, test.expected, testPolicy.Delay(test.count), "count %d", test.count)
+	}
+}
+
+func TestPolicyDelayCapsAtLockout(t *testing.T) {
+	policy := Policy{Free: 1, Backoff: time.Second, LockoutAfter: 1000, Lockout: time.Minute, Window: time.Hour}
+
+	assert.Equal(t, time.Minute, policy.Delay(999))
+}
+
+func TestLimiter(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Allows the free attempts", func(t *testing.T) {
+		limiter, _ := newTestLimiter()
+		for range 2 {
+			assert.Nil(t, limiter.Record(ctx, "a"))
+		}
+
+		wait, err := limiter.Wait(ctx, "a")
+		assert.Nil(t, err)
+		assert.Equal(t, time.Duration(0), wait)
+	})
+
+	t.Run("Backs off and then locks out", func(t *testing.T) {
+		limiter, now := newTestLimiter()
+		for range 3 {
+			limiter.Record(ctx, "a")
+		}
+
+		wait, _ := limiter.Wait(ctx, "a")
+		assert.Equal(t, time.Second, wait)
+
+		*now = now.Add(time.Second)
+		wait, _ = limiter.Wait(ctx, "a")
+		assert.Equal(t, time.Duration(0), wait)
+
+		for range 3 {
+			limiter.Record(ctx, "a")
+		}
+		wait, _ = limiter.Wait(ctx, "a")
+		assert.Equal(t, time.Minute, wait)
+	})
+
+	t.Run("Keeps keys apart", func(t *testing.T) {
+		limiter, _ := newTestLimiter()
+		for range 6 {
+			limiter.Record(ctx, "a")
+		}
+
+		wait, _ := limiter.Wait(ctx, "b")
+		assert.Equal(t, time.Duration(0), wait)
+	})
+
+	t.Run("Forgets attempts after the window", func(t *testing.T) {
+		limiter, now := newTestLimiter()
+		for range 6 {
+			limiter.Record(ctx, "a")
+		}
+
+		*now = now.Add(testPolicy.Window + time.Second)
+		wait, _ := limiter.Wait(ctx, "a")
+		assert.Equal(t, time.Duration(0), wait)
+
+		// The count starts over too.
+		limiter.Record(ctx, "a")
+		attempts, _ := limiter.Store.Get(ctx, "test:a")
+		assert.Equal(t, 1, attempts.Count)
+	})
+
+	t.Run("Attempt counts before deciding", func(t *testing.T) {
+		limiter, now := newTestLimiter()
+
+		// A burst at one instant gets the free attempts and no more.
+		for range 3 {
+			wait, err := limiter.Attempt(ctx, "a")
+			assert.Nil(t, err)
+			assert.Equal(t, time.Duration(0), wait)
+		}
+		wait, _ := limiter.Attempt(ctx, "a")
+		assert.Equal(t, time.Second, wait)
+
+		// Even refused attempts count, so the next one waits from the last.
+		*now = now.Add(time.Second)
+		wait, _ = limiter.Attempt(ctx, "a")
+		assert.Equal(t, time.Second, wait)
+
+		*now = now.Add(4 * time.Second)
+		wait, _ = limiter.Attempt(ctx, "a")
+		assert.Equal(t, time.Duration(0), wait)
+	})
+
+	t.Run("Reset clears the key", func(t *testing.T) {
+		limiter, _ := newTestLimiter()
+		for range 6 {
+			limiter.Record(ctx, "a")
+		}
+
+		assert.Nil(t, limiter.Reset(ctx, "a"))
+		wait, _ := limiter.Wait(ctx, "a")
+		assert.Equal(t, time.Duration(0), wait)
+	})
+}
+
+func TestMemoryStorePrune(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryStore()
+	now := time.Now()
+
+	store.Record(ctx, "old", now.Add(-2*time.Hour), now.Add(-24*time.Hour))
+	store.Record(ctx, "new", now, now.Add(-24*time.Hour))
+
+	assert.Nil(t, store.Prune(ctx, now.Add(-time.Hour)))
+
+	old, _ := store.Get(ctx, "old")
+	assert.Equal(t, 0, old.Count)
+	recent, _ := store.Get(ctx, "new")
+	assert.Equal(t, 1, recent.Count)
+}
@@ -0,0 +1,118 @@
+package render
+
+import (
+	"bytes"
+	"fmt"
+	"html/template"
+	"io/fs"
+	"net/http"
+	"path"
+	"strings"
+	"sync"
+)
+
+// Every page is parsed with the layout and all the partials.
+const (
+	layout      = "base.tmpl"
+	partialsDir = "partials"
+	pagesDir    = "pages"
+)
+
+// Renders the named pages in a template directory: base.tmpl for the layout,
+// partials/*.tmpl shared by every page, and one pages/<name>.tmpl per page.
+type Renderer struct {
+	fsys fs.FS
+	// Re-read the templates for every render, to see edits without a restart.
+	reload bool
+
+	mu    sync.RWMutex
+	pages map[string]*template.Template
+}
+
+// Parses every page in fsys, so a broken template stops startup rather than a request.
+func New(fsys fs.FS, reload bool) (*Renderer, error) {
+	pages, err := parsePages(fsys)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Renderer{
+		fsys:   fsys,
+		reload: reload,
+		pages:  pages,
+	}, nil
+}
+
+func parsePages(fsys fs.FS) (map[string]*template.Template, error) {
+	files, err := fs.Glob(fsys, path.Join(pagesDir, "*.tmpl"))
+	if err != nil {
+		return nil, err
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("no pages in %s", pagesDir)
+	}
+
+	partials, err := fs.Glob(fsys, path.Join(partialsDir, "*.tmpl"))
+	if err != nil {
+		return nil, err
+	}
+
+	pages := make(map[string]*template.Template, len(files))
+	for _, file := range files {
+		name := strings.TrimSuffix(path.Base(file), ".tmpl")
+
+		patterns := append([]string{layout}, partials...)
+		patterns = append(patterns, file)
+		tmpl, err := template.New(name).ParseFS(fsys, patterns...)
+		if err != nil {
+			return nil, fmt.Errorf("parsing page %s: %w", name, err)
+		}
+
+		pages[name] = tmpl
+	}
+
+	return pages, nil
+}
+
+// The parsed page, re-reading the templates first when reloading.
+func (r *Renderer) page(name string) (*template.Template, error) {
+	if r.reload {
+		pages, err := parsePages(r.fsys)
+		if err != nil {
+			return nil, err
+		}
+
+		r.mu.Lock()
+		r.pages = pages
+		r.mu.Unlock()
+	}
+
+	r.mu.RLock()
+	tmpl, ok := r.pages[name]
+	r.mu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("no page named %q", name)
+	}
+
+	return tmpl, nil
+}
+
+// Writes the page with the given status. The page is rendered in full before
+// anything is written, so on error the caller can still send its own response.
+func (r *Renderer) Render(w http.ResponseWriter, status int, name string, data any) error {
+	tmpl, err := r.page(name)
+	if err != nil {
+		return err
+	}
+
+	var buf bytes.Buffer
+	err = tmpl.ExecuteTemplate(&buf, "base", data)
+	if err != nil {
+		return fmt.Errorf("rendering page %s: %w", name, err)
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(status)
+	_, err = buf.WriteTo(w)
+	return err
+}
@@ -0,0 +1,82 @@
+package render
+
+import (
+	"io/fs"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"testing/fstest"
+
+	"github.com/ctiller15/tailscribe/ui"
+	"github.com/stretchr/testify/assert"
+)
+
+func testFS() fstest.MapFS {
+	return fstest.MapFS{
+		"base.tmpl":          {Data: []byte(`{{define "base"}}<title>{{template "title"}}</title>{{template "nav"}}{{template "main" .}}{{end}}`)},
+		"partials/nav.tmpl":  {Data: []byte(`{{define "nav"}}<nav></nav>{{end}}`)},
+		"pages/hello.tmpl":   {Data: []byte(`{{define "title"}}Hello{{end}}{{define "main"}}<p>Hi {{.}}</p>{{end}}`)},
+		"pages/goodbye.tmpl": {Data: []byte(`{{define "title"}}Goodbye{{end}}{{define "main"}}<p>Bye {{.Name}}</p>{{end}}`)},
+	}
+}
+
+func TestRender(t *testing.T) {
+	renderer, err := New(testFS(), false)
+	assert.NoError(t, err)
+
+	t.Run("Writes the page with its status", func(t *testing.T) {
+		response := httptest.NewRecorder()
+		err := renderer.Render(response, http.StatusCreated, "hello", "<Rex>")
+
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusCreated, response.Code)
+		assert.Equal(t, "text/html; charset=utf-8", response.Header().Get("Content-Type"))
+		assert.Equal(t, "<title>Hello</title><nav></nav><p>Hi &lt;Rex&gt;</p>", response.Body.String())
+	})
+
+	t.Run("Writes nothing when the page fails", func(t *testing.T) {
+		response := httptest.NewRecorder()
+		err := renderer.Render(response, http.StatusOK, "goodbye", "not a struct")
+
+		assert.Error(t, err)
+		assert.Empty(t, response.Body.String())
+		assert.Empty(t, response.Header().Get("Content-Type"))
+	})
+
+	t.Run("Unknown pages are errors", func(t *testing.T) {
+		err := renderer.Render(httptest.NewRecorder(), http.StatusOK, "missing", nil)
+		assert.Error(t, err)
+	})
+}
+
+func TestNewRejectsBrokenTemplates(t *testing.T) {
+	files := testFS()
+	files["pages/broken.tmpl"] = &fstest.MapFile{Data: []byte(`{{define "main"}}{{.Name}{{end}}`)}
+
+	_, err := New(files, false)
+	assert.Error(t, err)
+
+	_, err = New(fstest.MapFS{}, false)
+	assert.Error(t, err)
+}
+
+func TestReload(t *testing.T) {
+	files := testFS()
+	renderer, err := New(files, true)
+	assert.NoError(t, err)
+
+	files["pages/hello.tmpl"] = &fstest.MapFile{Data: []byte(`{{define "title"}}Hello{{end}}{{define "main"}}<p>Edited</p>{{end}}`)}
+
+	response := httptest.NewRecorder()
+	err = renderer.Render(response, http.StatusOK, "hello", nil)
+	assert.NoError(t, err)
+	assert.Contains(t, response.Body.String(), "Edited")
+}
+
+func TestEmbeddedTemplatesParse(t *testing.T) {
+	html, err := fs.Sub(ui.Files, "html")
+	assert.NoError(t, err)
+
+	_, err = New(html, false)
+	assert.NoError(t, err)
+}
@@ -1,14 +1,16 @@
 package main
 
 import (
-	"database/sql"
+	"context"
+	"fmt"
 	"log/slog"
 	"net/http"
 	"os"
+	"os/signal"
+	"syscall"
 	"time"
 
 	"github.com/ctiller15/tailscribe/internal/api"
-	"github.com/ctiller15/tailscribe/internal/database"
 	"github.com/joho/godotenv"
 
 	_ "github.com/lib/pq"
@@ -22,48 +24,87 @@ func main() {
 		logger.Warn("error loading .env file. \n Proceeding without env file...", slog.String("error", err.Error()))
 	}
 
-	envVars := api.NewEnvVars()
-	dbUrl := envVars.Database.ConnectionString()
+	envVars, err := api.LoadEnvVars(os.LookupEnv)
+	if err != nil {
+		logger.Error(err.Error())
+		os.Exit(1)
+	}
+	logger.Info("loaded config", slog.Any("config", envVars))
 
-	db, err := sql.Open("postgres", dbUrl)
+	db, err := envVars.Database.Open()
 	if err != nil {
 		logger.Error(err.Error())
 		os.Exit(1)
 	}
 
-	dbQueries := database.New(db)
+	apiCfg, err := api.NewAPIConfig(envVars, db, logger)
+	if err != nil {
+		logger.Error(err.Error())
+		os.Exit(1)
+	}
 
-	apiCfg := api.NewAPIConfig(envVars, dbQueries, logger)
+	// Stop on Ctrl-C, or when the platform asks.
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
 
-	// Initialize routing - break into own func first
-	fs := http.FileServer(http.Dir("./ui/static/"))
+	// Check the database before reporting ready.
+	go apiCfg.RunStartupChecks(ctx, 5*time.Second)
 
-	mux := http.NewServeMux()
+	// Erase accounts whose deletion grace period has passed.
+	go apiCfg.RunPurger(ctx, time.Hour)
+	go apiCfg.RunRateLimitPruner(ctx, time.Hour)
 
-	mux.Handle("/static/", http.StripPrefix("/static/", fs))
+	server := &http.Server{
+		Handler:           api.NewRouter(apiCfg),
+		Addr:              envVars.Addr,
+		ReadHeaderTimeout: 2 * time.Second,
+	}
 
-	mux.HandleFunc("GET /{$}", apiCfg.HandleIndex)
-	mux.HandleFunc("GET /signup", apiCfg.HandleSignupPage)
-	mux.HandleFunc("POST /signup", apiCfg.HandlePostSignup)
-	mux.HandleFunc("POST /login", apiCfg.HandlePostLogin)
-	mux.HandleFunc("POST /logout", apiCfg.HandlePostLogout)
-	mux.HandleFunc("/attributions", apiCfg.HandleAttributions)
-	mux.HandleFunc("/terms", apiCfg.HandleTerms)
-	mux.HandleFunc("/privacy", apiCfg.HandlePrivacyPolicy)
-	mux.HandleFunc("/contact", apiCfg.HandleContactUs)
+	serveErr := serve(ctx, server, logger)
+	if serveErr != nil {
+		logger.Error(serveErr.Error())
+	}
 
-	mux.Handle("GET /dashboard/add_new_pet", apiCfg.CheckAuthMiddleware(apiCfg.HandleGetAddNewPet))
+	// Only once requests have drained, so none lose their connection part way through.
+	closeErr := db.Close()
+	if closeErr != nil {
+		logger.Error("error closing database", slog.String("error", closeErr.Error()))
+	}
 
-	// Start server
-	server := http.Server{
-		Handler:           mux,
-		Addr:              envVars.Addr,
-		ReadHeaderTimeout: 2 * time.Second,
+	if serveErr != nil || closeErr != nil {
+		os.Exit(1)
 	}
+}
 
-	logger.Info("listening on", slog.String("addr", envVars.Addr))
+// How long requests in flight get to finish once shutdown starts.
+const shutdownTimeout = 20 * time.Second
+
+// Runs the server until it fails or ctx is done, then lets requests in flight finish.
+func serve(ctx context.Context, server *http.Server, logger *slog.Logger) error {
+	serverErr := make(chan error, 1)
+	go func() {
+		logger.Info("listening on", slog.String("addr", server.Addr))
+		serverErr <- server.ListenAndServe()
+	}()
+
+	select {
+	case err := <-serverErr:
+		return err
+	case <-ctx.Done():
+	}
+
+	logger.Info("shutting down", slog.Duration("timeout", shutdownTimeout))
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+
+	err := server.Shutdown(shutdownCtx)
+	if err != nil {
+		// Cut off whatever didn't finish in time.
+		server.Close()
+		return fmt.Errorf("shutting down: %w", err)
+	}
 
-	err = server.ListenAndServe()
-	logger.Error(err.Error())
-	os.Exit(1)
+	logger.Info("server stopped")
+	return nil
 }
@@ -0,0 +1,82 @@
+-- name: CreateGoal :one
+INSERT INTO goals(pet_id, description, target_date, created_at, updated_at)
+VALUES (
+    $1,
+    $2,
+    $3,
+    NOW(),
+    NOW()
+)
+RETURNING *;
+
+-- name: GetGoal :one
+SELECT *
+FROM goals
+WHERE id = $1 AND pet_id = $2;
+
+-- name: ListGoalsForPet :many
+SELECT *
+FROM goals
+WHERE pet_id = $1
+ORDER BY status = 'open' DESC, target_date NULLS LAST, id;
+
+-- name: ListOverdueGoalsForPet :many
+SELECT *
+FROM goals
+WHERE pet_id = $1 AND status = 'open' AND target_date < CURRENT_DATE
+ORDER BY target_date;
+
+-- name: ListRecentlyAchievedGoalsForPet :many
+SELECT *
+FROM goals
+WHERE pet_id = $1 AND status = 'achieved' AND achieved_at >= $2
+ORDER BY achieved_at DESC;
+
+-- name: UpdateGoal :one
+UPDATE goals
+SET description = $3,
+    target_date = $4,
+    status = $5,
+    achieved_at = $6,
+    updated_at = NOW()
+WHERE id = $1 AND pet_id = $2
+RETURNING *;
+
+-- name: DeleteGoal :exec
+DELETE FROM goals
+WHERE id = $1 AND pet_id = $2;
+
+-- name: AddGoalSkill :exec
+INSERT INTO goal_skills(goal_id, skill_id)
+VALUES (
+    $1,
+    $2
+)
+ON CONFLICT DO NOTHING;
+
+-- name: DeleteGoalSkills :exec
+DELETE FROM goal_skills
+WHERE goal_id = $1;
+
+-- name: ListGoalSkills :many
+SELECT skills.*
+FROM skills
+JOIN goal_skills ON goal_skills.skill_id = skills.id
+WHERE goal_skills.goal_id = $1
+ORDER BY skills.name;
+
+-- name: CreateGoalProgressNote :one
+INSERT INTO goal_progress_notes(goal_id, user_id, note, created_at)
+VALUES (
+    $1,
+    $2,
+    $3,
+    NOW()
+)
+RETURNING *;
+
+-- name: ListGoalProgressNotes :many
+SELECT *
+FROM goal_progress_notes
+WHERE goal_id = $1
+ORDER BY created_at DESC, id DESC;
@@ -0,0 +1,77 @@
+-- name: CreateLike :one
+INSERT INTO likes(pet_id, name, category, value_tier, notes, rank, created_at, updated_at)
+VALUES (
+    $1,
+    $2,
+    $3,
+    $4,
+    $5,
+    (SELECT COALESCE(MAX(rank), 0) + 1 FROM likes WHERE pet_id = $1),
+    NOW(),
+    NOW()
+)
+RETURNING *;
+
+-- name: GetLike :one
+SELECT *
+FROM likes
+WHERE id = $1 AND pet_id = $2;
+
+-- name: ListLikesForPet :many
+SELECT *
+FROM likes
+WHERE pet_id = $1
+ORDER BY rank, id;
+
+-- name: UpdateLike :one
+UPDATE likes
+SET name = $3,
+    category = $4,
+    value_tier = $5,
+    notes = $6,
+    updated_at = NOW()
+WHERE id = $1 AND pet_id = $2
+RETURNING *;
+
+-- name: UpdateLikeRank :exec
+UPDATE likes
+SET rank = $3,
+    updated_at = NOW()
+WHERE id = $1 AND pet_id = $2;
+
+-- name: DeleteLike :exec
+DELETE FROM likes
+WHERE id = $1 AND pet_id = $2;
+
+-- name: AddTrainingSessionLike :exec
+INSERT INTO training_session_likes(session_id, like_id)
+VALUES (
+    $1,
+    $2
+)
+ON CONFLICT DO NOTHING;
+
+-- name: DeleteTrainingSessionLikes :exec
+DELETE FROM training_session_likes
+WHERE session_id = $1;
+
+-- name: ListTrainingSessionLikes :many
+SELECT likes.*
+FROM likes
+JOIN training_session_likes ON training_session_likes.like_id = likes.id
+WHERE training_session_likes.session_id = $1
+ORDER BY likes.rank, likes.id;
+
+-- name: ListTrainingSessionLikesForPet :many
+SELECT training_session_likes.session_id, likes.id, likes.name
+FROM training_session_likes
+JOIN likes ON likes.id = training_session_likes.like_id
+WHERE likes.pet_id = $1
+ORDER BY likes.rank, likes.id;
+
+-- name: ListTrainingSessionLikesForSessions :many
+SELECT training_session_likes.session_id, likes.id, likes.name
+FROM training_session_likes
+JOIN likes ON likes.id = training_session_likes.like_id
+WHERE training_session_likes.session_id = ANY(sqlc.arg(session_ids)::int[])
+ORDER BY likes.rank, likes.id;
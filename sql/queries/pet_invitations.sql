@@ -0,0 +1,42 @@
+-- name: CreatePetInvitation :one
+INSERT INTO pet_invitations(pet_id, email, permissions_level, invited_by, expires_at, created_at, updated_at)
+VALUES (
+    $1,
+    $2,
+    $3,
+    $4,
+    $5,
+    NOW(),
+    NOW()
+)
+RETURNING *;
+
+-- name: GetPetInvitation :one
+SELECT *
+FROM pet_invitations
+WHERE id = $1;
+
+-- name: ListPendingPetInvitations :many
+SELECT *
+FROM pet_invitations
+WHERE pet_id = $1
+    AND accepted_at IS NULL
+    AND revoked_at IS NULL
+    AND expires_at > NOW()
+ORDER BY created_at, id;
+
+-- name: AcceptPetInvitation :execrows
+UPDATE pet_invitations
+SET accepted_by = $2,
+    accepted_at = NOW(),
+    updated_at = NOW()
+WHERE id = $1
+    AND accepted_at IS NULL
+    AND revoked_at IS NULL
+    AND expires_at > NOW();
+
+-- name: RevokePetInvitation :exec
+UPDATE pet_invitations
+SET revoked_at = NOW(),
+    updated_at = NOW()
+WHERE id = $1 AND pet_id = $2 AND accepted_at IS NULL;
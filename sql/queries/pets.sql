@@ -1,8 +1,9 @@
 -- name: CreatePet :one
-INSERT INTO pet(name, imageUrl, created_at, updated_at)
+INSERT INTO pet(name, imageUrl, slug, created_at, updated_at)
 VALUES (
     $1,
     $2,
+    $3,
     NOW(),
     NOW()
 )
@@ -20,6 +21,163 @@ VALUES (
 )
 RETURNING *;
 
+-- name: GetPet :one
+SELECT *
+FROM pet
+WHERE id = $1;
+
+-- name: GetPublicPetBySlug :one
+-- Only while an owner's account is still around, so deleting an account takes
+-- its pets' profiles down straight away rather than when it's purged.
+SELECT *
+FROM pet
+WHERE slug = $1
+    AND ispubliclyviewable
+    AND EXISTS (
+        SELECT 1
+        FROM UserPets
+        JOIN users ON users.id = UserPets.userId
+        WHERE UserPets.petId = pet.id
+            AND UserPets.permissions_level = 4
+            AND users.deleted_at IS NULL
+    );
+
+-- name: GetUserPet :one
+SELECT *
+FROM UserPets
+WHERE userId = $1 AND petId = $2;
+
+-- name: ListPetsForUser :many
+-- With what the dashboard shows about each, so it takes one query however many pets there are.
+SELECT sqlc.embed(pet), UserPets.permissions_level, UserPets.active, UserPets.hidden,
+    (
+        SELECT MAX(training_sessions.started_at)
+        FROM training_sessions
+        WHERE training_sessions.pet_id = pet.id
+    ) AS last_session_at,
+    (
+        SELECT COUNT(*)
+        FROM goals
+        WHERE goals.pet_id = pet.id
+            AND goals.status = 'open'
+    ) AS open_goals
+FROM pet
+JOIN UserPets ON UserPets.petId = pet.id
+WHERE UserPets.userId = $1
+ORDER BY pet.name, pet.id;
+
+-- name: ListPetAccess :many
+SELECT UserPets.userId, UserPets.permissions_level, users.email
+FROM UserPets
+JOIN users ON users.id = UserPets.userId
+WHERE UserPets.petId = $1
+ORDER BY UserPets.permissions_level DESC, users.email;
+
+-- name: UpdatePet :one
+UPDATE pet
+SET name = $2,
+    dateOfBirth = $3,
+    dateOfBirthExact = $4,
+    imageUrl = $5,
+    about_text = $6,
+    species = $7,
+    breed = $8,
+    sex = $9,
+    updated_at = NOW()
+WHERE id = $1
+RETURNING *;
+
+-- name: UpdatePetGoalsHidden :exec
+UPDATE pet
+SET goalsHidden = $2,
+    updated_at = NOW()
+WHERE id = $1;
+
+-- name: UpdatePetLikesHidden :exec
+UPDATE pet
+SET likesHidden = $2,
+    updated_at = NOW()
+WHERE id = $1;
+
+-- name: UpdatePetPubliclyViewable :exec
+UPDATE pet
+SET isPubliclyViewable = $2,
+    updated_at = NOW()
+WHERE id = $1;
+
+-- name: UpdatePetSkillsHidden :exec
+UPDATE pet
+SET skillsHidden = $2,
+    updated_at = NOW()
+WHERE id = $1;
+
+-- name: UpdatePetTitlesHidden :exec
+UPDATE pet
+SET titlesHidden = $2,
+    updated_at = NOW()
+WHERE id = $1;
+
+-- name: UpdateUserPetActive :exec
+UPDATE UserPets
+SET active = $3,
+    updated_at = NOW()
+WHERE userId = $1 AND petId = $2;
+
+-- name: UpdateUserPetHidden :exec
+UPDATE UserPets
+SET hidden = $3,
+    updated_at = NOW()
+WHERE userId = $1 AND petId = $2;
+
+-- name: UpdateUserPetPermissions :exec
+UPDATE UserPets
+SET permissions_level = $3,
+    updated_at = NOW()
+WHERE userId = $1 AND petId = $2;
+
+-- name: DeleteSolePetsForUser :exec
+-- Deletes the pets nobody but the user is linked to.
+DELETE FROM pet
+WHERE id IN (
+    SELECT petId
+    FROM UserPets
+    WHERE userId = sqlc.arg(user_id)
+)
+AND NOT EXISTS (
+    SELECT 1
+    FROM UserPets other
+    WHERE other.petId = pet.id
+        AND other.userId <> sqlc.arg(user_id)
+);
+
+-- name: PromotePetHeirsForUser :exec
+-- Makes someone else the owner of each pet the user is the only owner of,
+-- picking the member with the most access who joined first.
+UPDATE UserPets
+SET permissions_level = 4,
+    updated_at = NOW()
+FROM (
+    SELECT DISTINCT ON (heir.petId) heir.petId, heir.userId
+    FROM UserPets owned
+    JOIN UserPets heir ON heir.petId = owned.petId AND heir.userId <> owned.userId
+    WHERE owned.userId = sqlc.arg(user_id)
+        AND owned.permissions_level = 4
+        AND NOT EXISTS (
+            SELECT 1
+            FROM UserPets other_owner
+            WHERE other_owner.petId = owned.petId
+                AND other_owner.userId <> owned.userId
+                AND other_owner.permissions_level = 4
+        )
+    ORDER BY heir.petId, heir.permissions_level DESC, heir.created_at
+) heirs
+WHERE UserPets.petId = heirs.petId
+    AND UserPets.userId = heirs.userId;
+
+-- name: DeleteUserPet :exec
+DELETE FROM UserPets
+WHERE userId = $1 AND petId = $2;
+
 -- name: DeletePets :exec
 DELETE FROM pet;
 
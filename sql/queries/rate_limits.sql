@@ -0,0 +1,30 @@
+-- name: DeleteRateLimit :exec
+DELETE FROM rate_limits
+WHERE key = $1;
+
+-- name: DeleteStaleRateLimits :exec
+DELETE FROM rate_limits
+WHERE last_attempt_at < $1;
+
+-- name: GetRateLimit :one
+SELECT *
+FROM rate_limits
+WHERE key = $1;
+
+-- name: RecordRateLimitAttempt :one
+-- Counts an attempt, starting over if the last one was before the window.
+-- The previous attempt comes from the row being updated, so concurrent
+-- attempts each see the one just before them.
+INSERT INTO rate_limits (key, attempts, last_attempt_at)
+VALUES (sqlc.arg(key), 1, sqlc.arg(now))
+ON CONFLICT (key) DO UPDATE
+SET attempts = CASE
+        WHEN rate_limits.last_attempt_at < sqlc.arg(window_start) THEN 1
+        ELSE rate_limits.attempts + 1
+    END,
+    previous_attempt_at = CASE
+        WHEN rate_limits.last_attempt_at < sqlc.arg(window_start) THEN NULL
+        ELSE rate_limits.last_attempt_at
+    END,
+    last_attempt_at = sqlc.arg(now)
+RETURNING *;
@@ -0,0 +1,25 @@
+-- name: CountUnusedRecoveryCodes :one
+SELECT COUNT(*)
+FROM recovery_codes
+WHERE user_id = $1
+    AND used_at IS NULL;
+
+-- name: CreateRecoveryCode :exec
+INSERT INTO recovery_codes (user_id, code_hash, created_at)
+VALUES (
+    $1,
+    $2,
+    NOW()
+);
+
+-- name: DeleteRecoveryCodesForUser :exec
+DELETE FROM recovery_codes
+WHERE user_id = $1;
+
+-- name: UseRecoveryCode :execrows
+-- Marks an unused code as used. Affects no rows if the code is wrong or spent.
+UPDATE recovery_codes
+SET used_at = NOW()
+WHERE user_id = $1
+    AND code_hash = $2
+    AND used_at IS NULL;
@@ -0,0 +1,51 @@
+-- name: CreateSession :one
+INSERT INTO sessions (id, user_id, user_agent, ip_address, created_at, last_seen_at)
+VALUES (
+    $1,
+    $2,
+    $3,
+    $4,
+    NOW(),
+    NOW()
+)
+RETURNING *;
+
+-- name: GetActiveSession :one
+-- Finds a session that hasn't been revoked, for a user who hasn't been deleted.
+SELECT *
+FROM sessions
+WHERE id = $1
+    AND revoked_at IS NULL
+    AND EXISTS (
+        SELECT 1
+        FROM users
+        WHERE users.id = sessions.user_id
+            AND NOT users.is_deleted
+    );
+
+-- name: ListActiveSessionsForUser :many
+SELECT *
+FROM sessions
+WHERE user_id = $1
+    AND revoked_at IS NULL
+ORDER BY last_seen_at DESC;
+
+-- name: RevokeSession :execrows
+UPDATE sessions
+SET revoked_at = NOW()
+WHERE id = $1
+    AND user_id = $2
+    AND revoked_at IS NULL;
+
+-- name: RevokeSessionsForUser :exec
+UPDATE sessions
+SET revoked_at = NOW()
+WHERE user_id = $1
+    AND revoked_at IS NULL;
+
+-- name: TouchSession :exec
+UPDATE sessions
+SET last_seen_at = NOW(),
+    user_agent = $2,
+    ip_address = $3
+WHERE id = $1;
@@ -0,0 +1,52 @@
+-- name: CreateSkill :one
+INSERT INTO skills(pet_id, name, stage, created_at, updated_at)
+VALUES (
+    $1,
+    $2,
+    $3,
+    NOW(),
+    NOW()
+)
+RETURNING *;
+
+-- name: GetSkill :one
+SELECT *
+FROM skills
+WHERE id = $1 AND pet_id = $2;
+
+-- name: ListSkillsForPet :many
+SELECT *
+FROM skills
+WHERE pet_id = $1
+ORDER BY name;
+
+-- name: UpdateSkill :one
+UPDATE skills
+SET name = $3,
+    stage = $4,
+    updated_at = NOW()
+WHERE id = $1 AND pet_id = $2
+RETURNING *;
+
+-- name: DeleteSkill :exec
+DELETE FROM skills
+WHERE id = $1 AND pet_id = $2;
+
+-- name: CreateSkillStageChange :one
+INSERT INTO skill_stage_changes(skill_id, from_stage, to_stage, session_id, user_id, changed_at)
+VALUES (
+    $1,
+    $2,
+    $3,
+    $4,
+    $5,
+    NOW()
+)
+RETURNING *;
+
+-- name: ListSkillStageChanges :many
+SELECT skill_stage_changes.*, training_sessions.started_at AS session_started_at
+FROM skill_stage_changes
+LEFT JOIN training_sessions ON training_sessions.id = skill_stage_changes.session_id
+WHERE skill_stage_changes.skill_id = $1
+ORDER BY skill_stage_changes.changed_at DESC, skill_stage_changes.id DESC;
@@ -0,0 +1,41 @@
+-- name: CreateTitle :one
+INSERT INTO titles(pet_id, name, abbreviation, position, organization, earned_on, certificate_image_url, created_at, updated_at)
+VALUES (
+    $1,
+    $2,
+    $3,
+    $4,
+    $5,
+    $6,
+    $7,
+    NOW(),
+    NOW()
+)
+RETURNING *;
+
+-- name: GetTitle :one
+SELECT *
+FROM titles
+WHERE id = $1 AND pet_id = $2;
+
+-- name: ListTitlesForPet :many
+SELECT *
+FROM titles
+WHERE pet_id = $1
+ORDER BY earned_on, id;
+
+-- name: UpdateTitle :one
+UPDATE titles
+SET name = $3,
+    abbreviation = $4,
+    position = $5,
+    organization = $6,
+    earned_on = $7,
+    certificate_image_url = $8,
+    updated_at = NOW()
+WHERE id = $1 AND pet_id = $2
+RETURNING *;
+
+-- name: DeleteTitle :exec
+DELETE FROM titles
+WHERE id = $1 AND pet_id = $2;
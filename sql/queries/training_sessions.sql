@@ -0,0 +1,76 @@
+-- name: CreateTrainingSession :one
+INSERT INTO training_sessions(pet_id, user_id, started_at, duration_minutes, location, notes, created_at, updated_at)
+VALUES (
+    $1,
+    $2,
+    $3,
+    $4,
+    $5,
+    $6,
+    NOW(),
+    NOW()
+)
+RETURNING *;
+
+-- name: GetTrainingSession :one
+SELECT *
+FROM training_sessions
+WHERE id = $1 AND pet_id = $2;
+
+-- name: ListTrainingSessionsForPet :many
+SELECT *
+FROM training_sessions
+WHERE pet_id = $1
+ORDER BY started_at DESC;
+
+-- name: ListRecentTrainingSessionsForPet :many
+SELECT *
+FROM training_sessions
+WHERE pet_id = $1
+ORDER BY started_at DESC
+LIMIT $2;
+
+-- name: UpdateTrainingSession :one
+UPDATE training_sessions
+SET started_at = $3,
+    duration_minutes = $4,
+    location = $5,
+    notes = $6,
+    updated_at = NOW()
+WHERE id = $1 AND pet_id = $2
+RETURNING *;
+
+-- name: DeleteTrainingSession :exec
+DELETE FROM training_sessions
+WHERE id = $1 AND pet_id = $2;
+
+-- name: CreateTrainingSessionBehavior :one
+INSERT INTO training_session_behaviors(session_id, name)
+VALUES (
+    $1,
+    $2
+)
+RETURNING *;
+
+-- name: ListTrainingSessionBehaviorsForPet :many
+SELECT training_session_behaviors.*
+FROM training_session_behaviors
+JOIN training_sessions ON training_sessions.id = training_session_behaviors.session_id
+WHERE training_sessions.pet_id = $1
+ORDER BY training_session_behaviors.id;
+
+-- name: ListTrainingSessionBehaviorsForSessions :many
+SELECT *
+FROM training_session_behaviors
+WHERE session_id = ANY(sqlc.arg(session_ids)::int[])
+ORDER BY id;
+
+-- name: ListTrainingSessionBehaviors :many
+SELECT *
+FROM training_session_behaviors
+WHERE session_id = $1
+ORDER BY id;
+
+-- name: DeleteTrainingSessionBehaviors :exec
+DELETE FROM training_session_behaviors
+WHERE session_id = $1;
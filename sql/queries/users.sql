@@ -1,17 +1,155 @@
 -- name: CreateUser :one
-INSERT INTO users(created_at, updated_at, email, password)
+INSERT INTO users(created_at, updated_at, email, password, email_verified_at)
 VALUES (
     NOW(),
     NOW(),
     $1,
-    $2
+    $2,
+    $3
 )
 RETURNING *;
 
+-- name: DeleteUser :exec
+DELETE FROM users
+WHERE id = $1;
+
 -- name: DeleteUsers :exec
 DELETE FROM users;
 
+-- name: DisableUserTOTP :exec
+UPDATE users
+SET totp_secret = NULL,
+    totp_enabled_at = NULL,
+    totp_last_step = NULL,
+    updated_at = NOW()
+WHERE id = $1;
+
+-- name: EnableUserTOTP :execrows
+-- Finishes enrollment with the step of the code that confirmed it.
+UPDATE users
+SET totp_enabled_at = NOW(),
+    totp_last_step = $2,
+    updated_at = NOW()
+WHERE id = $1
+    AND totp_secret IS NOT NULL
+    AND totp_enabled_at IS NULL;
+
+-- name: GetUser :one
+SELECT *
+FROM users
+WHERE id = $1;
+
+-- name: GetDeletedUserByEmail :one
+SELECT *
+FROM users
+WHERE LOWER(email) = LOWER(@email)
+    AND is_deleted;
+
 -- name: GetUserByEmail :one
 SELECT *
 FROM users
-WHERE email = $1;
\ No newline at end of file
+WHERE LOWER(email) = LOWER(@email)
+    AND NOT is_deleted;
+
+-- name: GetUserByFacebookID :one
+SELECT *
+FROM users
+WHERE facebook_id = $1
+    AND NOT is_deleted;
+
+-- name: GetUserByOIDCIdentity :one
+SELECT *
+FROM users
+WHERE oidc_issuer = $1
+    AND oidc_subject = $2
+    AND NOT is_deleted;
+
+-- name: GetUserByResetPasswordToken :one
+SELECT *
+FROM users
+WHERE reset_password_token = $1
+    AND reset_password_expires > NOW()
+    AND NOT is_deleted;
+
+-- name: ListUsersToPurge :many
+SELECT id
+FROM users
+WHERE is_deleted
+    AND deleted_at < $1;
+
+-- name: MarkUserEmailVerified :execrows
+-- Verifies the user's email, if it's still the address the link was sent to.
+UPDATE users
+SET email_verified_at = COALESCE(email_verified_at, NOW()),
+    updated_at = NOW()
+WHERE id = @id
+    AND LOWER(email) = LOWER(@email)
+    AND NOT is_deleted;
+
+-- name: RehashUserPassword :exec
+-- Swaps in a new hash of the same password, unless it changed in the meantime.
+UPDATE users
+SET password = @password,
+    updated_at = NOW()
+WHERE id = @id
+    AND password = @old_password;
+
+-- name: ResetUserPassword :one
+UPDATE users
+SET password = $2,
+    reset_password_token = NULL,
+    reset_password_expires = NULL,
+    updated_at = NOW()
+WHERE reset_password_token = $1
+    AND reset_password_expires > NOW()
+    AND NOT is_deleted
+RETURNING *;
+
+-- name: SetUserTOTPSecret :exec
+-- Starts enrollment. Two-factor stays off until EnableUserTOTP.
+UPDATE users
+SET totp_secret = $2,
+    totp_enabled_at = NULL,
+    totp_last_step = NULL,
+    updated_at = NOW()
+WHERE id = $1;
+
+-- name: SoftDeleteUser :exec
+-- Frees the user's social logins, so the same provider accounts can sign up
+-- again. The email stays with them until they're purged, in case they're restored.
+UPDATE users
+SET is_deleted = TRUE,
+    deleted_at = NOW(),
+    facebook_id = NULL,
+    oidc_issuer = NULL,
+    oidc_subject = NULL,
+    updated_at = NOW()
+WHERE id = $1;
+
+-- name: UpdateUserFacebookID :exec
+UPDATE users
+SET facebook_id = $2,
+    updated_at = NOW()
+WHERE id = $1;
+
+-- name: UpdateUserOIDCIdentity :exec
+UPDATE users
+SET oidc_issuer = $2,
+    oidc_subject = $3,
+    updated_at = NOW()
+WHERE id = $1;
+
+-- name: UpdateUserResetPasswordToken :exec
+UPDATE users
+SET reset_password_token = $2,
+    reset_password_expires = $3,
+    updated_at = NOW()
+WHERE id = $1;
+
+-- name: UseUserTOTPStep :execrows
+-- Records an accepted code's time step. Affects no rows if that step, or a
+-- later one, was already used.
+UPDATE users
+SET totp_last_step = $2
+WHERE id = $1
+    AND (totp_last_step IS NULL OR totp_last_step < $2);
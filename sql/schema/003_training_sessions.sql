@@ -0,0 +1,37 @@
+-- +goose Up
+CREATE TABLE training_sessions (
+    id INTEGER GENERATED ALWAYS AS IDENTITY PRIMARY KEY,
+    pet_id INTEGER NOT NULL,
+    -- The user who logged the session. Kept when the user is removed.
+    user_id INTEGER,
+    started_at TIMESTAMP NOT NULL,
+    duration_minutes INTEGER NOT NULL DEFAULT 0,
+    location TEXT,
+    notes TEXT,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL,
+    CONSTRAINT fk_training_sessions_pets
+    FOREIGN KEY (pet_id)
+    REFERENCES pet(id)
+    ON DELETE CASCADE,
+    CONSTRAINT fk_training_sessions_users
+    FOREIGN KEY (user_id)
+    REFERENCES users(id)
+    ON DELETE SET NULL
+);
+
+CREATE INDEX idx_training_sessions_pet_started ON training_sessions(pet_id, started_at DESC);
+
+CREATE TABLE training_session_behaviors (
+    id INTEGER GENERATED ALWAYS AS IDENTITY PRIMARY KEY,
+    session_id INTEGER NOT NULL,
+    name TEXT NOT NULL,
+    CONSTRAINT fk_training_session_behaviors_sessions
+    FOREIGN KEY (session_id)
+    REFERENCES training_sessions(id)
+    ON DELETE CASCADE
+);
+
+-- +goose Down
+DROP TABLE training_session_behaviors;
+DROP TABLE training_sessions;
@@ -0,0 +1,44 @@
+-- +goose Up
+CREATE TABLE skills (
+    id INTEGER GENERATED ALWAYS AS IDENTITY PRIMARY KEY,
+    pet_id INTEGER NOT NULL,
+    name TEXT NOT NULL,
+    -- introduced, learning, fluent, generalized or proofed.
+    stage TEXT NOT NULL DEFAULT 'introduced',
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL,
+    CONSTRAINT uq_skills_pet_name UNIQUE (pet_id, name),
+    CONSTRAINT chk_skills_stage CHECK (stage IN ('introduced', 'learning', 'fluent', 'generalized', 'proofed')),
+    CONSTRAINT fk_skills_pets
+    FOREIGN KEY (pet_id)
+    REFERENCES pet(id)
+    ON DELETE CASCADE
+);
+
+CREATE TABLE skill_stage_changes (
+    id INTEGER GENERATED ALWAYS AS IDENTITY PRIMARY KEY,
+    skill_id INTEGER NOT NULL,
+    -- NULL for the stage a skill was created with.
+    from_stage TEXT,
+    to_stage TEXT NOT NULL,
+    -- The training session that moved the stage, if any.
+    session_id INTEGER,
+    user_id INTEGER,
+    changed_at TIMESTAMP NOT NULL,
+    CONSTRAINT fk_skill_stage_changes_skills
+    FOREIGN KEY (skill_id)
+    REFERENCES skills(id)
+    ON DELETE CASCADE,
+    CONSTRAINT fk_skill_stage_changes_sessions
+    FOREIGN KEY (session_id)
+    REFERENCES training_sessions(id)
+    ON DELETE SET NULL,
+    CONSTRAINT fk_skill_stage_changes_users
+    FOREIGN KEY (user_id)
+    REFERENCES users(id)
+    ON DELETE SET NULL
+);
+
+-- +goose Down
+DROP TABLE skill_stage_changes;
+DROP TABLE skills;
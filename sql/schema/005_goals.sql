@@ -0,0 +1,52 @@
+-- +goose Up
+CREATE TABLE goals (
+    id INTEGER GENERATED ALWAYS AS IDENTITY PRIMARY KEY,
+    pet_id INTEGER NOT NULL,
+    description TEXT NOT NULL,
+    target_date DATE,
+    -- open, achieved or abandoned.
+    status TEXT NOT NULL DEFAULT 'open',
+    achieved_at TIMESTAMP,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL,
+    CONSTRAINT chk_goals_status CHECK (status IN ('open', 'achieved', 'abandoned')),
+    CONSTRAINT fk_goals_pets
+    FOREIGN KEY (pet_id)
+    REFERENCES pet(id)
+    ON DELETE CASCADE
+);
+
+CREATE TABLE goal_skills (
+    goal_id INTEGER NOT NULL,
+    skill_id INTEGER NOT NULL,
+    PRIMARY KEY (goal_id, skill_id),
+    CONSTRAINT fk_goal_skills_goals
+    FOREIGN KEY (goal_id)
+    REFERENCES goals(id)
+    ON DELETE CASCADE,
+    CONSTRAINT fk_goal_skills_skills
+    FOREIGN KEY (skill_id)
+    REFERENCES skills(id)
+    ON DELETE CASCADE
+);
+
+CREATE TABLE goal_progress_notes (
+    id INTEGER GENERATED ALWAYS AS IDENTITY PRIMARY KEY,
+    goal_id INTEGER NOT NULL,
+    user_id INTEGER,
+    note TEXT NOT NULL,
+    created_at TIMESTAMP NOT NULL,
+    CONSTRAINT fk_goal_progress_notes_goals
+    FOREIGN KEY (goal_id)
+    REFERENCES goals(id)
+    ON DELETE CASCADE,
+    CONSTRAINT fk_goal_progress_notes_users
+    FOREIGN KEY (user_id)
+    REFERENCES users(id)
+    ON DELETE SET NULL
+);
+
+-- +goose Down
+DROP TABLE goal_progress_notes;
+DROP TABLE goal_skills;
+DROP TABLE goals;
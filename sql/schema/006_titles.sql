@@ -0,0 +1,23 @@
+-- +goose Up
+CREATE TABLE titles (
+    id INTEGER GENERATED ALWAYS AS IDENTITY PRIMARY KEY,
+    pet_id INTEGER NOT NULL,
+    name TEXT NOT NULL,
+    -- Shown around the pet's name, e.g. CH or CGC.
+    abbreviation TEXT,
+    -- prefix or suffix.
+    position TEXT NOT NULL DEFAULT 'suffix',
+    organization TEXT,
+    earned_on DATE NOT NULL,
+    certificate_image_url TEXT,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL,
+    CONSTRAINT chk_titles_position CHECK (position IN ('prefix', 'suffix')),
+    CONSTRAINT fk_titles_pets
+    FOREIGN KEY (pet_id)
+    REFERENCES pet(id)
+    ON DELETE CASCADE
+);
+
+-- +goose Down
+DROP TABLE titles;
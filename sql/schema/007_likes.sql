@@ -0,0 +1,40 @@
+-- +goose Up
+CREATE TABLE likes (
+    id INTEGER GENERATED ALWAYS AS IDENTITY PRIMARY KEY,
+    pet_id INTEGER NOT NULL,
+    name TEXT NOT NULL,
+    -- food, toy, play or environmental.
+    category TEXT NOT NULL,
+    -- low, medium or high.
+    value_tier TEXT NOT NULL DEFAULT 'medium',
+    notes TEXT,
+    -- 1 is the pet's favorite.
+    rank INTEGER NOT NULL,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL,
+    CONSTRAINT chk_likes_category CHECK (category IN ('food', 'toy', 'play', 'environmental')),
+    CONSTRAINT chk_likes_value_tier CHECK (value_tier IN ('low', 'medium', 'high')),
+    CONSTRAINT fk_likes_pets
+    FOREIGN KEY (pet_id)
+    REFERENCES pet(id)
+    ON DELETE CASCADE
+);
+
+-- Reinforcers used during a training session.
+CREATE TABLE training_session_likes (
+    session_id INTEGER NOT NULL,
+    like_id INTEGER NOT NULL,
+    PRIMARY KEY (session_id, like_id),
+    CONSTRAINT fk_training_session_likes_sessions
+    FOREIGN KEY (session_id)
+    REFERENCES training_sessions(id)
+    ON DELETE CASCADE,
+    CONSTRAINT fk_training_session_likes_likes
+    FOREIGN KEY (like_id)
+    REFERENCES likes(id)
+    ON DELETE CASCADE
+);
+
+-- +goose Down
+DROP TABLE training_session_likes;
+DROP TABLE likes;
@@ -0,0 +1,13 @@
+-- +goose Up
+ALTER TABLE pet ADD COLUMN slug TEXT;
+
+-- Existing pets get their name plus id, which is already unique.
+UPDATE pet
+SET slug = TRIM(BOTH '-' FROM LOWER(REGEXP_REPLACE(name, '[^a-zA-Z0-9]+', '-', 'g'))) || '-' || id;
+
+ALTER TABLE pet ALTER COLUMN slug SET NOT NULL;
+ALTER TABLE pet ADD CONSTRAINT uq_pet_slug UNIQUE (slug);
+
+-- +goose Down
+ALTER TABLE pet DROP CONSTRAINT uq_pet_slug;
+ALTER TABLE pet DROP COLUMN slug;
@@ -0,0 +1,31 @@
+-- +goose Up
+CREATE TABLE pet_invitations (
+    id INTEGER GENERATED ALWAYS AS IDENTITY PRIMARY KEY,
+    pet_id INTEGER NOT NULL,
+    email TEXT NOT NULL,
+    -- Matches UserPets.permissions_level. Owners can't be invited.
+    permissions_level INTEGER NOT NULL,
+    invited_by INTEGER,
+    expires_at TIMESTAMP NOT NULL,
+    accepted_by INTEGER,
+    accepted_at TIMESTAMP,
+    revoked_at TIMESTAMP,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL,
+    CONSTRAINT chk_pet_invitations_permissions_level CHECK (permissions_level BETWEEN 1 AND 3),
+    CONSTRAINT fk_pet_invitations_pets
+    FOREIGN KEY (pet_id)
+    REFERENCES pet(id)
+    ON DELETE CASCADE,
+    CONSTRAINT fk_pet_invitations_invited_by
+    FOREIGN KEY (invited_by)
+    REFERENCES users(id)
+    ON DELETE SET NULL,
+    CONSTRAINT fk_pet_invitations_accepted_by
+    FOREIGN KEY (accepted_by)
+    REFERENCES users(id)
+    ON DELETE SET NULL
+);
+
+-- +goose Down
+DROP TABLE pet_invitations;
@@ -0,0 +1,22 @@
+-- +goose Up
+CREATE TABLE refresh_tokens (
+    id INTEGER GENERATED ALWAYS AS IDENTITY PRIMARY KEY,
+    user_id INTEGER NOT NULL,
+    -- SHA-256 of the token. The token itself only ever lives in the user's cookie.
+    token_hash TEXT NOT NULL UNIQUE,
+    -- Shared by every token rotated from the same login.
+    family_id UUID NOT NULL,
+    expires_at TIMESTAMP NOT NULL,
+    revoked_at TIMESTAMP,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL,
+    CONSTRAINT fk_refresh_tokens_users
+    FOREIGN KEY (user_id)
+    REFERENCES users(id)
+    ON DELETE CASCADE
+);
+
+CREATE INDEX idx_refresh_tokens_family_id ON refresh_tokens(family_id);
+
+-- +goose Down
+DROP TABLE refresh_tokens;
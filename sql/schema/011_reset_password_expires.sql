@@ -0,0 +1,9 @@
+-- +goose Up
+-- Nothing has written these columns yet, so there are no stored tokens to keep.
+UPDATE users SET reset_password_token = NULL, reset_password_expires = NULL;
+ALTER TABLE users ALTER COLUMN reset_password_expires TYPE TIMESTAMP USING NULL;
+CREATE UNIQUE INDEX idx_users_reset_password_token ON users(reset_password_token);
+
+-- +goose Down
+DROP INDEX idx_users_reset_password_token;
+ALTER TABLE users ALTER COLUMN reset_password_expires TYPE TEXT USING reset_password_expires::TEXT;
@@ -0,0 +1,11 @@
+-- +goose Up
+ALTER TABLE users ADD COLUMN oidc_issuer TEXT;
+ALTER TABLE users ADD COLUMN oidc_subject TEXT;
+CREATE UNIQUE INDEX idx_users_facebook_id ON users(facebook_id);
+CREATE UNIQUE INDEX idx_users_oidc_identity ON users(oidc_issuer, oidc_subject);
+
+-- +goose Down
+DROP INDEX idx_users_oidc_identity;
+DROP INDEX idx_users_facebook_id;
+ALTER TABLE users DROP COLUMN oidc_subject;
+ALTER TABLE users DROP COLUMN oidc_issuer;
@@ -0,0 +1,6 @@
+-- +goose Up
+-- When the user asked to be deleted. Their data is purged once the grace period has passed.
+ALTER TABLE users ADD COLUMN deleted_at TIMESTAMP;
+
+-- +goose Down
+ALTER TABLE users DROP COLUMN deleted_at;
@@ -0,0 +1,12 @@
+-- +goose Up
+-- Attempt counters for rate limiting, such as failed logins by IP or email.
+CREATE TABLE rate_limits (
+    key TEXT PRIMARY KEY,
+    attempts INTEGER NOT NULL,
+    last_attempt_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX idx_rate_limits_last_attempt_at ON rate_limits(last_attempt_at);
+
+-- +goose Down
+DROP TABLE rate_limits;
@@ -0,0 +1,39 @@
+-- +goose Up
+-- One row per login. Its id is the family_id shared by the login's refresh
+-- tokens, and is carried in the login's access tokens.
+CREATE TABLE sessions (
+    id UUID PRIMARY KEY,
+    user_id INTEGER NOT NULL,
+    user_agent TEXT NOT NULL,
+    ip_address TEXT NOT NULL,
+    created_at TIMESTAMP NOT NULL,
+    last_seen_at TIMESTAMP NOT NULL,
+    revoked_at TIMESTAMP,
+    CONSTRAINT fk_sessions_users
+    FOREIGN KEY (user_id)
+    REFERENCES users(id)
+    ON DELETE CASCADE
+);
+
+CREATE INDEX idx_sessions_user_id ON sessions(user_id);
+
+-- Logins from before sessions were recorded, with no device details.
+INSERT INTO sessions (id, user_id, user_agent, ip_address, created_at, last_seen_at, revoked_at)
+SELECT family_id,
+    MIN(user_id),
+    '',
+    '',
+    MIN(created_at),
+    MAX(updated_at),
+    CASE WHEN BOOL_AND(revoked_at IS NOT NULL) THEN MAX(revoked_at) END
+FROM refresh_tokens
+GROUP BY family_id;
+
+ALTER TABLE refresh_tokens ADD CONSTRAINT fk_refresh_tokens_sessions
+FOREIGN KEY (family_id)
+REFERENCES sessions(id)
+ON DELETE CASCADE;
+
+-- +goose Down
+ALTER TABLE refresh_tokens DROP CONSTRAINT fk_refresh_tokens_sessions;
+DROP TABLE sessions;
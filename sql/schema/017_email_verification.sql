@@ -0,0 +1,16 @@
+-- +goose Up
+ALTER TABLE users ADD COLUMN email_verified_at TIMESTAMP;
+
+-- Social logins only ever stored addresses their provider had verified.
+UPDATE users
+SET email_verified_at = created_at
+WHERE email IS NOT NULL
+    AND (facebook_id IS NOT NULL OR oidc_subject IS NOT NULL);
+
+-- Emails are matched ignoring case, so "Bob@x.com" and "bob@x.com" are one
+-- account. Fails if both are already registered. Merge or rename them first.
+CREATE UNIQUE INDEX idx_users_email_lower ON users(LOWER(email));
+
+-- +goose Down
+DROP INDEX idx_users_email_lower;
+ALTER TABLE users DROP COLUMN email_verified_at;
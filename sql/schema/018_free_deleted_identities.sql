@@ -0,0 +1,10 @@
+-- +goose Up
+-- Deleting an account now frees its social logins. Free the ones already deleted.
+UPDATE users
+SET facebook_id = NULL,
+    oidc_issuer = NULL,
+    oidc_subject = NULL
+WHERE is_deleted;
+
+-- +goose Down
+-- The identities are gone, so there's nothing to put back.
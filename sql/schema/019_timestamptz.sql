@@ -0,0 +1,55 @@
+-- +goose Up
+-- These are compared with the app's clock, so they need to be instants.
+-- TIMESTAMP drops the zone, so NOW() wrote the database's local time while
+-- the app read it back as UTC. Existing values are taken to be in the
+-- database's zone, which is what NOW() wrote them in.
+ALTER TABLE refresh_tokens
+    ALTER COLUMN expires_at TYPE TIMESTAMPTZ,
+    ALTER COLUMN revoked_at TYPE TIMESTAMPTZ,
+    ALTER COLUMN created_at TYPE TIMESTAMPTZ,
+    ALTER COLUMN updated_at TYPE TIMESTAMPTZ;
+
+ALTER TABLE sessions
+    ALTER COLUMN created_at TYPE TIMESTAMPTZ,
+    ALTER COLUMN last_seen_at TYPE TIMESTAMPTZ,
+    ALTER COLUMN revoked_at TYPE TIMESTAMPTZ;
+
+ALTER TABLE pet_invitations
+    ALTER COLUMN expires_at TYPE TIMESTAMPTZ,
+    ALTER COLUMN accepted_at TYPE TIMESTAMPTZ,
+    ALTER COLUMN revoked_at TYPE TIMESTAMPTZ,
+    ALTER COLUMN created_at TYPE TIMESTAMPTZ,
+    ALTER COLUMN updated_at TYPE TIMESTAMPTZ;
+
+ALTER TABLE users
+    ALTER COLUMN reset_password_expires TYPE TIMESTAMPTZ,
+    ALTER COLUMN deleted_at TYPE TIMESTAMPTZ;
+
+ALTER TABLE rate_limits
+    ALTER COLUMN last_attempt_at TYPE TIMESTAMPTZ;
+
+-- +goose Down
+ALTER TABLE rate_limits
+    ALTER COLUMN last_attempt_at TYPE TIMESTAMP;
+
+ALTER TABLE users
+    ALTER COLUMN deleted_at TYPE TIMESTAMP,
+    ALTER COLUMN reset_password_expires TYPE TIMESTAMP;
+
+ALTER TABLE pet_invitations
+    ALTER COLUMN updated_at TYPE TIMESTAMP,
+    ALTER COLUMN created_at TYPE TIMESTAMP,
+    ALTER COLUMN revoked_at TYPE TIMESTAMP,
+    ALTER COLUMN accepted_at TYPE TIMESTAMP,
+    ALTER COLUMN expires_at TYPE TIMESTAMP;
+
+ALTER TABLE sessions
+    ALTER COLUMN revoked_at TYPE TIMESTAMP,
+    ALTER COLUMN last_seen_at TYPE TIMESTAMP,
+    ALTER COLUMN created_at TYPE TIMESTAMP;
+
+ALTER TABLE refresh_tokens
+    ALTER COLUMN updated_at TYPE TIMESTAMP,
+    ALTER COLUMN created_at TYPE TIMESTAMP,
+    ALTER COLUMN revoked_at TYPE TIMESTAMP,
+    ALTER COLUMN expires_at TYPE TIMESTAMP;
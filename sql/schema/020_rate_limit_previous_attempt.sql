@@ -0,0 +1,7 @@
+-- +goose Up
+-- When the attempt before last_attempt_at was, so recording an attempt can
+-- say how long it should have waited without a separate read.
+ALTER TABLE rate_limits ADD COLUMN previous_attempt_at TIMESTAMPTZ;
+
+-- +goose Down
+ALTER TABLE rate_limits DROP COLUMN previous_attempt_at;
@@ -0,0 +1,9 @@
+package schema
+
+import "embed"
+
+// The goose migrations, built into the binary so it knows which version of
+// the schema it was written for.
+//
+//go:embed *.sql
+var Migrations embed.FS
@@ -0,0 +1,8 @@
+package ui
+
+import "embed"
+
+// The HTML templates, built into the binary so it doesn't need them on disk.
+//
+//go:embed html
+var Files embed.FS
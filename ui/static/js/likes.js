@@ -0,0 +1,48 @@
+// Drag-to-reorder for a pet's likes. Posts the new order after each drop.
+(function () {
+    const list = document.querySelector(".likes[data-order-url]");
+    if (!list) {
+        return;
+    }
+
+    let dragged = null;
+
+    list.addEventListener("dragstart", (event) => {
+        dragged = event.target.closest("[data-like-id]");
+    });
+
+    list.addEventListener("dragover", (event) => {
+        const target = event.target.closest("[data-like-id]");
+        if (!dragged || !target || target === dragged) {
+            return;
+        }
+        event.preventDefault();
+
+        const box = target.getBoundingClientRect();
+        const after = event.clientY > box.top + box.height / 2;
+        list.insertBefore(dragged, after ? target.nextSibling : target);
+    });
+
+    list.addEventListener("drop", (event) => {
+        event.preventDefault();
+    });
+
+    list.addEventListener("dragend", () => {
+        dragged = null;
+
+        const body = new URLSearchParams();
+        list.querySelectorAll("[data-like-id]").forEach((item) => {
+            body.append("like_ids", item.dataset.likeId);
+        });
+
+        fetch(list.dataset.orderUrl, {
+            method: "POST",
+            headers: { "X-CSRF-Token": list.dataset.csrfToken, "Accept": "application/json" },
+            body: body,
+        }).then((response) => {
+            if (!response.ok) {
+                window.location.reload();
+            }
+        });
+    });
+})();